@@ -0,0 +1,261 @@
+/*
+Package abc calibrates an experiment by approximate Bayesian computation.
+
+Parameters are drawn from uniform priors and simulated, a draw is accepted
+if the summary statistics of its run are close to the observed ones. The
+rejection sampler accepts the closest share of draws from the prior, the
+sequential Monte Carlo sampler (population Monte Carlo) then perturbs the
+accepted particles over several generations with a shrinking tolerance. The
+result is a weighted sample of the posterior.
+*/
+package abc
+
+import "context"
+import "fmt"
+import "math"
+import "math/rand"
+import "sort"
+
+import "flache/ecm/experiment"
+
+// a uniform prior
+type Prior struct {
+	Name     string
+	Min, Max float64
+}
+
+func (p Prior) contains(v float64) bool {
+	return v >= p.Min && v <= p.Max
+}
+
+// a summary statistic of a run and its observed value
+type Statistic struct {
+	Name     string
+	Observed float64
+	Value    func(experiment.SimRes) float64
+}
+
+// runs the model with the parameters theta (in the order of the priors)
+type Simulator func(ctx context.Context, theta []float64, seed int64) (experiment.SimRes, error)
+
+// Particle is an accepted draw
+type Particle struct {
+	Theta    []float64
+	Weight   float64
+	Distance float64
+	// the summary statistics of its run
+	Stats []float64
+}
+
+// ABC is the setup of a calibration
+type ABC struct {
+	Priors   []Prior
+	Stats    []Statistic
+	Simulate Simulator
+	// runs the simulations of a generation
+	Executor *experiment.Executor
+	Rand     *rand.Rand
+	// accepted particles of a generation
+	Particles int
+	// share of the simulations of a generation which is accepted
+	Quantile float64
+	// called with the posterior of every generation
+	Generation func(*Posterior)
+
+	// scale of every statistic in the distance
+	scales []float64
+}
+
+// rejection ABC: accepts the closest Particles of Particles/Quantile draws
+// from the prior
+func (a *ABC) Rejection(ctx context.Context) (*Posterior, error) {
+	return a.SMC(ctx, 1)
+}
+
+// population Monte Carlo ABC: the first generation is the rejection sampler,
+// the following ones draw from the weighted particles of the previous one,
+// perturbed by a normal kernel with twice their variance. The tolerance of
+// a generation is the distance of its last accepted particle. If ctx is
+// canceled the posterior of the last finished generation is returned.
+func (a *ABC) SMC(ctx context.Context, generations int) (*Posterior, error) {
+	var post *Posterior
+	for g := 0; g < generations; g++ {
+		next, err := a.generation(ctx, post)
+		if err != nil {
+			return post, err
+		}
+		if len(next.Particles) == 0 {
+			return post, fmt.Errorf("generation %d: no run finished", g)
+		}
+		next.Generation = g
+		if post != nil {
+			next.Simulations += post.Simulations
+		}
+		post = next
+		if a.Generation != nil {
+			a.Generation(post)
+		}
+	}
+	return post, nil
+}
+
+// number of simulations of a generation
+func (a *ABC) draws() int {
+	return int(math.Ceil(float64(a.Particles) / a.Quantile))
+}
+
+func (a *ABC) generation(ctx context.Context, prev *Posterior) (*Posterior, error) {
+	n := a.draws()
+	thetas := make([][]float64, n)
+	var kernel []float64
+	if prev == nil {
+		for i := range thetas {
+			thetas[i] = a.fromPrior()
+		}
+	} else {
+		kernel = prev.kernel()
+		for i := range thetas {
+			thetas[i] = a.perturb(prev, kernel)
+		}
+	}
+
+	jobs := make([]experiment.Job, n)
+	for i := range jobs {
+		jobs[i] = experiment.Job{Set: i, Seed: a.Rand.Int63()}
+	}
+	results := a.Executor.Execute(ctx, jobs, func(ctx context.Context, j experiment.Job) (experiment.SimRes, error) {
+		return a.Simulate(ctx, thetas[j.Set], j.Seed)
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	stats := make([][]float64, n)
+	for i, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		stats[i] = make([]float64, len(a.Stats))
+		for k, s := range a.Stats {
+			stats[i][k] = s.Value(r.Res)
+		}
+	}
+	if a.scales == nil {
+		a.scales = scales(stats, len(a.Stats))
+	}
+
+	particles := make([]Particle, 0, n)
+	for i := range thetas {
+		if stats[i] == nil {
+			// failed runs are never accepted
+			continue
+		}
+		particles = append(particles, Particle{Theta: thetas[i], Stats: stats[i],
+			Distance: a.distance(stats[i])})
+	}
+	sort.SliceStable(particles, func(i, j int) bool {
+		return particles[i].Distance < particles[j].Distance
+	})
+	if len(particles) > a.Particles {
+		particles = particles[:a.Particles]
+	}
+
+	post := &Posterior{Priors: a.Priors, Particles: particles, Simulations: n}
+	if len(particles) > 0 {
+		post.Epsilon = particles[len(particles)-1].Distance
+	}
+	for i := range particles {
+		particles[i].Weight = 1
+		if prev != nil {
+			// prior over the proposal density, the priors are uniform
+			particles[i].Weight = 1 / prev.density(particles[i].Theta, kernel)
+		}
+	}
+	post.normalize()
+	return post, nil
+}
+
+func (a *ABC) fromPrior() []float64 {
+	theta := make([]float64, len(a.Priors))
+	for i, p := range a.Priors {
+		theta[i] = p.Min + a.Rand.Float64()*(p.Max-p.Min)
+	}
+	return theta
+}
+
+// a particle of prev drawn by weight and moved by the kernel, redrawn until
+// it lies inside the priors
+func (a *ABC) perturb(prev *Posterior, kernel []float64) []float64 {
+	for {
+		u := a.Rand.Float64()
+		p := prev.Particles[len(prev.Particles)-1]
+		for _, q := range prev.Particles {
+			if u < q.Weight {
+				p = q
+				break
+			}
+			u -= q.Weight
+		}
+
+		theta := make([]float64, len(p.Theta))
+		inside := true
+		for i, v := range p.Theta {
+			theta[i] = v + a.Rand.NormFloat64()*kernel[i]
+			inside = inside && a.Priors[i].contains(theta[i])
+		}
+		if inside {
+			return theta
+		}
+	}
+}
+
+// euclidean distance of the scaled statistics to the observed ones
+func (a *ABC) distance(stats []float64) float64 {
+	d := 0.0
+	for k, s := range a.Stats {
+		v := (stats[k] - s.Observed) / a.scales[k]
+		d += v * v
+	}
+	return math.Sqrt(d)
+}
+
+// standard deviation of every statistic over the runs, 1 if it is 0
+func scales(stats [][]float64, k int) []float64 {
+	sc := make([]float64, k)
+	for j := range sc {
+		var values []float64
+		for _, s := range stats {
+			if s != nil {
+				values = append(values, s[j])
+			}
+		}
+		_, sd := meanSD(values, nil)
+		if sd == 0 || math.IsNaN(sd) {
+			sd = 1
+		}
+		sc[j] = sd
+	}
+	return sc
+}
+
+// weighted mean and standard deviation, equal weights if w is nil
+func meanSD(v, w []float64) (float64, float64) {
+	if len(v) == 0 {
+		return math.NaN(), math.NaN()
+	}
+	weight := func(i int) float64 {
+		if w == nil {
+			return 1 / float64(len(v))
+		}
+		return w[i]
+	}
+	mean := 0.0
+	for i, x := range v {
+		mean += weight(i) * x
+	}
+	ss := 0.0
+	for i, x := range v {
+		ss += weight(i) * (x - mean) * (x - mean)
+	}
+	return mean, math.Sqrt(ss)
+}
@@ -0,0 +1,85 @@
+package abc
+
+import "context"
+import "math"
+import "math/rand"
+import "testing"
+
+import "flache/ecm/experiment"
+
+// the echo chamber ratio of a run is theta plus noise with sd 0.05
+func testABC(observed float64) *ABC {
+	return &ABC{
+		Priors: []Prior{{"theta", 0, 1}},
+		Stats: []Statistic{{"EchoChamberRatio", observed,
+			func(r experiment.SimRes) float64 { return r.EchoChamberRatio }}},
+		Simulate: func(ctx context.Context, theta []float64, seed int64) (experiment.SimRes, error) {
+			r := rand.New(rand.NewSource(seed))
+			return experiment.SimRes{EchoChamberRatio: theta[0] + 0.05*r.NormFloat64(), Seed: seed}, nil
+		},
+		Executor:  &experiment.Executor{Workers: 4},
+		Rand:      rand.New(rand.NewSource(1)),
+		Particles: 100,
+		Quantile:  0.1,
+	}
+}
+
+func TestRejection(t *testing.T) {
+	post, err := testABC(0.3).Rejection(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(post.Particles) != 100 || post.Simulations != 1000 {
+		t.Errorf("%d particles of %d simulations, want 100 of 1000", len(post.Particles), post.Simulations)
+	}
+	mean, sd := post.Mean(0)
+	if math.Abs(mean-0.3) > 0.03 || sd > 0.1 {
+		t.Errorf("posterior %g ± %g, want about 0.3", mean, sd)
+	}
+	if lo, hi := post.Interval(0, 0.95); lo > 0.3 || hi < 0.3 {
+		t.Errorf("95%% interval [%g, %g] misses 0.3", lo, hi)
+	}
+	for _, p := range post.Particles {
+		if p.Distance > post.Epsilon {
+			t.Fatalf("particle at distance %g above the tolerance %g", p.Distance, post.Epsilon)
+		}
+	}
+}
+
+func TestSMC(t *testing.T) {
+	a := testABC(0.7)
+	var epsilons []float64
+	a.Generation = func(p *Posterior) { epsilons = append(epsilons, p.Epsilon) }
+	post, err := a.SMC(context.Background(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Generation != 2 || post.Simulations != 3000 {
+		t.Errorf("generation %d after %d simulations, want 2 after 3000", post.Generation, post.Simulations)
+	}
+	// the tolerance shrinks from generation to generation
+	if len(epsilons) != 3 || epsilons[1] >= epsilons[0] || epsilons[2] >= epsilons[1] {
+		t.Errorf("tolerances %v", epsilons)
+	}
+	sum := 0.0
+	for _, p := range post.Particles {
+		sum += p.Weight
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("weights sum to %g", sum)
+	}
+	mean, _ := post.Mean(0)
+	if math.Abs(mean-0.7) > 0.03 {
+		t.Errorf("posterior mean %g, want about 0.7", mean)
+	}
+}
+
+func TestSMCCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	a := testABC(0.3)
+	a.Generation = func(*Posterior) { cancel() }
+	post, err := a.SMC(ctx, 3)
+	if err == nil || post == nil || post.Generation != 0 {
+		t.Errorf("canceled after the first generation: %v, %+v", err, post)
+	}
+}
@@ -0,0 +1,53 @@
+package abc
+
+import "context"
+import "fmt"
+import "sort"
+
+import "flache/ecm/experiment"
+
+// the priors of an experiment: the α and β of the sweep probabilities over
+// their Min and Max, and the factors of ABC.Priors
+func Priors(x *experiment.Experiment) []Prior {
+	var priors []Prior
+	for i, b := range x.Sweep.Probabilities {
+		priors = append(priors,
+			Prior{fmt.Sprintf("Probabilities[%d].Alpha", i), b.Alpha.Min, b.Alpha.Max},
+			Prior{fmt.Sprintf("Probabilities[%d].Beta", i), b.Beta.Min, b.Beta.Max})
+	}
+	for _, name := range x.ABC.Priors.Names() {
+		r := x.ABC.Priors[name]
+		priors = append(priors, Prior{name, r[0], r[1]})
+	}
+	return priors
+}
+
+// the observed statistics of an experiment, ordered by name
+func Stats(x *experiment.Experiment) ([]Statistic, error) {
+	var stats []Statistic
+	for name, observed := range x.ABC.Observed {
+		f, err := experiment.Statistic(name)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, Statistic{name, observed, f})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats, nil
+}
+
+// runs the experiment of the target with the values of theta
+func ExperimentSimulator(mt experiment.MyTarget, priors []Prior) (Simulator, error) {
+	names := make([]string, len(priors))
+	for i, prior := range priors {
+		names[i] = prior.Name
+	}
+	factors, err := experiment.NewFactors(names)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, theta []float64, seed int64) (experiment.SimRes, error) {
+		t, p := factors.Apply(mt, theta)
+		return t.SimulateContext(ctx, p, seed)
+	}, nil
+}
@@ -0,0 +1,137 @@
+package abc
+
+import "fmt"
+import "io"
+import "math"
+import "sort"
+
+// Posterior is a weighted sample of the parameters
+type Posterior struct {
+	Priors    []Prior
+	Particles []Particle
+	// distance of the last accepted particle
+	Epsilon     float64
+	Generation  int
+	Simulations int
+}
+
+func (p *Posterior) normalize() {
+	sum := 0.0
+	for _, q := range p.Particles {
+		sum += q.Weight
+	}
+	for i := range p.Particles {
+		p.Particles[i].Weight /= sum
+	}
+}
+
+func (p *Posterior) values(i int) ([]float64, []float64) {
+	v := make([]float64, len(p.Particles))
+	w := make([]float64, len(p.Particles))
+	for k, q := range p.Particles {
+		v[k], w[k] = q.Theta[i], q.Weight
+	}
+	return v, w
+}
+
+// weighted mean and standard deviation of parameter i
+func (p *Posterior) Mean(i int) (mean, sd float64) {
+	return meanSD(p.values(i))
+}
+
+// the weighted q-quantile of parameter i
+func (p *Posterior) Quantile(i int, q float64) float64 {
+	v, w := p.values(i)
+	if len(v) == 0 {
+		return math.NaN()
+	}
+	order := make([]int, len(v))
+	for k := range order {
+		order[k] = k
+	}
+	sort.Slice(order, func(a, b int) bool { return v[order[a]] < v[order[b]] })
+	cum := 0.0
+	for _, k := range order {
+		cum += w[k]
+		if cum >= q {
+			return v[k]
+		}
+	}
+	return v[order[len(order)-1]]
+}
+
+// the equal tailed credible interval of parameter i
+func (p *Posterior) Interval(i int, level float64) (lo, hi float64) {
+	tail := (1 - level) / 2
+	return p.Quantile(i, tail), p.Quantile(i, 1-tail)
+}
+
+// effective sample size of the weights
+func (p *Posterior) ESS() float64 {
+	sq := 0.0
+	for _, q := range p.Particles {
+		sq += q.Weight * q.Weight
+	}
+	return 1 / sq
+}
+
+// the standard deviation of the perturbation kernel of every parameter:
+// twice the weighted variance
+func (p *Posterior) kernel() []float64 {
+	k := make([]float64, len(p.Priors))
+	for i := range k {
+		_, sd := p.Mean(i)
+		k[i] = math.Sqrt(2) * sd
+	}
+	return k
+}
+
+// the density of theta under the perturbed particles, up to a constant
+func (p *Posterior) density(theta []float64, kernel []float64) float64 {
+	d := 0.0
+	for _, q := range p.Particles {
+		l := q.Weight
+		for i, v := range theta {
+			if kernel[i] == 0 {
+				continue
+			}
+			z := (v - q.Theta[i]) / kernel[i]
+			l *= math.Exp(-z * z / 2)
+		}
+		d += l
+	}
+	return d
+}
+
+// writes the particles as csv: weight, distance and the parameters
+func (p *Posterior) Write(w io.Writer) error {
+	fmt.Fprintf(w, "weight, distance")
+	for _, prior := range p.Priors {
+		fmt.Fprintf(w, ", %s", prior.Name)
+	}
+	if _, err := fmt.Fprintf(w, "\n"); err != nil {
+		return err
+	}
+	for _, q := range p.Particles {
+		fmt.Fprintf(w, "%g, %g", q.Weight, q.Distance)
+		for _, v := range q.Theta {
+			fmt.Fprintf(w, ", %g", v)
+		}
+		if _, err := fmt.Fprintf(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prints the tolerance and the mean, sd and credible interval of every
+// parameter
+func (p *Posterior) Summary(w io.Writer, level float64) {
+	fmt.Fprintf(w, "generation %d: ε %g, %d particles (ess %.1f), %d simulations\n",
+		p.Generation, p.Epsilon, len(p.Particles), p.ESS(), p.Simulations)
+	for i, prior := range p.Priors {
+		mean, sd := p.Mean(i)
+		lo, hi := p.Interval(i, level)
+		fmt.Fprintf(w, "%s: %g ± %g, %g%% CI [%g, %g]\n", prior.Name, mean, sd, 100*level, lo, hi)
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import "bufio"
+import "bytes"
+import "encoding/csv"
+import "encoding/json"
+import "fmt"
+import "io"
+import "io/ioutil"
+import "math"
+import "os"
+import "path/filepath"
+import "sort"
+import "strconv"
+import "strings"
+import "text/tabwriter"
+
+import "flache/ecm/experiment"
+
+// a result file as columns of values, non numeric cells are NaN
+type table struct {
+	header []string
+	rows   [][]float64
+}
+
+func (t *table) column(name string) int {
+	for i, h := range t.header {
+		if h == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// summarizes result files: the scores of a sweep, the recorded stats (csv
+// or jsonl) and run stores. Recorded runs are summarized by their last step,
+// stored runs by their parameter set.
+func analyze(w io.Writer, files []string) error {
+	if len(files) == 0 {
+		return fmt.Errorf("analyze: no result files")
+	}
+	for _, path := range files {
+		t, err := readTable(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s: %d rows\n", path, len(t.rows))
+
+		if t.column("run") >= 0 && t.column("step") >= 0 {
+			t = t.finalSteps()
+			fmt.Fprintf(w, "final step of %d runs\n", len(t.rows))
+		}
+		t.summarize(w)
+
+		if s := t.column("score"); s >= 0 && len(t.rows) > 0 {
+			best := t.rows[0]
+			for _, r := range t.rows {
+				if r[s] < best[s] {
+					best = r
+				}
+			}
+			fmt.Fprintf(w, "best:")
+			for i, h := range t.header {
+				fmt.Fprintf(w, " %s=%g", h, best[i])
+			}
+			fmt.Fprintf(w, "\n")
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	return nil
+}
+
+func (t *table) summarize(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "column\tn\tmean\tsd\tmin\tmax\n")
+	for i, h := range t.header {
+		if h == "run" || h == "set" {
+			continue
+		}
+		var values []float64
+		for _, r := range t.rows {
+			if !math.IsNaN(r[i]) {
+				values = append(values, r[i])
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+		mean, sd := meanSD(values)
+		min, max := values[0], values[0]
+		for _, v := range values {
+			min = math.Min(min, v)
+			max = math.Max(max, v)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%g\t%g\t%g\t%g\n", h, len(values), mean, sd, min, max)
+	}
+	tw.Flush()
+}
+
+// the row with the highest step of every run
+func (t *table) finalSteps() *table {
+	run, step := t.column("run"), t.column("step")
+	last := make(map[float64][]float64)
+	var runs []float64
+	for _, r := range t.rows {
+		l, ok := last[r[run]]
+		if !ok {
+			runs = append(runs, r[run])
+		}
+		if !ok || r[step] >= l[step] {
+			last[r[run]] = r
+		}
+	}
+	sort.Float64s(runs)
+	f := &table{header: t.header}
+	for _, r := range runs {
+		f.rows = append(f.rows, last[r])
+	}
+	return f
+}
+
+func meanSD(values []float64) (float64, float64) {
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	ss := 0.0
+	for _, v := range values {
+		ss += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(ss / float64(len(values)-1))
+}
+
+func readTable(path string) (*table, error) {
+	switch filepath.Ext(path) {
+	case ".csv":
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return readCSV(f)
+	case ".jsonl":
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if isStore(data) {
+			return readStore(bytes.NewReader(data))
+		}
+		return readJSONL(bytes.NewReader(data))
+	}
+	return nil, fmt.Errorf("%s: unknown result format, use .csv or .jsonl", path)
+}
+
+func readCSV(r io.Reader) (*table, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return &table{}, nil
+	}
+
+	t := &table{header: records[0]}
+	for _, rec := range records[1:] {
+		row := make([]float64, len(t.header))
+		for i := range row {
+			row[i] = math.NaN()
+			if i < len(rec) {
+				if v, err := strconv.ParseFloat(strings.TrimSpace(rec[i]), 64); err == nil {
+					row[i] = v
+				} else if t.header[i] == "run" {
+					row[i] = runNumber(rec[i])
+				}
+			}
+		}
+		t.rows = append(t.rows, row)
+	}
+	return t, nil
+}
+
+// the rows of a JSONLRecorder
+func readJSONL(r io.Reader) (*table, error) {
+	type row struct {
+		Run   string
+		Seed  int64
+		Step  int
+		Stats map[string]float64
+	}
+
+	var rows []row
+	stats := make(map[string]bool)
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for s.Scan() {
+		if strings.TrimSpace(s.Text()) == "" {
+			continue
+		}
+		var rw row
+		if err := json.Unmarshal(s.Bytes(), &rw); err != nil {
+			return nil, err
+		}
+		for name := range rw.Stats {
+			stats[name] = true
+		}
+		rows = append(rows, rw)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	t := &table{header: append([]string{"run", "seed", "step"}, names...)}
+	for _, rw := range rows {
+		values := []float64{runNumber(rw.Run), float64(rw.Seed), float64(rw.Step)}
+		for _, name := range names {
+			v, ok := rw.Stats[name]
+			if !ok {
+				v = math.NaN()
+			}
+			values = append(values, v)
+		}
+		t.rows = append(t.rows, values)
+	}
+	return t, nil
+}
+
+// whether the first row is a stored run
+func isStore(data []byte) bool {
+	line := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line = data[:i]
+	}
+	var row map[string]json.RawMessage
+	if err := json.Unmarshal(line, &row); err != nil {
+		return false
+	}
+	_, key := row["Key"]
+	_, set := row["Set"]
+	return key && set
+}
+
+// a row per parameter set of an experiment.Store with the mean score of
+// its runs
+func readStore(r io.Reader) (*table, error) {
+	s, err := experiment.ReadStore(r)
+	if err != nil {
+		return nil, err
+	}
+	t := &table{header: []string{"set", "n", "score", "sd", "alpha", "beta"}}
+	for i, g := range s.Groups() {
+		mean, sd := g.Score()
+		alpha, beta := math.NaN(), math.NaN()
+		if len(g.Parameters.Probabilities) > 0 {
+			alpha = g.Parameters.Probabilities[0].Alpha.Var
+			beta = g.Parameters.Probabilities[0].Beta.Var
+		}
+		t.rows = append(t.rows, []float64{float64(i), float64(len(g.Rows)), mean, sd, alpha, beta})
+	}
+	return t, nil
+}
+
+// the number of a run id like run-3
+func runNumber(id string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimPrefix(id, "run-"), 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return v
+}
@@ -1,642 +1,579 @@
-/*
-A Agent model to simulate opinion dynamics in echo chambers using the goabm library
-Copyright 2014 by Remo Hertig <remo.hertig@bluewin.ch>
-
-v2
-
-model:
-
-agent has relation to other agents r
-a relation consists of an series of interactions
-
-agent can have at most N relation
-
-the agent is attracted to other with p(similarity)
-sort agents according to score function -> nInter * nDaysSince * Similarity
-however he will also interact with long known "friends": p( nInteractions^f=1 * nDaysSinceLastI^-f=1)
-
-c1: condition on which he will seach a new interaction partner
-case 1: nearby new neighbor
-        if there is unknown neighbor -> try
-                otherwise internet
-case 2: "internet"
-
-for every feature we keep track of interactions & potential partners
-if a certain feature's count falls below a treshold, the agent starts to search for potential partners
- f[needtointeract] = e^(x/f=1) -> triggers search for this feature
-
- on each interactions: update similarity (overall) -> especially for feature list
-                        interact (change feature)
-
-6 factor
-
-
-/// v2
-
-agent loop
-        * is there physical ip?
-                *interact normally
-        * no: blog loop
-                * evaluate blogs
-                * search new blog
-                * read postings
-                * comment
-
-*/
-
 package main
 
-import "goabm"
+import "context"
+import "flag"
 import "fmt"
-
-import "os"
-
-import "sort"
+import "io"
 import "math"
+import "math/rand"
+import "os"
+import "os/signal"
+import "strings"
+import "time"
 
-
-import "runtime/pprof"
-import "log"
-import "flag"
 import . "flache/ecm/model"
+import "flache/ecm/abc"
+import "flache/ecm/experiment"
+import "flache/ecm/optimize"
+import "flache/ecm/sampler"
+import "flache/ecm/sensitivity"
+
+func init() {
+	register("ecm2", &ecm2Variant{})
+}
 
-import "code.google.com/p/probab/dst"
+// the blogosphere model, configured by an experiment
+type ecm2Variant struct {
+	x      *experiment.Experiment
+	config string
+	fs     *flag.FlagSet
+	cmd    string
 
-//import "time"
-import "runtime"
-import "github.com/GaryBoone/GoStats/stats"
+	progress time.Duration
+}
 
-type SimRes struct {
-	Cultures           int
-	OnlineInteraction  int
-	OfflineInteraction int
-	TotalEchoChambers  int
-	EchoChamberRatio   float64
-	Events             int
+func (v *ecm2Variant) Description() string {
+	return "blogosphere on top of Axelrod cultures (experiment files with -config)"
 }
 
+// the flags write into the experiment
+func (v *ecm2Variant) Flags(cmd string, fs *flag.FlagSet) {
+	v.fs, v.cmd = fs, cmd
+	v.x = experiment.DefaultExperiment()
+	x := v.x
+	fs.StringVar(&v.config, "config", "", "experiment file (.json or .yaml), flags override its values")
+	fs.StringVar(&x.Similarity, "similarity", x.Similarity, "similarity metric: exact, weighted, ordinal or jaccard")
+	fs.Var((*experiment.FloatList)(&x.Salience), "salience", "comma separated feature salience for the weighted metric")
+	fs.StringVar(&x.Discovery, "discovery", x.Discovery, "blog discovery: most-similar, similarity-weighted, popularity, random or serendipity")
+	fs.StringVar(&x.TargetMetric, "target-metric", x.TargetMetric, "echo chamber metric to calibrate: "+strings.Join(MetricNames, ", "))
+	fs.Float64Var(&x.Target, "target", x.Target, "echo chamber ratio to calibrate against")
+	fs.Float64Var(&x.MaxConfort, "max-confort", x.MaxConfort, "upper bound of the comfort zone")
+	fs.Float64Var(&x.UnsubscribeRate, "unsubscribe-rate", x.UnsubscribeRate, "probability to check the subscriptions when reading")
+	fs.IntVar(&x.ComfortWindow, "comfort-window", x.ComfortWindow, "number of recent posts averaged when checking a subscription")
+	fs.BoolVar(&x.Boredom, "boredom", x.Boredom, "also unsubscribe from blogs above the comfort zone")
+	fs.StringVar(&x.Output.Checkpoints.Dir, "checkpoint-dir", "", "write checkpoints of the runs into this directory")
+	fs.IntVar(&x.Output.Checkpoints.Every, "checkpoint-every", x.Output.Checkpoints.Every, "steps between two checkpoints")
+	fs.StringVar(&x.Output.Checkpoints.Restore, "restore", "", "continue the run from this checkpoint, with a single replicate")
+	fs.StringVar(&x.Output.Record, "record", "", "record the stats of every step into this file")
+	fs.StringVar(&x.Output.RecordFormat, "record-format", x.Output.RecordFormat, "format of the recorded stats: csv or jsonl")
+	fs.StringVar(&x.Output.Export.Path, "export", "", "export the network of each run (.graphml, .gexf or .dot)")
+	fs.IntVar(&x.Output.Export.Every, "export-every", 0, "steps between two time slices of a dynamic .gexf export")
+	fs.Int64Var(&x.Seed, "seed", 0, "seed of the simulation runs, 0 for a random seed")
+	fs.Float64Var(&x.PRecommend, "recommend", x.PRecommend, "probability to subscribe to a recommended blog instead of searching")
+	fs.Float64Var(&x.Serendipity, "serendipity", x.Serendipity, "share of searches sent to dissimilar blogs (serendipity discovery)")
+	fs.Float64Var(&x.SerendipityThreshold, "serendipity-threshold", x.SerendipityThreshold, "similarity below which a blog counts as dissimilar (serendipity discovery)")
+	fs.Var(x.Distributions, "dist", "per agent distribution of a parameter, e.g. PVeloc=beta(2,5), repeatable ("+strings.Join(AgentParams, ", ")+")")
+
+	fs.IntVar(&x.Features, "features", x.Features, "number of cultural features")
+	fs.IntVar(&x.Traits, "traits", x.Traits, "number of cultural traits per feature")
+	fs.IntVar(&x.Size, "size", x.Size, "size (width/height) of the landscape")
+	fs.IntVar(&x.Agents, "agents", x.Agents, "number of agents to simulate")
+	fs.IntVar(&x.Steps, "steps", x.Steps, "maximal number of simulation steps")
+	fs.IntVar(&x.Stopping.Absorbing, "stop-absorbing", x.Stopping.Absorbing, "check every n steps whether no feature can change any more and stop, 0 never")
+	fs.Float64Var(&x.Stopping.WallTime, "stop-walltime", x.Stopping.WallTime, "seconds after which a run stops and keeps its results, 0 for no limit")
+	if cmd != "run" {
+		fs.IntVar(&x.Replicates, "replicates", x.Replicates, "runs per parameter set")
+		fs.IntVar(&x.CPUs, "cpus", x.CPUs, "number of parallel runs")
+		fs.Float64Var(&x.Timeout, "timeout", x.Timeout, "seconds a single run may take, 0 for no limit")
+		fs.DurationVar(&v.progress, "progress", 10*time.Second, "interval of the progress reports, 0 for none")
+	}
+	if cmd == "sensitivity" {
+		sa := &x.Sensitivity
+		fs.StringVar(&sa.Method, "method", sa.Method, "design of the analysis: saltelli or morris")
+		fs.Var(sa.Factors, "factor", "range of a factor, e.g. PStartBlogging=0.05:0.3, repeatable")
+		fs.IntVar(&sa.Samples, "samples", sa.Samples, "base samples (saltelli) or trajectories (morris)")
+		fs.IntVar(&sa.Levels, "levels", sa.Levels, "grid levels of morris")
+		fs.StringVar(&sa.Output, "stat", sa.Output, "the output statistic, e.g. EchoChamberRatio or Cultures")
+		fs.IntVar(&sa.Bootstrap, "bootstrap", sa.Bootstrap, "bootstrap resamples of the confidence intervals")
+		fs.StringVar(&sa.Report, "report", sa.Report, "csv with the indices")
+	}
+	if cmd == "sweep" || cmd == "calibrate" {
+		fs.StringVar(&x.Sweep.Method, "sweep", x.Sweep.Method, "sampling of the parameter sets: grid, mc, lhs or sobol")
+		fs.IntVar(&x.Sweep.Samples, "samples", x.Sweep.Samples, "number of parameter sets")
+		fs.StringVar(&x.Output.Scores, "scores", x.Output.Scores, "csv with the score of every parameter set")
+		fs.StringVar(&x.Output.Design, "design", x.Output.Design, "json with the sample design of the sweep")
+		fs.StringVar(&x.Output.Store, "store", x.Output.Store, "store of the finished runs, a restarted sweep skips the stored runs")
+		fs.Float64Var(&x.Replication.Tolerance, "tolerance", x.Replication.Tolerance, "add replicates until the confidence half-width of a score is at most this, 0 for a fixed number")
+		fs.IntVar(&x.Replication.MaxReplicates, "max-replicates", x.Replication.MaxReplicates, "most replicates of a parameter set with -tolerance")
+		fs.Var(&x.Objectives, "objective", "further target, e.g. Cultures=5:10 for stat=target:scale, repeatable")
+		fs.StringVar(&x.Output.Front, "front", x.Output.Front, "csv with the Pareto front of the objectives")
+	}
+	if cmd == "calibrate" {
+		o := &x.Optimizer
+		fs.StringVar(&o.Method, "optimizer", o.Method, "search instead of the sweep: sa, nelder-mead or cma-es")
+		fs.IntVar(&o.Iterations, "iterations", o.Iterations, "steps, iterations or generations of the optimizer")
+		fs.IntVar(&o.Restarts, "restarts", o.Restarts, "optimizer runs from random start points after the first")
+		fs.IntVar(&o.Budget, "budget", o.Budget, "maximal evaluations of the target, 0 for no limit")
+		fs.StringVar(&o.History, "history", o.History, "csv with every evaluation of the optimizer")
+		a := &x.ABC
+		fs.StringVar(&a.Method, "abc", a.Method, "approximate Bayesian computation instead of the sweep: rejection or smc")
+		fs.IntVar(&a.Particles, "particles", a.Particles, "accepted particles per ABC generation")
+		fs.IntVar(&a.Generations, "generations", a.Generations, "generations of the smc ABC")
+		fs.StringVar(&a.Posterior, "posterior", a.Posterior, "csv with the weighted posterior sample of the ABC")
+	}
+}
 
-func simRun(traits, features, size, numAgents, runs int,
-	probveloc, steplength, sight,
-	 PLooking, PStartBlogging, PRespondBlogPost float64,
-	RSubscribedBlogs IntRange, RSimilarityConfortLevel FloatRange,
-	ret chan SimRes, rules goabm.Ruleset,
-	pfUnderstanding BPFP,
-	pfOnline, pfRead, pfRespond  NPFP) {
-
-	model := &EchoChamberModel{
-		NTraits:                 traits,
-		NFeatures:               features,
-		PVeloc:                  probveloc,
-		Steplength:              steplength,
-		PStartBlogging:          PStartBlogging,
-		RSubscribedBlogs:        RSubscribedBlogs,
-		RSimilarityConfortLevel: RSimilarityConfortLevel,
-		PFOnline: pfOnline.Pf,//dst.Beta(pfOnline.α.Var, pfOnline.β.Var),
-		PFConsumptive: pfRead.Pf,
-		PFExpressive: pfRespond.Pf,
-		
-		//PFAI: dst.Beta(pfActiveInteraction.α.Var, pfActiveInteraction.β.Var),
-		PFU: dst.Beta(pfUnderstanding.α.Var, pfUnderstanding.β.Var)}
-
-	model.Ruleset = rules
-	//fmt.Printf("rule: %v", rules)
-
-	sim := &goabm.Simulation{Landscape: &goabm.FixedLandscapeWithMovement{Size: size, NAgents: numAgents, Sight: sight},
-		Model: model, Log: goabm.Logger{StdOut: false}}
-	sim.Init()
-
-	nvar := 70
-	r := make([]float64, runs)
-	//last := 9.0
-
-        variance := 0.0
-	for i := 0; i < runs; i++ {
-		//fmt.Printf("Step #%d, Events:%d, Cultures:%d\n", i, sim.Stats.Events, model.Cultures)
-		/*if model.Cultures == 1 {
-			sim.Stop()
-		fmt.Printf("Stimulation prematurely done\n")
-				break
-			}*/
-		sim.Step()
-		t := model.EchoChamberRatio
-
-		//last = t
-		r[i] = t
-		//fmt.Printf("%d %f %d\n",c,t,runs)
-
-		// check for variance in last N steps
-		if i > nvar {
-			slidingWindow := r[i-nvar : i]
-			variance = stats.StatsSampleVariance(slidingWindow)
-			//fmt.Printf("%f\n",variance)
-
-			// if we have such a smal variance the model is considered
-			// stable and we abort computation here to save cpu resources
-			if variance < 0.00005 {
-				//fmt.Printf("stop it at %d %f\n",i, variance)
-				break
-			}
+func (v *ecm2Variant) Init(args []string) error {
+	if v.config != "" {
+		if err := v.x.Load(v.config); err != nil {
+			return err
 		}
-		if (i+1) >= runs {
-		//fmt.Printf("after %d variance did not converge.. %f\n",runs, variance)
+		// flags on the command line win over the file
+		if err := v.fs.Parse(args); err != nil {
+			return err
 		}
 	}
-	sim.Stop()
-
-	ret <- SimRes{Cultures: model.Cultures,
-		OnlineInteraction:  model.OnlineInteraction,
-		OfflineInteraction: model.OfflineInteraction,
-		TotalEchoChambers:  model.TotalEchoChambers,
-		EchoChamberRatio:   model.EchoChamberRatio,
-		Events:             sim.Stats.Events}
+	if v.cmd == "run" {
+		// a single replicate of the current values
+		v.x.Replicates, v.x.Replication.Tolerance = 1, 0
+	}
+	// validated once, a value of the file may be fixed by a flag
+	return v.x.Validate()
 }
 
+// echoes the effective configuration and sets up the target function, the
+// returned func releases it
+func (v *ecm2Variant) target(w io.Writer) (experiment.MyTarget, func(), error) {
+	x := v.x
+	mt := experiment.MyTarget{Experiment: x}
+	if v.progress > 0 {
+		mt.Progress = experiment.ProgressPrinter(os.Stderr, v.progress)
+	}
+	done := func() {}
 
+	fmt.Fprintf(w, "# effective configuration:\n")
+	if err := x.Write(w); err != nil {
+		return mt, done, err
+	}
+	if x.Output.Config != "" {
+		f, err := os.Create(x.Output.Config)
+		if err != nil {
+			return mt, done, err
+		}
+		if err := x.Write(f); err != nil {
+			f.Close()
+			return mt, done, err
+		}
+		f.Close()
+	}
 
+	if x.Output.Record != "" {
+		f, err := os.Create(x.Output.Record)
+		if err != nil {
+			return mt, done, err
+		}
+		mt.Recorder, err = NewRecorder(x.Output.RecordFormat, f)
+		if err != nil {
+			f.Close()
+			return mt, done, err
+		}
+		done = func() { f.Close() }
+	}
 
-type Parameters struct {
-	Probabilities []BPFP
-	Discrete      []DiscreteVarWithLimit
-	Ranges        []Range
-	Rules         goabm.Ruleset
+	if x.Output.Store != "" {
+		s, err := experiment.OpenStore(x.Output.Store)
+		if err != nil {
+			done()
+			return mt, func() {}, err
+		}
+		if err := s.Check(x.RunKey()); err != nil {
+			s.Close()
+			done()
+			return mt, func() {}, fmt.Errorf("%s: %v", x.Output.Store, err)
+		}
+		fmt.Fprintf(w, "# %d runs in %s\n", s.Len(), x.Output.Store)
+		mt.Store = s
+		closeRecord := done
+		done = func() {
+			s.Close()
+			closeRecord()
+		}
+	}
+	return mt, done, nil
 }
 
-type TargetFunction interface {
-	Run(Parameters) float64
+// a single run with the current values (Var) of the sweep
+func (v *ecm2Variant) Run(w io.Writer) error {
+	mt, done, err := v.target(w)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	seed := v.x.Seed
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+	return writeResult(w, mt.Simulate(v.x.Parameters(), seed))
 }
 
-type MyTarget struct {
+func (v *ecm2Variant) Sweep(w io.Writer) error {
+	_, err := v.sweep(w)
+	return err
 }
 
-func (tf MyTarget) Run(p Parameters) float64 {
-
-	/*if len(p.Probabilities) != 3 {
-	  fmt.Printf("%v",p)
-	  //return 100.0
-	  panic("len is not 3")
-	  }*/
-	//PStartBlogging := p.Probabilities[0]
-	
-	//pfOnline := p.Probabilities[0]
-	//pfActiveInteraction := p.Probabilities[1]
-	
-	/*pfOnline := BPFP{α: DiscreteVarWithLimit{Var:1.8}, 
-        β: DiscreteVarWithLimit{ Var:2.1}}
-        
-        pfRead := BPFP{α: DiscreteVarWithLimit{Var:1.8}, 
-        β: DiscreteVarWithLimit{ Var:2.1}}
-        
-        pfRespond := BPFP{α: DiscreteVarWithLimit{Var:1.8}, 
-        β: DiscreteVarWithLimit{ Var:2.1}}*/
-        
-        pfOnline := NPFP{μ: 2.7, σ: 2.0, Base: Range{Min:0, Max: 10}}
-        pfOnline.Init()
-        pfRead := NPFP{μ: 7.69, σ: 2.26 , Base: Range{Min:0, Max: 10}}
-        pfRead.Init()
-        pfRespond := NPFP{μ: 10.35, σ:5.68, Base: Range{Min:0, Max: 10} }
-        pfRespond.Init()
-        
-	pfUnderstanding := p.Probabilities[0]
-	
-	MinConfort := 0.4
-	PRespondBlogPost := 0.2
-
-	PStartBlogging := 0.1
-
-
-	features := 30
-	traits := 30
-
-	size := 200
-	numAgents := 150
-
-	runs := 400
-
-	probveloc := 0.15
-	steplength := 1.5
-	sight := 1.0
-
-	PLooking := 0.2
-
-	RSubscribedBlogs := IntRange{1, 10}
-
-	RSimilarityConfortLevel := FloatRange{MinConfort, 1}
-
-	NCPU := 2
-	innerRuns :=  2 // multiple of NCPU!
-	runtime.GOMAXPROCS(NCPU)
-	scoreSum := 0.0
-	l := make([]float64, innerRuns)
-
-	tevents := 0
-	//start := time.Now()
-
-	resc := make(chan SimRes, NCPU)
-
-	//dispatch workers
-	i := 0
-	for i < innerRuns {
-		for j := 0; j < NCPU; j++ {
-			i++
-			go simRun(traits, features, size, numAgents, runs,
-				probveloc, steplength, sight, PLooking,
-				PStartBlogging, PRespondBlogPost, RSubscribedBlogs,
-				RSimilarityConfortLevel,
-				resc, p.Rules, pfUnderstanding, pfOnline, pfRead, pfRespond)
-		}
+// scores every parameter set of the sweep and keeps the best
+func (v *ecm2Variant) sweep(w io.Writer) (*experiment.Results, error) {
+	x := v.x
+	mt, done, err := v.target(w)
+	if err != nil {
+		return nil, err
 	}
+	defer done()
 
-	for i := 0; i < innerRuns; i++ {
-
-		// collect results
-		r := <-resc
-
-		ratio := r.EchoChamberRatio
-		target := 0.64
-
-		tevents += r.Events
-		score := math.Abs(target - ratio)
-		scoreSum += score
-		l[i] = score
-		//fmt.Printf("score: %f  ratio: %f\n", score, ratio)
+	// parameter search
+	design, err := sampler.Sample(x.Sweep.Method, experiment.NewSpace(x.Parameters()), x.Sweep.Samples, x.Sweep.Seed)
+	if err != nil {
+		return nil, err
+	}
+	pars := design.Parameters
+	fmt.Fprintf(w, "size of ps: %d\n", len(pars))
+	if x.Output.Design != "" {
+		f, err := os.Create(x.Output.Design)
+		if err != nil {
+			return nil, err
+		}
+		if err := design.Write(f); err != nil {
+			f.Close()
+			return nil, err
+		}
+		if err := f.Close(); err != nil {
+			return nil, err
+		}
 	}
-	/* usedTime := time.Since(start)
-	   eps := float64(tevents) / usedTime.Seconds()
-	   fmt.Printf("%f events/s\t",eps)*/
-	return scoreSum / float64(innerRuns)
-}
 
-type NPFP struct {
-        μ  float64
-        σ float64
-        Base Range
-        f func()float64
-}
-func (n*NPFP) Init() {
-        n.f = dst.Normal(n.μ, n.σ)
-}
+	fu, err := os.Create(x.Output.Scores)
+	if err != nil {
+		return nil, err
+	}
+	defer fu.Close()
+
+	// keep n best and the front of several objectives
+	best := &experiment.Results{}
+	best.Init(x.Sweep.Keep)
+	best.Front.Size = x.Output.FrontSize
+	objectives := mt.ObjectiveNames()
+	multi := len(objectives) > 1
+
+	header := []string{"score", "sd", "lo", "hi", "n", "recommended_ec", "searched_ec"}
+	if multi {
+		header = append(header, objectives...)
+	}
+	header = append(header, design.Names...)
+	fmt.Fprintf(w, "run, %s\n", strings.Join(header, ", "))
+	if _, err := fmt.Fprintf(fu, "%s\n", strings.Join(header, ", ")); err != nil {
+		return nil, err
+	}
 
-func (n*NPFP) Pf() float64 {
-        // sample from f until in range Base
-        s := -9999.0
-               // fmt.Printf("s:%f > %f < %f",s,n.Base.Min, n.Base.Max )
-               c:=0
-        for (s < n.Base.Min || s > n.Base.Max) {
-         s = n.f()
-         c++
-        //fmt.Printf("jajaj %f",s)
-        }
-        //fmt.Printf("c:%d\n",c)
-        // normalize [0,1]
-        r := (s- n.Base.Min)/(n.Base.Max- n.Base.Min)
-        //fmt.Printf("pf()=%f\t",r)
-        return r
-}
+	// run model for each parameter, every set is written as soon as it is
+	// finished. An interrupt stops the sweep and keeps the finished sets.
+	ctx, cancel := interruptible()
+	defer cancel()
+	var werr error
+	mt.Summaries(ctx, pars, func(i int, s experiment.Summary) {
+		r := s.Mean
+		if math.IsNaN(r) || werr != nil {
+			return
+		}
+		values := []string{fmt.Sprintf("%f", r), fmt.Sprintf("%f", s.SD),
+			fmt.Sprintf("%f", s.Lo), fmt.Sprintf("%f", s.Hi), fmt.Sprintf("%d", s.N),
+			fmt.Sprintf("%f", s.RecommendedEchoChamberRatio), fmt.Sprintf("%f", s.SearchedEchoChamberRatio)}
+		if multi {
+			for _, d := range s.Objectives {
+				values = append(values, fmt.Sprintf("%f", d))
+			}
+		}
+		for _, v := range design.Values[i] {
+			values = append(values, fmt.Sprintf("%f", v))
+		}
 
-type BPFP struct {
-        α DiscreteVarWithLimit
-        β DiscreteVarWithLimit
-}
+		fmt.Fprintf(w, "%d,\t %s\n", i, strings.Join(values, ",\t "))
 
-func sampleBPF(n int,start BPFP) []BPFP {
-sizeα := start.α.Max - start.α.Min
-stepα := sizeα / (float64(n)/2)
-sizeβ := start.β.Max - start.β.Min
-stepβ := sizeβ / (float64(n)/2)
- 
- r:=  make([]BPFP, n)
- 
- c:=0
-for i := start.α.Min; i <= start.α.Max; i += stepα {
-        for j := start.β.Min; j <= start.β.Max; j += stepβ {
-          r[c].α.Var = i
-          r[c].β.Var = j
-          c++
-        }
+		res := experiment.SimRunRes{Parameters: pars[i], Score: r}
+		if multi {
+			res.Objectives = s.Objectives
+		}
+		best.Check(res)
+		if _, err := fmt.Fprintf(fu, "%s\n", strings.Join(values, ", ")); err != nil {
+			werr = err
+			cancel()
+		}
+	})
+	if werr != nil {
+		return nil, werr
+	}
+	if multi {
+		if err := writeFront(w, &best.Front, objectives, experiment.NewSpace(x.Parameters()), x.Output.Front); err != nil {
+			return nil, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return best, fmt.Errorf("sweep interrupted")
+	}
+	return best, fu.Close()
 }
-return r	
 
+// prints the size of the Pareto front and writes it to path unless it is
+// empty
+func writeFront(w io.Writer, front *experiment.Front, objectives []string, space *experiment.Space, path string) error {
+	fmt.Fprintf(w, "pareto front: %d sets of %s\n", len(front.Members), strings.Join(objectives, ", "))
+	if path == "" {
+		return nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := front.Write(f, objectives, space); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
 
-func randomBPF(start BPFP) BPFP {
- r := BPFP{}
- r.α.Var = goabm.Random(start.α.Min, start.α.Max)
-  r.β.Var = goabm.Random(start.β.Min, start.β.Max)
-  return r
+// a context which is canceled on an interrupt
+func interruptible() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		select {
+		case <-sig:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(sig)
+	}()
+	return ctx, cancel
 }
 
-func MCsampleBPF(n int,start BPFP) []BPFP {
-//sizeα := start.α.Max - start.α.Min
-//stepα := sizeα / (float64(n)/2)
-//sizeβ := start.β.Max - start.β.Min
-//stepβ := sizeβ / (float64(n)/2)
- 
- r:=  make([]BPFP, n)
- 
- c:=0
-for i := 0; i < n; i++ {
-          r[c] = randomBPF(start)
-          c++
-}
-return r	
-}
+// sweeps and reports the best parameter sets and their average
+func (v *ecm2Variant) Calibrate(w io.Writer) error {
+	if v.x.ABC.Method != "" {
+		return v.abc(w)
+	}
+	if v.x.Optimizer.Method != "" {
+		return v.optimize(w)
+	}
+	best, err := v.sweep(w)
+	if err != nil {
+		return err
+	}
 
-func samplePS(initial Parameters, samples int) []Parameters {
-	/* samples per probability */
-	//step := samples / float64(len(initial.Probabilities ))
-
-        d1 := initial.Probabilities[0].α.Max - initial.Probabilities[0].α.Min
-        d2 := initial.Probabilities[0].β.Max - initial.Probabilities[0].β.Min
-        
-        comb := math.Floor(math.Sqrt(float64(samples)))
-	step1 := d1 / comb
-	step2 := d2 / (comb)
-		
-	res := make([]Parameters, int(samples)+1)
-	
-	for i := 0; i < len(initial.Probabilities ); i++ {
-	        
-	}
-	//fmt.Printf("n: %f l: %d", n, len(res))
-	c := 0
-	//fmt.Printf("%d smaples, c: %f\n",samples, comb)
-	ci:=0
-	
-	for i := initial.Probabilities[0].α.Min; ci < int(comb); i += step1 {
-			ci++
-			cj:=0
-		for j := initial.Probabilities[0].β.Min; cj < int(comb); j += step2 {
-                        cj++
-		res[c].Probabilities = []BPFP{BPFP{α: DiscreteVarWithLimit{Var:i},β:DiscreteVarWithLimit{Var:j}}}
-		res[c].Ranges = initial.Ranges
-				res[c].Rules = initial.Rules
-				res[c].Discrete = initial.Discrete
-		c++
+	fmt.Fprintf(w, "Best res:\n")
+	// the average of every probability over the listed sets
+	var sumA, sumB []float64
+	n := 0
+	for i, r := range best.Best {
+		if r.Score > 10 {
+			// an unused slot
+			continue
 		}
-	
-	}
-	fmt.Printf("%d, %f samples\n",c,comb)
-	/*for i := initial.Probabilities[0]; i < 1.0; i += resolution {
-		for j := initial.Probabilities[1]; j < 1.0; j += resolution {
-			for k := initial.Probabilities[2]; k < 1.0; k += resolution {
-				//fmt.Printf("c: %d, i:%f j:%f, k:%f\n", c, i, j, k)
-				res[c].Probabilities = []float64{i, j, k}
-
-				res[c].Ranges = initial.Ranges
-				res[c].Rules = initial.Rules
-				res[c].Discrete = initial.Discrete
-				c++
-			}
+		if sumA == nil {
+			sumA = make([]float64, len(r.Probabilities))
+			sumB = make([]float64, len(r.Probabilities))
+		}
+		fmt.Fprintf(w, "#%d, score: %f\t", i, r.Score)
+		for k, p := range r.Probabilities {
+			fmt.Fprintf(w, " (α: %.2f,β: %.2f),", p.Alpha.Var, p.Beta.Var)
+			sumA[k] += p.Alpha.Var
+			sumB[k] += p.Beta.Var
 		}
-	}*/
-	return res[:c]
+		fmt.Fprintf(w, "\n")
+		n++
+	}
+	if n == 0 {
+		fmt.Fprintf(w, "no parameter set finished\n")
+		return nil
+	}
+	fmt.Fprintf(w, "avg:\t")
+	for k := range sumA {
+		fmt.Fprintf(w, " (α: %.2f,β: %.2f),", sumA[k]/float64(n), sumB[k]/float64(n))
+	}
+	fmt.Fprintf(w, "\t \n")
+	return nil
 }
-        
-func MCsamplePS(initial Parameters, samples int) []Parameters {
-
-/*
- draw approximately n samples for each PDF
-*/
-
-//fmt.Printf("mc sample:%d\n",n)
-res := make([]Parameters, int(samples))
-c:=0
-for i := 0; i < samples; i++ {
-	        
-
-          
-		                // use prefedined ranges
-		                res[c].Probabilities = []BPFP{
-		                randomBPF(initial.Probabilities[0]),
-		                randomBPF(initial.Probabilities[1]),
-		                randomBPF(initial.Probabilities[2])}	                
-		                
-				res[c].Ranges = initial.Ranges
-				res[c].Rules = initial.Rules
-				res[c].Discrete = initial.Discrete
-				c++
-	        
-	}
-
-return res[:c]
 
+// the optimizer of the experiment, restarted from random points if asked
+func optimizer(o experiment.Optimizer) optimize.Optimizer {
+	var opt optimize.Optimizer
+	switch o.Method {
+	case "sa":
+		// scores are distances of ratios, cool down to a hundredth
+		opt = optimize.SimulatedAnnealing{Temp: 0.1, KMax: o.Iterations,
+			CoolingRate: 1 - math.Pow(0.01, 1/float64(o.Iterations))}
+	case "nelder-mead":
+		opt = optimize.NelderMead{MaxIter: o.Iterations, Tol: 1e-4}
+	case "cma-es":
+		opt = optimize.CMAES{MaxGen: o.Iterations, Tol: 1e-4}
+	}
+	if o.Restarts > 0 {
+		opt = optimize.RandomRestarts{Local: opt, Restarts: o.Restarts}
+	}
+	return opt
 }
 
-type Results struct {
-  Best []SimRunRes
-}
+// searches the probabilities of the sweep with the optimizer
+func (v *ecm2Variant) optimize(w io.Writer) error {
+	x := v.x
+	mt, done, err := v.target(w)
+	if err != nil {
+		return err
+	}
+	defer done()
 
-func (r *Results) Init(n int) { 
- r.Best = make([]SimRunRes,n)
+	seed := x.Seed
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+	e := optimize.NewEvaluator(mt, x.Parameters(), seed)
+	e.Repeats, e.MaxRepeats = x.Optimizer.Repeats, x.Optimizer.MaxRepeats
+	e.Budget = x.Optimizer.Budget
+	e.FrontSize = x.Output.FrontSize
+	r := optimizer(x.Optimizer).Minimize(e, nil)
+
+	if x.Optimizer.History != "" {
+		f, err := os.Create(x.Optimizer.History)
+		if err != nil {
+			return err
+		}
+		if err := e.WriteHistory(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
 
- for i:= range r.Best {
-  r.Best[i].Score = 9999.9
- }
-  //fmt.Printf("b: %v , len %d\n",r, len(r.Best))
-}
-func (r *Results) Check(nr SimRunRes) { 
-//fmt.Printf("best: %v\n",r.Best)
-if nr.Score < r.Best[len(r.Best)-1].Score {
-// better score than worst
-r.Best[len(r.Best)-1] = nr
-sort.Sort(ByScore(r.Best))
+	fmt.Fprintf(w, "%d evaluations\n", r.Evaluations)
+	names := e.Space.Names()
+	for i, p := range r.Candidates {
+		fmt.Fprintf(w, "#%d, score: %f (%d runs)\t", i, p.Score(), len(p.Scores))
+		for j, value := range e.Space.Values(p.X) {
+			fmt.Fprintf(w, " %s: %.2f", names[j], value)
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	if objectives := e.ObjectiveNames(); len(objectives) > 1 {
+		return writeFront(w, &r.Front, objectives, e.Space, x.Output.Front)
+	}
+	return nil
 }
 
-}
+// the posterior of the sweep probabilities and the probability functions by
+// approximate Bayesian computation
+func (v *ecm2Variant) abc(w io.Writer) error {
+	x := v.x
+	mt, done, err := v.target(w)
+	if err != nil {
+		return err
+	}
+	defer done()
 
-type SimRunRes struct {
- Parameters
- Score float64
+	stats, err := abc.Stats(x)
+	if err != nil {
+		return err
+	}
+	priors := abc.Priors(x)
+	sim, err := abc.ExperimentSimulator(mt, priors)
+	if err != nil {
+		return err
+	}
+	seed := x.Seed
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+	a := &abc.ABC{Priors: priors, Stats: stats, Simulate: sim,
+		Executor: mt.Executor(), Rand: rand.New(rand.NewSource(seed)),
+		Particles: x.ABC.Particles, Quantile: x.ABC.Quantile,
+		Generation: func(p *abc.Posterior) { p.Summary(w, x.ABC.Level) }}
+
+	ctx, cancel := interruptible()
+	defer cancel()
+	var post *abc.Posterior
+	if x.ABC.Method == "rejection" {
+		post, err = a.Rejection(ctx)
+	} else {
+		post, err = a.SMC(ctx, x.ABC.Generations)
+	}
+	if post != nil && x.ABC.Posterior != "" {
+		f, ferr := os.Create(x.ABC.Posterior)
+		if ferr != nil {
+			return ferr
+		}
+		if ferr := post.Write(f); ferr != nil {
+			f.Close()
+			return ferr
+		}
+		if ferr := f.Close(); ferr != nil {
+			return ferr
+		}
+	}
+	return err
 }
 
-type ByScore []SimRunRes
-
-func (a ByScore) Len() int           { return len(a) }
-func (a ByScore) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a ByScore) Less(i, j int) bool { return a[i].Score < a[j].Score }
-
-func main() {
-
-//initialize the goabm library (logs & flags)
-	goabm.Init()
+// sensitivity of the output statistic to the factors
+func (v *ecm2Variant) Sensitivity(w io.Writer) error {
+	x := v.x
+	sa := x.Sensitivity
+	if len(sa.Factors) == 0 {
+		return fmt.Errorf("no factors, set them with -factor or Sensitivity.Factors")
+	}
+	mt, done, err := v.target(w)
+	if err != nil {
+		return err
+	}
+	defer done()
 
-var memprofile = flag.String("memprofile", "", "write memory profile to this file")
-	var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
+	names := sa.Factors.Names()
+	factors, err := experiment.NewFactors(names)
+	if err != nil {
+		return err
+	}
+	stat, err := experiment.Statistic(sa.Output)
+	if err != nil {
+		return err
+	}
+	seed := x.Seed
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+	a := &sensitivity.Analysis{Executor: mt.Executor(), Replicates: x.Replicates,
+		Rand: rand.New(rand.NewSource(seed)), Bootstrap: sa.Bootstrap, Level: sa.Level,
+		Model: func(ctx context.Context, values []float64, seed int64) (float64, error) {
+			t, p := factors.Apply(mt, values)
+			r, err := t.SimulateContext(ctx, p, seed)
+			return stat(r), err
+		}}
+	for _, name := range names {
+		a.Factors = append(a.Factors, sensitivity.Factor{Name: name, Min: sa.Factors[name][0], Max: sa.Factors[name][1]})
+	}
 
-	flag.Parse()
+	ctx, cancel := interruptible()
+	defer cancel()
+	var ix *sensitivity.Indices
+	if sa.Method == "saltelli" {
+		ix, err = a.Saltelli(ctx, sa.Samples)
+	} else {
+		ix, err = a.Morris(ctx, sa.Samples, sa.Levels)
+	}
+	if err != nil {
+		return err
+	}
 
-	if *cpuprofile != "" {
-		f, err := os.Create(*cpuprofile)
+	fmt.Fprintf(w, "sensitivity of %s:\n", sa.Output)
+	ix.Print(w)
+	if sa.Report != "" {
+		f, err := os.Create(sa.Report)
 		if err != nil {
-			log.Fatal(err)
-		}
-		pprof.StartCPUProfile(f)
-		defer pprof.StopCPUProfile()
-	}
-	
-//f := dst.Beta(2.0,2.0)
-
-	
-
-	/*
-	    combine MC with Sa?
-	   inner loop is MC sampling?
-	*/
-	//fmt.Printf("#Parameter search...\n");
-
-	//fmt.Printf("%d, %d, %d, %d, %d, %f, %f\n", traits, features, r.CultureDiff, r.OnlineCultures, r.OfflineCultures, r.AvgOnline, r.AvgOffline)
-	/*
-		PStartBlogging := 0.005
-		PWriteBlogPost := 0.2
-		PRespondBlogPost := 0.1
-	*/
-	/*
-		// simulated annealing
-		p := Parameters{
-			Probabilities: []float64{PStartBlogging,
-				PWriteBlogPost,
-				PRespondBlogPost},
+			return err
 		}
-
-		sa := SimulatedAnnealing{Temp: 100, CoolingRate: 0.01, KMax: 400}
-		sa.Parameters = p
-		sa.TF = mt
-		r := sa.Run()
-		fmt.Printf("best score %f for %v\n", r.Energy, r)*/
-	rules := goabm.Ruleset{}
-	rules.Init()
-	rules.SetRule("movement", true)            // not implemented
-	rules.SetRule("transmission_error", false) // not implemented
-	rules.SetRule("only_stable_models", false) // not implemented
-
-        /*
-        pf(Online) = α, β
-        pf(ActiveInteraction) = α, β
-        pf(Understanding) = α, β
-        */
-        
-        
-        /*pfOnline := BPFP{α: DiscreteVarWithLimit{Min:2.5, Max: 12, Var:1.5}, 
-        β: DiscreteVarWithLimit{Min:0.1, Max: 7, Var:2}}
-        
-        pfActiveInteraction := BPFP{α: DiscreteVarWithLimit{Min:0.5, Max: 7, Var:2} , 
-        β: DiscreteVarWithLimit{Min:0.5, Max: 9, Var:2}}*/
-        
-        pfUnderstanding := BPFP{α: DiscreteVarWithLimit{Min:300, Max: 1150, Var:1.8}, 
-        β: DiscreteVarWithLimit{Min:1, Max: 500, Var:2.1}}     
-        
-        /*
-        search space = 6 dimensions
-        */
-
-	p := Parameters{Probabilities: []BPFP{/*pfOnline,pfActiveInteraction,*/pfUnderstanding,pfUnderstanding,pfUnderstanding}, Rules: rules}
-	
-	// parameter search
-	res:= 55
-	samples := res * res // best multiple of N^2
-
-	pars := samplePS(p, samples)
-	fmt.Printf("size of ps: %d", len(pars))
-	mt := MyTarget{}
-	// run model for each parameter
-	fmt.Printf("run, score, pfOnline, pfActiveInteraction, pfUnderstanding\n")
-	
-	// keep n best
-	keep:= 15
-	
-	best := Results{}
-	best.Init(keep)
-	
-	
-	
-	fon,_ := os.Create("pfOnline.csv")
-	fai,_ := os.Create("pfAI.csv")
-	fu,_ := os.Create("pfU.csv")
-	
-	 fmt.Fprintf(fon,"score, α, β\n")
-	 fmt.Fprintf(fai,"score, α, β\n")
-	 fmt.Fprintf(fu,"score, α, β\n")
-            
-	for i, p := range pars {
-		/*if 0 == p.Probabilities[0] {
-		continue
-		}*/
-		r := mt.Run(p)
-
-/*
-		fmt.Printf("%d,\t %f,\t (α: %.2f,β: %.2f),\t (α: %.2f,β: %.2f),\t (α: %.2f,β: %.2f)\n", i, r, 
-		p.Probabilities[0].α.Var, p.Probabilities[0].β.Var,
-		p.Probabilities[1].α.Var, p.Probabilities[1].β.Var,
-		p.Probabilities[2].α.Var, p.Probabilities[2].β.Var,)*/
-		
-		fmt.Printf("%d,\t %f,\t (α: %.2f,β: %.2f),\t \n", i, r, 
-		p.Probabilities[0].α.Var, p.Probabilities[0].β.Var)
-		
-		srr := SimRunRes{Parameters: p, Score: r}
-		best.Check(srr)
-                
-                //fmt.Fprintf(fon,"%f, %f, %f\n",r, p.Probabilities[0].α.Var, p.Probabilities[0].β.Var)
-               // fmt.Fprintf(fai,"%f, %f, %f\n",r, p.Probabilities[1].α.Var, p.Probabilities[2].β.Var)
-                fmt.Fprintf(fu,"%f, %f, %f\n",r, p.Probabilities[0].α.Var, p.Probabilities[0].β.Var)
-                
-	}
-	
-	/*ioutil.WriteFile("pfOnline.csv",[]byte(logpfOnline),0777)
-		ioutil.WriteFile("pfAI.csv",[]byte(logpfActiveInteraction),0777)
-			ioutil.WriteFile("pfU.csv",[]byte(logpfUnderstanding),0777)/*/
-			
-	fmt.Printf("Best res:\n")
-	
-	asA1:=0.0
-	asB1:=0.0
-	/*	asA2:=0.0
-	asB2:=0.0
-		asA3:=0.0
-	asB3:=0.0*/
-	
-	for i:=0;i<keep;i++ {
-	if best.Best[i].Score > 10 {
-	continue
-	}
-	/* fmt.Printf("#%d, score: %f\t (α: %.2f,β: %.2f),\t (α: %.2f,β: %.2f),\t (α: %.2f,β: %.2f)\n",i, best.Best[i].Score,
-	 best.Best[i].Probabilities[0].α.Var, best.Best[i].Probabilities[0].β.Var,
-		best.Best[i].Probabilities[1].α.Var, best.Best[i].Probabilities[1].β.Var,
-		best.Best[i].Probabilities[2].α.Var, best.Best[i].Probabilities[2].β.Var,)*/
-		
-	fmt.Printf("#%d, score: %f\t (α: %.2f,β: %.2f),\n",i, best.Best[i].Score,
-	 best.Best[i].Probabilities[0].α.Var, best.Best[i].Probabilities[0].β.Var)
-		
-	asA1 += best.Best[i].Probabilities[0].α.Var
-	//	asA2 += best.Best[i].Probabilities[1].α.Var
-	//		asA3 += best.Best[i].Probabilities[2].α.Var
-			
-	asB1 += best.Best[i].Probabilities[0].β.Var
-	//	asB2 += best.Best[i].Probabilities[1].β.Var
-	//		asB3 += best.Best[i].Probabilities[2].β.Var
-	}
-	
-	avgA1 := asA1 / float64(keep)
-	//avgA2 := asA2 / float64(keep)
-	//avgA3 := asA3 / float64(keep)
-	avgB1 := asB1 / float64(keep)
-	//avgB2 := asB2 / float64(keep)
-	//avgB3 := asB3 / float64(keep)
-	
-
-	/*fmt.Printf("avg:\t (α: %.2f,β: %.2f),\t (α: %.2f,β: %.2f),\t (α: %.2f,β: %.2f)\n",
-	avgA1,avgB1, avgA2, avgB2, avgA3, avgB3)*/
-	fmt.Printf("avg:\t (α: %.2f,β: %.2f),\t \n",
-	avgA1,avgB1)
-	
-	
-	if *memprofile != "" {
-		f, err := os.Create(*memprofile)
-		if err != nil {
-			log.Fatal(err)
+		if err := ix.Write(f); err != nil {
+			f.Close()
+			return err
 		}
-		pprof.WriteHeapProfile(f)
-		f.Close()
-		return
+		return f.Close()
 	}
+	return nil
 }
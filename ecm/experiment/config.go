@@ -0,0 +1,610 @@
+package experiment
+
+import "goabm"
+import "encoding/json"
+import "fmt"
+import "io"
+import "io/ioutil"
+import "path/filepath"
+import "strconv"
+import "strings"
+
+import . "flache/ecm/model"
+
+import "gopkg.in/yaml.v2"
+
+// Experiment describes a complete parameter study of ecm2. It is read from
+// a .json or .yaml file, the keys are the field names (case insensitive),
+// missing keys keep the values of DefaultExperiment.
+type Experiment struct {
+	// cultures: number of features and traits per feature
+	Features int
+	Traits   int
+
+	// landscape and population
+	Size   int
+	Agents int
+	Sight  float64
+	// maximal number of steps of a run
+	Steps int
+
+	// movement
+	PVeloc     float64
+	Steplength float64
+
+	// blogging parameters
+	PLooking         float64
+	PStartBlogging   float64
+	PRespondBlogPost float64
+	RSubscribedBlogs IntRange
+
+	// probability functions of going online, reading and responding
+	POnline  NormalPF
+	PRead    NormalPF
+	PRespond NormalPF
+
+	// per agent distributions of the agent parameters, either a spec
+	// object or the short form, e.g. "PVeloc": "beta(2,5)"
+	Distributions Distributions
+
+	// switches of the goabm ruleset
+	Rules map[string]bool
+
+	// similarity metric of the model: exact, weighted, ordinal or jaccard
+	Similarity string
+	// per feature salience for the weighted metric
+	Salience []float64
+	// blog discovery: most-similar, similarity-weighted, popularity, random or serendipity
+	Discovery string
+	// share of searches sent to dissimilar blogs by the serendipity policy
+	Serendipity float64
+	// blogs below this similarity count as dissimilar for serendipity
+	SerendipityThreshold float64
+	// probability to take a subscription from the recommender instead of searching
+	PRecommend float64
+
+	// comfort zone [MinConfort, MaxConfort] and how agents check it
+	MinConfort      float64
+	MaxConfort      float64
+	UnsubscribeRate float64
+	ComfortWindow   int
+	Boredom         bool
+
+	// calibration target: metric (see MetricNames) and the echo chamber
+	// ratio it should reach
+	TargetMetric string
+	Target       float64
+	// further targets, a calibration of several objectives keeps the Pareto
+	// front of their distances
+	Objectives Objectives
+	// thresholds of the metrics
+	Metrics *MetricsConfig
+
+	// the parameter study
+	Sweep Sweep
+	// search of calibrate, replaces the sweep if it has a method
+	Optimizer Optimizer
+	// approximate Bayesian computation of calibrate, replaces the sweep
+	// and the optimizer if it has a method
+	ABC ABC
+	// the sensitivity analysis
+	Sensitivity Sensitivity
+	// runs per parameter set and how many run in parallel
+	Replicates int
+	CPUs       int
+	// more runs of a parameter set until its score is precise enough
+	Replication Replication
+	// seconds a single run may take, 0 for no limit
+	Timeout float64
+	// when a run stops before Steps
+	Stopping Stopping
+	// seed of the first replicate, the others follow in sequence. 0 draws a
+	// fresh seed for every replicate.
+	Seed int64
+
+	Output Output
+}
+
+// a normal probability function, samples in [Min, Max] are mapped to [0, 1]
+type NormalPF struct {
+	Mu    float64
+	Sigma float64
+	Min   float64
+	Max   float64
+}
+
+func (n NormalPF) NPFP() NPFP {
+	p := NPFP{Mu: n.Mu, Sigma: n.Sigma, Base: Range{Min: n.Min, Max: n.Max}}
+	p.Init()
+	return p
+}
+
+// the sampled dimensions of a parameter study
+type Sweep struct {
+	// grid, mc, lhs or sobol (see package sampler)
+	Method  string
+	Samples int
+	// seed of the mc and lhs designs, a restarted sweep draws the same sets
+	Seed int64
+	// number of best parameter sets reported
+	Keep int
+	// beta probability functions, the sample spans the α and β of all
+	Probabilities []BetaRange
+}
+
+// a search over the probabilities of the sweep, see package optimize
+type Optimizer struct {
+	// sa, nelder-mead or cma-es, empty to calibrate with the sweep
+	Method string
+	// steps, iterations or generations of the method
+	Iterations int
+	// runs from random start points after the first
+	Restarts int
+	// runs of the target function, 0 for no limit
+	Budget int
+	// runs of a new point and of a point which is evaluated again
+	Repeats    int
+	MaxRepeats int
+	// csv with every run of the target function, empty for none
+	History string
+}
+
+// approximate Bayesian computation over the probabilities of the sweep and
+// further factors, see package abc
+type ABC struct {
+	// rejection or smc, empty to calibrate otherwise
+	Method string
+	// accepted particles of a generation
+	Particles int
+	// share of the simulations of a generation which is accepted
+	Quantile float64
+	// generations of smc
+	Generations int
+	// observed summary statistics (see Statistic), e.g. "EchoChamberRatio": 0.64
+	Observed map[string]float64
+	// uniform priors of further factors (see SetterOf), e.g. "POnline.Mu":
+	// [0.3, 0.8]. The α and β of the sweep probabilities range over their
+	// Min and Max.
+	Priors FactorRanges
+	// credibility of the reported intervals
+	Level float64
+	// csv with the weighted posterior sample, empty for none
+	Posterior string
+}
+
+// a global sensitivity analysis of an output statistic, see package
+// sensitivity
+type Sensitivity struct {
+	// saltelli or morris
+	Method string
+	// ranges of the factors (see SetterOf), e.g. "PStartBlogging": [0.05, 0.3]
+	Factors FactorRanges
+	// base samples of saltelli, trajectories of morris
+	Samples int
+	// grid levels of morris
+	Levels int
+	// the output statistic (see Statistic)
+	Output string
+	// bootstrap resamples and the level of the confidence intervals
+	Bootstrap int
+	Level     float64
+	// csv with the indices, empty for none
+	Report string
+}
+
+type BetaRange struct {
+	Alpha DiscreteVarWithLimit
+	Beta  DiscreteVarWithLimit
+}
+
+type Output struct {
+	// csv with the score of every parameter set
+	Scores string
+	// the effective configuration as json, empty to only print it
+	Config string
+	// stats of every step (csv or jsonl), empty to record nothing
+	Record       string
+	RecordFormat string
+	Checkpoints  Checkpoints
+	Export       Export
+	// append-only store of the finished runs, a sweep resumes from it
+	Store string
+	// json with the sample design of the sweep, empty for none
+	Design string
+	// csv with the Pareto front of the objectives, empty for none, and its
+	// largest size, 0 keeps all
+	Front     string
+	FrontSize int
+}
+
+// the experiment ecm2 ran before it could be configured
+func DefaultExperiment() *Experiment {
+	return &Experiment{
+		Features: 30, Traits: 30,
+		Size: 200, Agents: 150, Sight: 1,
+		Steps:      400,
+		PVeloc:     0.15,
+		Steplength: 1.5,
+
+		PLooking:         0.2,
+		PStartBlogging:   0.1,
+		PRespondBlogPost: 0.2,
+		RSubscribedBlogs: IntRange{1, 10},
+
+		POnline:  NormalPF{Mu: 2.7, Sigma: 2.0, Min: 0, Max: 10},
+		PRead:    NormalPF{Mu: 7.69, Sigma: 2.26, Min: 0, Max: 10},
+		PRespond: NormalPF{Mu: 10.35, Sigma: 5.68, Min: 0, Max: 10},
+
+		Distributions: Distributions{},
+		Rules: map[string]bool{
+			"movement":           true,  // agents move with probability PVeloc
+			"transmission_error": false, // an interaction may change a random trait instead of copying
+			"only_stable_models": false, // runs no stop condition found stable fail
+			"threaded_replies":   false, // reply to the last comment read instead of the post
+		},
+
+		Similarity:           "exact",
+		Discovery:            "most-similar",
+		Serendipity:          0.2,
+		SerendipityThreshold: 0.5,
+
+		MinConfort:      0.4,
+		MaxConfort:      1,
+		UnsubscribeRate: 0.4,
+		ComfortWindow:   1,
+
+		TargetMetric: MetricApproval,
+		Target:       0.64,
+		Metrics:      DefaultMetricsConfig(),
+
+		Sweep: Sweep{Method: "grid", Samples: 55 * 55, Keep: 15, Seed: 1,
+			Probabilities: []BetaRange{{
+				Alpha: DiscreteVarWithLimit{Min: 300, Max: 1150, Var: 1.8},
+				Beta:  DiscreteVarWithLimit{Min: 1, Max: 500, Var: 2.1}}}},
+		Optimizer: Optimizer{Iterations: 200, Repeats: 1, MaxRepeats: 5},
+		ABC: ABC{Particles: 100, Quantile: 0.2, Generations: 5,
+			Observed: map[string]float64{"EchoChamberRatio": 0.64}, Level: 0.95},
+		Sensitivity: Sensitivity{Method: "saltelli", Factors: FactorRanges{},
+			Samples: 256, Levels: 4, Output: "EchoChamberRatio", Bootstrap: 1000, Level: 0.95},
+		Replicates:  2,
+		CPUs:        2,
+		Replication: Replication{MaxReplicates: 20, Level: 0.95},
+		Stopping: Stopping{Variance: []VarianceStop{
+			{Stat: "EchoChamberRatio", Window: 70, Threshold: 0.00005}}},
+
+		Output: Output{Scores: "pfU.csv", Design: "pfU.design.json", RecordFormat: "csv",
+			Checkpoints: Checkpoints{Every: 100}},
+	}
+}
+
+// reads the experiment file over the current values, the caller validates
+// the result once everything else (e.g. flags) is applied
+func (x *Experiment) Load(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+	case ".yaml", ".yml":
+		if data, err = yamlToJSON(data); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	default:
+		return fmt.Errorf("%s: unknown config format, use .json or .yaml", path)
+	}
+
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(x); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// yaml is decoded through json, so both formats share the field names and
+// the decoding of distributions
+func yamlToJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	v, err := jsonValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func jsonValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			e, err := jsonValue(e)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(k)] = e
+		}
+		return m, nil
+	case []interface{}:
+		for i, e := range v {
+			e, err := jsonValue(e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = e
+		}
+	}
+	return v, nil
+}
+
+var knownRules = []string{"movement", "transmission_error", "only_stable_models", "threaded_replies"}
+
+// checks the experiment before anything is run
+func (x *Experiment) Validate() error {
+	positive := []struct {
+		name string
+		v    int
+	}{{"Features", x.Features}, {"Traits", x.Traits}, {"Size", x.Size},
+		{"Agents", x.Agents}, {"Steps", x.Steps}, {"ComfortWindow", x.ComfortWindow},
+		{"Sweep.Samples", x.Sweep.Samples}, {"Sweep.Keep", x.Sweep.Keep},
+		{"Replicates", x.Replicates}, {"CPUs", x.CPUs}}
+	for _, p := range positive {
+		if p.v <= 0 {
+			return fmt.Errorf("%s must be positive, got %d", p.name, p.v)
+		}
+	}
+
+	probabilities := []struct {
+		name string
+		v    float64
+	}{{"PVeloc", x.PVeloc}, {"PLooking", x.PLooking}, {"PStartBlogging", x.PStartBlogging},
+		{"PRespondBlogPost", x.PRespondBlogPost}, {"Serendipity", x.Serendipity},
+		{"SerendipityThreshold", x.SerendipityThreshold},
+		{"PRecommend", x.PRecommend}, {"UnsubscribeRate", x.UnsubscribeRate}}
+	for _, p := range probabilities {
+		if p.v < 0 || p.v > 1 {
+			return fmt.Errorf("%s must be a probability, got %g", p.name, p.v)
+		}
+	}
+
+	if x.Agents > x.Size*x.Size {
+		return fmt.Errorf("%d agents do not fit on a %dx%d landscape", x.Agents, x.Size, x.Size)
+	}
+	if x.RSubscribedBlogs[0] < 0 || x.RSubscribedBlogs[0] > x.RSubscribedBlogs[1] {
+		return fmt.Errorf("invalid RSubscribedBlogs %v", x.RSubscribedBlogs)
+	}
+	if x.MinConfort > x.MaxConfort {
+		return fmt.Errorf("MinConfort %g > MaxConfort %g", x.MinConfort, x.MaxConfort)
+	}
+
+	for name, n := range map[string]NormalPF{"POnline": x.POnline, "PRead": x.PRead, "PRespond": x.PRespond} {
+		if n.Sigma <= 0 || n.Min >= n.Max {
+			return fmt.Errorf("%s: needs Sigma > 0 and Min < Max, got %+v", name, n)
+		}
+	}
+
+	if err := (&EchoChamberModel{Distributions: x.Distributions}).ValidateDistributions(); err != nil {
+		return err
+	}
+
+	for name := range x.Rules {
+		if !contains(knownRules, name) {
+			return fmt.Errorf("unknown rule %q, known: %s", name, strings.Join(knownRules, ", "))
+		}
+	}
+
+	similarities := []string{"exact", "weighted", "ordinal", "jaccard"}
+	if !contains(similarities, x.Similarity) {
+		return fmt.Errorf("unknown similarity metric %q, known: %s", x.Similarity, strings.Join(similarities, ", "))
+	}
+	if x.Similarity == "weighted" && len(x.Salience) > 0 {
+		if len(x.Salience) != x.Features {
+			return fmt.Errorf("%d salience weights for %d features", len(x.Salience), x.Features)
+		}
+		total := 0.0
+		for _, w := range x.Salience {
+			if w < 0 {
+				return fmt.Errorf("negative salience %g", w)
+			}
+			total += w
+		}
+		if total == 0 {
+			return fmt.Errorf("every salience weight is 0")
+		}
+	}
+	discoveries := []string{"most-similar", "similarity-weighted", "popularity", "random", "serendipity"}
+	if !contains(discoveries, x.Discovery) {
+		return fmt.Errorf("unknown discovery policy %q, known: %s", x.Discovery, strings.Join(discoveries, ", "))
+	}
+	if !contains(MetricNames, x.TargetMetric) {
+		return fmt.Errorf("unknown target metric %q, known: %s", x.TargetMetric, strings.Join(MetricNames, ", "))
+	}
+	if x.Metrics == nil {
+		return fmt.Errorf("missing Metrics")
+	}
+	if !contains(MetricNames, x.Metrics.Primary) {
+		return fmt.Errorf("unknown Metrics.Primary %q, known: %s", x.Metrics.Primary, strings.Join(MetricNames, ", "))
+	}
+	for i, o := range x.Objectives {
+		if _, err := Statistic(o.Stat); err != nil {
+			return fmt.Errorf("Objectives[%d]: %v", i, err)
+		}
+		if o.Scale < 0 {
+			return fmt.Errorf("Objectives[%d]: Scale must not be negative", i)
+		}
+	}
+
+	methods := []string{"grid", "mc", "lhs", "sobol"}
+	if !contains(methods, x.Sweep.Method) {
+		return fmt.Errorf("unknown sweep method %q, known: %s", x.Sweep.Method, strings.Join(methods, ", "))
+	}
+	if len(x.Sweep.Probabilities) < 1 {
+		return fmt.Errorf("the sweep needs a probability function")
+	}
+	for i, b := range x.Sweep.Probabilities {
+		if b.Alpha.Min <= 0 || b.Alpha.Min >= b.Alpha.Max || b.Beta.Min <= 0 || b.Beta.Min >= b.Beta.Max {
+			return fmt.Errorf("Sweep.Probabilities[%d]: needs 0 < Min < Max for α and β", i)
+		}
+	}
+	optimizers := []string{"", "sa", "nelder-mead", "cma-es"}
+	if !contains(optimizers, x.Optimizer.Method) {
+		return fmt.Errorf("unknown optimizer %q, known: sa, nelder-mead, cma-es", x.Optimizer.Method)
+	}
+	if o := x.Optimizer; o.Method != "" {
+		if o.Iterations < 1 || o.Repeats < 1 || o.MaxRepeats < o.Repeats {
+			return fmt.Errorf("Optimizer: needs Iterations >= 1 and 1 <= Repeats <= MaxRepeats")
+		}
+		if o.Restarts < 0 || o.Budget < 0 {
+			return fmt.Errorf("Optimizer: Restarts and Budget must not be negative")
+		}
+	}
+	if a := x.ABC; a.Method != "" {
+		if a.Method != "rejection" && a.Method != "smc" {
+			return fmt.Errorf("unknown ABC method %q, known: rejection, smc", a.Method)
+		}
+		if a.Particles < 1 || a.Quantile <= 0 || a.Quantile > 1 || a.Generations < 1 {
+			return fmt.Errorf("ABC: needs Particles >= 1, 0 < Quantile <= 1 and Generations >= 1")
+		}
+		if len(a.Observed) == 0 {
+			return fmt.Errorf("ABC: no observed statistics")
+		}
+		for name := range a.Observed {
+			if _, err := Statistic(name); err != nil {
+				return fmt.Errorf("ABC.Observed: %v", err)
+			}
+		}
+		for name, r := range a.Priors {
+			if _, err := SetterOf(name); err != nil {
+				return fmt.Errorf("ABC.Priors: %v", err)
+			}
+			if r[0] >= r[1] {
+				return fmt.Errorf("ABC.Priors[%s]: needs Min < Max", name)
+			}
+		}
+		if a.Level <= 0 || a.Level >= 1 {
+			return fmt.Errorf("ABC.Level must be in (0, 1), got %g", a.Level)
+		}
+	}
+	sa := x.Sensitivity
+	if sa.Method != "saltelli" && sa.Method != "morris" {
+		return fmt.Errorf("unknown sensitivity method %q, known: saltelli, morris", sa.Method)
+	}
+	if sa.Samples < 2 || sa.Levels < 2 || sa.Bootstrap < 0 {
+		return fmt.Errorf("Sensitivity: needs Samples >= 2, Levels >= 2 and Bootstrap >= 0")
+	}
+	if sa.Level <= 0 || sa.Level >= 1 {
+		return fmt.Errorf("Sensitivity.Level must be in (0, 1), got %g", sa.Level)
+	}
+	if _, err := Statistic(sa.Output); err != nil {
+		return fmt.Errorf("Sensitivity.Output: %v", err)
+	}
+	for name, r := range sa.Factors {
+		if _, err := SetterOf(name); err != nil {
+			return fmt.Errorf("Sensitivity.Factors: %v", err)
+		}
+		if r[0] >= r[1] {
+			return fmt.Errorf("Sensitivity.Factors[%s]: needs Min < Max", name)
+		}
+	}
+	if x.Replication.Tolerance < 0 {
+		return fmt.Errorf("Replication.Tolerance must not be negative, got %g", x.Replication.Tolerance)
+	}
+	if rep := x.Replication; rep.Tolerance > 0 {
+		if rep.MaxReplicates < x.Replicates {
+			return fmt.Errorf("Replication.MaxReplicates %d < Replicates %d", rep.MaxReplicates, x.Replicates)
+		}
+		if rep.Level <= 0 || rep.Level >= 1 {
+			return fmt.Errorf("Replication.Level must be in (0, 1), got %g", rep.Level)
+		}
+	}
+	if err := x.Stopping.validate(); err != nil {
+		return err
+	}
+	if x.Timeout < 0 {
+		return fmt.Errorf("Timeout must not be negative, got %g", x.Timeout)
+	}
+
+	if x.Output.Scores == "" {
+		return fmt.Errorf("missing Output.Scores")
+	}
+	if x.Output.FrontSize < 0 {
+		return fmt.Errorf("Output.FrontSize must not be negative")
+	}
+	if x.Output.RecordFormat != "csv" && x.Output.RecordFormat != "jsonl" {
+		return fmt.Errorf("unknown record format %q, known: csv, jsonl", x.Output.RecordFormat)
+	}
+	if x.Output.Checkpoints.Dir != "" && x.Output.Checkpoints.Every <= 0 {
+		return fmt.Errorf("Output.Checkpoints.Every must be positive")
+	}
+	// a restored run takes the seed of the checkpoint, further replicates
+	// would repeat it
+	if x.Output.Checkpoints.Restore != "" && (x.Replicates != 1 || x.Replication.Tolerance > 0) {
+		return fmt.Errorf("Output.Checkpoints.Restore continues a single run, it needs Replicates 1 and no Replication.Tolerance")
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// the goabm ruleset of the experiment
+func (x *Experiment) Ruleset() goabm.Ruleset {
+	rules := goabm.Ruleset{}
+	rules.Init()
+	for name, active := range x.Rules {
+		rules.SetRule(name, active)
+	}
+	return rules
+}
+
+// the parameter space spanned by the sweep
+func (x *Experiment) Parameters() Parameters {
+	p := Parameters{Rules: x.Ruleset()}
+	for _, b := range x.Sweep.Probabilities {
+		p.Probabilities = append(p.Probabilities, BPFP{Alpha: b.Alpha, Beta: b.Beta})
+	}
+	return p
+}
+
+// writes the effective configuration as json
+func (x *Experiment) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(x, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// comma separated floats on the command line
+type FloatList []float64
+
+func (l *FloatList) String() string {
+	parts := make([]string, len(*l))
+	for i, v := range *l {
+		parts[i] = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *FloatList) Set(s string) error {
+	*l = nil
+	for _, w := range strings.Split(s, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(w), 64)
+		if err != nil {
+			return err
+		}
+		*l = append(*l, v)
+	}
+	return nil
+}
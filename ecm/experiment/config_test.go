@@ -0,0 +1,33 @@
+package experiment
+
+import "strings"
+import "testing"
+
+import . "flache/ecm/model"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(x *Experiment)
+		// part of the error, "" if the experiment is valid
+		err string
+	}{
+		{"default", func(x *Experiment) {}, ""},
+		{"primary metric", func(x *Experiment) { x.Metrics.Primary = MetricHomophily }, ""},
+		{"unknown primary metric", func(x *Experiment) { x.Metrics.Primary = "aproval" }, "Metrics.Primary"},
+		{"unknown target metric", func(x *Experiment) { x.TargetMetric = "aproval" }, "target metric"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := DefaultExperiment()
+			tt.change(x)
+			err := x.Validate()
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("Validate: %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("Validate: %v, want an error about %s", err, tt.err)
+			}
+		})
+	}
+}
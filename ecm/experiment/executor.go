@@ -0,0 +1,165 @@
+package experiment
+
+import "context"
+import "fmt"
+import "io"
+import "runtime/debug"
+import "sync"
+import "time"
+
+// Job is a single simulation run: a replicate of a parameter set
+type Job struct {
+	// position in the queue, set by Execute
+	ID int
+	// index of the parameter set the job belongs to
+	Set       int
+	Params    Parameters
+	Replicate int
+	Seed      int64
+}
+
+type JobResult struct {
+	Job
+	Res SimRes
+	// set if the run panicked, timed out or was canceled
+	Err      error
+	Duration time.Duration
+}
+
+// Progress of an execution, reported after every job
+type Progress struct {
+	Done   int
+	Failed int
+	Total  int
+	// simulation events of the finished jobs
+	Events       int
+	Elapsed      time.Duration
+	EventsPerSec float64
+	// estimated time until all jobs are done
+	ETA time.Duration
+}
+
+func (p Progress) String() string {
+	return fmt.Sprintf("%d/%d jobs (%d failed), %.0f events/s, ETA %v",
+		p.Done, p.Total, p.Failed, p.EventsPerSec, p.ETA)
+}
+
+// Executor runs jobs on a fixed number of workers
+type Executor struct {
+	Workers int
+	// per job timeout, 0 for none
+	Timeout time.Duration
+	// called after every finished job, never concurrently
+	Progress func(Progress)
+	// called with every result as it arrives, never concurrently
+	OnResult func(JobResult)
+}
+
+// runs all jobs and returns their results in queue order. A canceled context
+// stops the running jobs, the remaining ones fail with the context's error.
+// The run func should return once its context is done.
+func (e *Executor) Execute(ctx context.Context, jobs []Job, run func(context.Context, Job) (SimRes, error)) []JobResult {
+	workers := e.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	for i := range jobs {
+		jobs[i].ID = i
+	}
+
+	queue := make(chan Job)
+	done := make(chan JobResult)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				done <- e.runJob(ctx, job, run)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(queue)
+		for i, job := range jobs {
+			select {
+			case queue <- job:
+			case <-ctx.Done():
+				// fail the rest without running them
+				for _, j := range jobs[i:] {
+					done <- JobResult{Job: j, Err: ctx.Err()}
+				}
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	results := make([]JobResult, len(jobs))
+	progress := Progress{Total: len(jobs)}
+	start := time.Now()
+	for r := range done {
+		results[r.ID] = r
+
+		progress.Done++
+		if r.Err != nil {
+			progress.Failed++
+		}
+		progress.Events += r.Res.Events
+		progress.Elapsed = time.Since(start)
+		if s := progress.Elapsed.Seconds(); s > 0 {
+			progress.EventsPerSec = float64(progress.Events) / s
+		}
+		progress.ETA = time.Duration(float64(progress.Elapsed) / float64(progress.Done) *
+			float64(progress.Total-progress.Done))
+
+		if e.OnResult != nil {
+			e.OnResult(r)
+		}
+		if e.Progress != nil {
+			e.Progress(progress)
+		}
+	}
+	return results
+}
+
+// runs a single job, a panic only fails the job
+func (e *Executor) runJob(ctx context.Context, job Job, run func(context.Context, Job) (SimRes, error)) (r JobResult) {
+	r.Job = job
+	start := time.Now()
+	defer func() {
+		r.Duration = time.Since(start)
+		if p := recover(); p != nil {
+			r.Err = fmt.Errorf("job %d panicked: %v\n%s", job.ID, p, debug.Stack())
+		}
+	}()
+
+	if err := ctx.Err(); err != nil {
+		r.Err = err
+		return r
+	}
+	if e.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.Timeout)
+		defer cancel()
+	}
+	r.Res, r.Err = run(ctx, job)
+	return r
+}
+
+// prints the progress to w at most once per interval and after the last job
+func ProgressPrinter(w io.Writer, interval time.Duration) func(Progress) {
+	var last time.Time
+	return func(p Progress) {
+		if p.Done < p.Total && time.Since(last) < interval {
+			return
+		}
+		last = time.Now()
+		fmt.Fprintf(w, "%v\n", p)
+	}
+}
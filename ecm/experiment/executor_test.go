@@ -0,0 +1,125 @@
+package experiment
+
+import "context"
+import "errors"
+import "strings"
+import "testing"
+import "time"
+
+func TestExecutor(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		// cancel the context before the jobs run
+		cancel bool
+		// what job i does
+		run func(ctx context.Context, i int) (SimRes, error)
+		// the error of job i, nil if it succeeds
+		check func(i int, err error) bool
+	}{
+		{"all succeed", 0, false,
+			func(ctx context.Context, i int) (SimRes, error) { return SimRes{Seed: int64(i)}, nil },
+			func(i int, err error) bool { return err == nil }},
+		{"errors", 0, false,
+			func(ctx context.Context, i int) (SimRes, error) {
+				if i%2 == 1 {
+					return SimRes{}, errFailed
+				}
+				return SimRes{Seed: int64(i)}, nil
+			},
+			func(i int, err error) bool { return (i%2 == 1) == (err == errFailed) }},
+		{"panic", 0, false,
+			func(ctx context.Context, i int) (SimRes, error) {
+				if i == 3 {
+					panic("boom")
+				}
+				return SimRes{Seed: int64(i)}, nil
+			},
+			func(i int, err error) bool {
+				if i == 3 {
+					return err != nil && strings.Contains(err.Error(), "panicked: boom")
+				}
+				return err == nil
+			}},
+		{"timeout", 10 * time.Millisecond, false,
+			func(ctx context.Context, i int) (SimRes, error) {
+				if i == 2 {
+					<-ctx.Done()
+					return SimRes{}, ctx.Err()
+				}
+				return SimRes{Seed: int64(i)}, nil
+			},
+			func(i int, err error) bool { return (i == 2) == (err == context.DeadlineExceeded) }},
+		{"canceled", 0, true,
+			func(ctx context.Context, i int) (SimRes, error) { return SimRes{Seed: int64(i)}, nil },
+			func(i int, err error) bool { return err == context.Canceled }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+			jobs := make([]Job, 8)
+			for i := range jobs {
+				jobs[i].Set = i
+			}
+			var progress []Progress
+			ex := &Executor{Workers: 3, Timeout: tt.timeout,
+				Progress: func(p Progress) { progress = append(progress, p) }}
+			results := ex.Execute(ctx, jobs, func(ctx context.Context, j Job) (SimRes, error) {
+				return tt.run(ctx, j.Set)
+			})
+
+			if len(results) != len(jobs) {
+				t.Fatalf("%d results for %d jobs", len(results), len(jobs))
+			}
+			failed := 0
+			for i, r := range results {
+				if r.ID != i || r.Set != i {
+					t.Errorf("result %d is job %d of set %d", i, r.ID, r.Set)
+				}
+				if !tt.check(i, r.Err) {
+					t.Errorf("job %d: error %v", i, r.Err)
+				}
+				if r.Err == nil && r.Res.Seed != int64(i) {
+					t.Errorf("job %d: result of job %d", i, r.Res.Seed)
+				}
+				if r.Err != nil {
+					failed++
+				}
+			}
+			if len(progress) != len(jobs) {
+				t.Fatalf("%d progress reports for %d jobs", len(progress), len(jobs))
+			}
+			if last := progress[len(progress)-1]; last.Done != len(jobs) || last.Failed != failed {
+				t.Errorf("last progress %+v, want %d done and %d failed", last, len(jobs), failed)
+			}
+		})
+	}
+}
+
+func TestExecutorCancelRunning(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan bool)
+	ex := &Executor{Workers: 2}
+	go func() {
+		<-started
+		cancel()
+	}()
+	jobs := make([]Job, 20)
+	results := ex.Execute(ctx, jobs, func(ctx context.Context, j Job) (SimRes, error) {
+		if j.ID == 0 {
+			started <- true
+		}
+		<-ctx.Done()
+		return SimRes{}, ctx.Err()
+	})
+	for i, r := range results {
+		if r.Err != context.Canceled {
+			t.Errorf("job %d: error %v, want %v", i, r.Err, context.Canceled)
+		}
+	}
+}
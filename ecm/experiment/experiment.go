@@ -0,0 +1,625 @@
+/*
+A Agent model to simulate opinion dynamics in echo chambers using the goabm library
+Copyright 2014 by Remo Hertig <remo.hertig@bluewin.ch>
+
+v2
+
+model:
+
+agent has relation to other agents r
+a relation consists of an series of interactions
+
+agent can have at most N relation
+
+the agent is attracted to other with p(similarity)
+sort agents according to score function -> nInter * nDaysSince * Similarity
+however he will also interact with long known "friends": p( nInteractions^f=1 * nDaysSinceLastI^-f=1)
+
+c1: condition on which he will seach a new interaction partner
+case 1: nearby new neighbor
+        if there is unknown neighbor -> try
+                otherwise internet
+case 2: "internet"
+
+for every feature we keep track of interactions & potential partners
+if a certain feature's count falls below a treshold, the agent starts to search for potential partners
+ f[needtointeract] = e^(x/f=1) -> triggers search for this feature
+
+ on each interactions: update similarity (overall) -> especially for feature list
+                        interact (change feature)
+
+6 factor
+
+
+/// v2
+
+agent loop
+        * is there physical ip?
+                *interact normally
+        * no: blog loop
+                * evaluate blogs
+                * search new blog
+                * read postings
+                * comment
+
+*/
+
+package experiment
+
+import "goabm"
+import "context"
+import "fmt"
+
+import "os"
+import "path/filepath"
+
+import "sort"
+import "math"
+import "strings"
+
+
+import "log"
+import . "flache/ecm/model"
+
+import "math/rand"
+
+//import "time"
+import "time"
+import "sync/atomic"
+
+type SimRes struct {
+	Cultures           int
+	OnlineInteraction  int
+	OfflineInteraction int
+	TotalEchoChambers  int
+	EchoChamberRatio   float64
+	Events             int
+	Seed               int64
+
+	// Axelrod order parameters of the physical landscape
+	Regions       int
+	LargestRegion float64
+
+	// per metric: share of echo chamber blogs and mean value over the blogs
+	EchoChamberRatios map[string]float64
+	MetricMeans       map[string]float64
+
+	RecommendedSubscriptions    int
+	RecommendedEchoChamberRatio float64
+	SearchedEchoChamberRatio    float64
+
+	// blogs and their share of the agents
+	TotalBlogs   int
+	BlogAdoption float64
+
+	// why and after which step the run stopped, and whether a condition
+	// found the model stable
+	StopReason string
+	StopStep   int
+	Stable     bool
+}
+
+// numbers the runs for the recorder
+var runCounter int64
+
+// checkpointing of a simulation run
+type Checkpoints struct {
+	// write a checkpoint every Every steps into Dir
+	Dir   string
+	Every int
+	// continue the run from this checkpoint with its seed, every parameter
+	// set branches off with its own parameters
+	Restore string
+
+	// key of the parameter set, part of the file names
+	set string
+}
+
+// network export of a simulation run
+type Export struct {
+	// .graphml, .gexf or .dot, the run id is added to the file name
+	Path string
+	// with a .gexf file: add a time slice every Every steps, 0 only
+	// exports the final network
+	Every int
+}
+
+func (ex Export) write(model *EchoChamberModel, timeline *Timeline, runID string) error {
+	ext := filepath.Ext(ex.Path)
+	f, err := os.Create(strings.TrimSuffix(ex.Path, ext) + "-" + runID + ext)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if timeline != nil {
+		if n := len(timeline.Slices); n == 0 || timeline.Slices[n-1].Step != model.Step {
+			timeline.Add(model.Graph())
+		}
+		return timeline.WriteGEXF(f)
+	}
+	return model.Graph().Write(f, ex.Path)
+}
+
+// a single run of the experiment with the parameters until the model is
+// stable, it reached step Steps or ctx is done. Fails without a result if
+// the experiment does not set up a model or the checkpoint cannot be
+// restored.
+func SimRun(ctx context.Context, x *Experiment, p Parameters, seed int64, rec Recorder) (SimRes, error) {
+	pfOnline := x.POnline.NPFP()
+	pfRead := x.PRead.NPFP()
+	pfRespond := x.PRespond.NPFP()
+	pfUnderstanding := p.Probabilities[0]
+
+	metric, err := similarityMetric(x.Similarity, x.Traits, x.Salience)
+	if err != nil {
+		return SimRes{}, err
+	}
+	discovery, err := discoveryPolicy(x.Discovery, x.Serendipity, x.SerendipityThreshold)
+	if err != nil {
+		return SimRes{}, err
+	}
+	stop, err := x.Stopping.Conditions()
+	if err != nil {
+		return SimRes{}, err
+	}
+	cp, ex := x.Output.Checkpoints, x.Output.Export
+	cp.set = SetKey(p)
+
+	model := &EchoChamberModel{
+		NTraits:                 x.Traits,
+		NFeatures:               x.Features,
+		PVeloc:                  x.PVeloc,
+		Steplength:              x.Steplength,
+		PStartBlogging:          x.PStartBlogging,
+		RSubscribedBlogs:        x.RSubscribedBlogs,
+		RSimilarityConfortLevel: FloatRange{x.MinConfort, x.MaxConfort},
+		PFOnline: pfOnline.Pf,//dst.Beta(pfOnline.Alpha.Var, pfOnline.Beta.Var),
+		PFConsumptive: pfRead.Pf,
+		PFExpressive: pfRespond.Pf,
+		
+		//PFAI: dst.Beta(pfActiveInteraction.Alpha.Var, pfActiveInteraction.Beta.Var),
+		PFU: Beta(pfUnderstanding.Alpha.Var, pfUnderstanding.Beta.Var),
+		Metric: metric,
+		Discovery: discovery,
+		Recommender: CollaborativeFilter{},
+		PRecommend: x.PRecommend,
+		Seed: seed,
+		MetricsConfig: x.Metrics,
+		Unsubscribe: ComfortZone{Rate: x.UnsubscribeRate, Window: x.ComfortWindow, TwoSided: x.Boredom},
+		Distributions: x.Distributions}
+
+	model.Ruleset = p.Rules
+	//fmt.Printf("rule: %v", rules)
+
+	sim := &goabm.Simulation{Landscape: &goabm.FixedLandscapeWithMovement{Size: x.Size, NAgents: x.Agents, Sight: x.Sight},
+		Model: model, Log: goabm.Logger{StdOut: false}}
+	sim.Init()
+
+	if cp.Restore != "" {
+		if err := model.LoadCheckpoint(cp.Restore); err != nil {
+			return SimRes{}, fmt.Errorf("restore %s: %v", cp.Restore, err)
+		}
+		// the run goes on with the seed of the checkpoint
+		seed = model.Seed
+	}
+
+	runID := fmt.Sprintf("run-%d", atomic.AddInt64(&runCounter, 1))
+
+	var timeline *Timeline
+	if ex.Every > 0 && filepath.Ext(ex.Path) == ".gexf" {
+		timeline = &Timeline{}
+	}
+
+	reason := StopSteps
+	stable := false
+	// a restored run goes on from the step of its checkpoint
+	for model.Step < x.Steps {
+		if ctx.Err() != nil {
+			reason = StopCanceled
+			break
+		}
+		sim.Step()
+
+		if rec != nil {
+			if err := rec.Record(runID, model); err != nil {
+				log.Print(err)
+			}
+		}
+		if timeline != nil && model.Step%ex.Every == 0 {
+			timeline.Add(model.Graph())
+		}
+
+		if cp.Dir != "" && cp.Every > 0 && model.Step%cp.Every == 0 {
+			path := filepath.Join(cp.Dir, fmt.Sprintf("ecm-%s-%d-%06d.json", cp.set, seed, model.Step))
+			if err := model.SaveCheckpoint(path); err != nil {
+				log.Print(err)
+			}
+		}
+
+		// a condition ends the run early, e.g. once the model is stable,
+		// to save cpu resources
+		r := simRes(model, sim, x.Agents, seed)
+		for _, c := range stop {
+			if why := c.Stop(model, r); why != "" {
+				reason, stable = why, c.Converged()
+				break
+			}
+		}
+		if reason != StopSteps {
+			break
+		}
+	}
+	sim.Stop()
+
+	if rec != nil {
+		if err := rec.Flush(); err != nil {
+			log.Print(err)
+		}
+	}
+	if ex.Path != "" {
+		if err := ex.write(model, timeline, runID); err != nil {
+			log.Print(err)
+		}
+	}
+
+	if model.Metrics == nil {
+		// the model never stepped
+		model.Metrics = &MetricsReport{}
+	}
+
+	res := simRes(model, sim, x.Agents, seed)
+	res.StopReason, res.StopStep, res.Stable = reason, model.Step, stable
+	return res, nil
+}
+
+// the results of the model after its latest step
+func simRes(model *EchoChamberModel, sim *goabm.Simulation, numAgents int, seed int64) SimRes {
+	r := SimRes{Cultures: model.Cultures,
+		OnlineInteraction:  model.OnlineInteraction,
+		OfflineInteraction: model.OfflineInteraction,
+		TotalEchoChambers:  model.TotalEchoChambers,
+		EchoChamberRatio:   model.EchoChamberRatio,
+		Events:             sim.Stats.Events,
+		Seed:               seed,
+
+		Regions:       model.Regions,
+		LargestRegion: model.LargestRegion,
+
+		RecommendedSubscriptions:    model.RecommendedSubscriptions,
+		RecommendedEchoChamberRatio: model.RecommendedEchoChamberRatio,
+		SearchedEchoChamberRatio:    model.SearchedEchoChamberRatio,
+
+		TotalBlogs:   model.TotalBlogs,
+		BlogAdoption: float64(model.TotalBlogs) / float64(numAgents)}
+	if model.Metrics != nil {
+		r.EchoChamberRatios = model.Metrics.Ratios
+		r.MetricMeans = model.Metrics.Means
+	}
+	return r
+}
+
+
+
+
+type Parameters struct {
+	Probabilities []BPFP
+	Discrete      []DiscreteVarWithLimit
+	Ranges        []Range
+	Rules         goabm.Ruleset
+}
+
+type TargetFunction interface {
+	Run(Parameters) float64
+}
+
+// a noisy target which evaluates a parameter set again with other runs, the
+// target of the n-th evaluation (from 0) of a set
+type RerunTargetFunction interface {
+	TargetFunction
+	Rerun(n int) TargetFunction
+}
+
+type MyTarget struct {
+	*Experiment
+	// records the stats of every step, nil to record nothing
+	Recorder Recorder
+	// reports the progress of the runs, nil to report nothing
+	Progress func(Progress)
+	// finished runs of this experiment, nil to keep nothing
+	Store *Store
+	// the first replicate of every parameter set, see Rerun
+	Offset int
+}
+
+// agent parameter distributions, set on the command line as
+// -dist PVeloc=uniform(0,0.3)
+type Distributions map[string]*Distribution
+
+func (d Distributions) String() string {
+	names := make([]string, 0, len(d))
+	for name := range d {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + d[name].String()
+	}
+	return strings.Join(parts, " ")
+}
+
+func (d Distributions) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i < 0 {
+		return fmt.Errorf("expected parameter=distribution, got %q", s)
+	}
+	dist, err := ParseDistribution(s[i+1:])
+	if err != nil {
+		return err
+	}
+	d[strings.TrimSpace(s[:i])] = dist
+	return nil
+}
+
+func similarityMetric(name string, traits int, salience []float64) (SimilarityMetric, error) {
+	switch name {
+	case "", "exact":
+		return ExactMatch{}, nil
+	case "weighted":
+		return WeightedOverlap{Salience: salience}, nil
+	case "ordinal":
+		return OrdinalDistance{NTraits: traits}, nil
+	case "jaccard":
+		return Jaccard{}, nil
+	}
+	return nil, fmt.Errorf("unknown similarity metric: %s", name)
+}
+
+func discoveryPolicy(name string, serendipity, threshold float64) (DiscoveryPolicy, error) {
+	switch name {
+	case "", "most-similar":
+		return MostSimilar{}, nil
+	case "similarity-weighted":
+		return SimilarityWeighted{}, nil
+	case "popularity":
+		return PopularityWeighted{}, nil
+	case "random":
+		return UniformRandom{}, nil
+	case "serendipity":
+		return Serendipity{Share: serendipity, Threshold: threshold, Base: MostSimilar{}}, nil
+	}
+	return nil, fmt.Errorf("unknown discovery policy: %s", name)
+}
+
+// a single simulation run of the experiment with the parameters
+func (tf MyTarget) Simulate(p Parameters, seed int64) SimRes {
+	r, _ := tf.simulate(context.Background(), p, seed)
+	return r
+}
+
+// a single run which stops early once ctx is done, the error is ctx's then
+func (tf MyTarget) SimulateContext(ctx context.Context, p Parameters, seed int64) (SimRes, error) {
+	return tf.simulate(ctx, p, seed)
+}
+
+// the error is ctx's if the run was stopped early
+func (tf MyTarget) simulate(ctx context.Context, p Parameters, seed int64) (SimRes, error) {
+	res, err := SimRun(ctx, tf.Experiment, p, seed, tf.Recorder)
+	if err != nil {
+		return SimRes{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+	if p.Rules.IsRuleActive("only_stable_models") && !res.Stable {
+		return res, fmt.Errorf("unstable after %d steps (%s)", res.StopStep, res.StopReason)
+	}
+	return res, nil
+}
+
+// the replicates of every parameter set, seeds follow Seed
+func (tf MyTarget) Jobs(ps []Parameters) []Job {
+	var jobs []Job
+	for set, p := range ps {
+		for i := 0; i < tf.Replicates; i++ {
+			jobs = append(jobs, tf.job(set, p, i))
+		}
+	}
+	return jobs
+}
+
+// replicate Offset+i of a parameter set
+func (tf MyTarget) job(set int, p Parameters, i int) Job {
+	i += tf.Offset
+	seed := tf.Seed + int64(i)
+	if tf.Seed == 0 {
+		seed = rand.Int63()
+		// a stored replicate keeps its seed so it is not run again
+		if tf.Store != nil {
+			if row, ok := tf.Store.Replicate(SetKey(p), i); ok {
+				seed = row.Seed
+			}
+		}
+	}
+	return Job{Set: set, Params: p, Replicate: i, Seed: seed}
+}
+
+// an executor with the CPUs, timeout and progress of the experiment
+func (tf MyTarget) Executor() *Executor {
+	return &Executor{Workers: tf.CPUs,
+		Timeout:  time.Duration(tf.Timeout * float64(time.Second)),
+		Progress: tf.Progress}
+}
+
+// distance of a run to the targets, the mean over the objectives
+func (tf MyTarget) score(r SimRes) float64 {
+	sum := 0.0
+	values := tf.objectiveValues(r)
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// runs the jobs, runs found in the Store are not run again. Every result is
+// passed to result as it arrives, never concurrently.
+func (tf MyTarget) run(ctx context.Context, jobs []Job, result func(JobResult)) {
+	var pending []Job
+	for _, j := range jobs {
+		if tf.Store != nil {
+			if row, ok := tf.Store.Get(RowKey(SetKey(j.Params), j.Replicate, j.Seed)); ok {
+				result(JobResult{Job: j, Res: row.Res})
+				continue
+			}
+		}
+		pending = append(pending, j)
+	}
+
+	ex := tf.Executor()
+	var config string
+	if tf.Store != nil {
+		config = tf.RunKey()
+	}
+	ex.OnResult = func(r JobResult) {
+		if tf.Store != nil && r.Err == nil {
+			row := Row{Parameters: r.Params, Config: config, Replicate: r.Replicate, Seed: r.Seed,
+				Score: tf.score(r.Res), Res: r.Res}
+			if err := tf.Store.Append(row); err != nil {
+				log.Printf("run %d of set %d not stored: %v", r.Replicate, r.Set, err)
+			}
+		}
+		result(r)
+	}
+	ex.Execute(ctx, pending, func(ctx context.Context, j Job) (SimRes, error) {
+		return tf.simulate(ctx, j.Params, j.Seed)
+	})
+}
+
+// average score of every parameter set over its replicates. Failed runs
+// are logged and left out, a set without a finished run or with a canceled
+// one scores NaN. Runs found in the Store are not run again.
+func (tf MyTarget) Scores(ctx context.Context, ps []Parameters) []float64 {
+	scores, _ := tf.Evaluate(ctx, ps)
+	return scores
+}
+
+// the average score and objective values of every parameter set over its
+// replicates, NaN like Scores
+func (tf MyTarget) Evaluate(ctx context.Context, ps []Parameters) ([]float64, [][]float64) {
+	scores := make([]float64, len(ps))
+	objectives := make([][]float64, len(ps))
+	for set, s := range tf.Summaries(ctx, ps, nil) {
+		scores[set], objectives[set] = s.Mean, s.Objectives
+	}
+	return scores, objectives
+}
+
+// average score over the replicates, +Inf if no run finished
+func (tf MyTarget) Run(p Parameters) float64 {
+	score := tf.Scores(context.Background(), []Parameters{p})[0]
+	if math.IsNaN(score) {
+		return math.Inf(1)
+	}
+	return score
+}
+
+// average distance to every objective over the replicates, +Inf if no run
+// finished
+func (tf MyTarget) RunAll(p Parameters) []float64 {
+	_, objectives := tf.Evaluate(context.Background(), []Parameters{p})
+	values := objectives[0]
+	for i, v := range values {
+		if math.IsNaN(v) {
+			values[i] = math.Inf(1)
+		}
+	}
+	return values
+}
+
+// the target of the n-th evaluation of a parameter set, its replicates and
+// seeds follow those of the evaluations before
+func (tf MyTarget) Rerun(n int) TargetFunction {
+	per := tf.Replicates
+	if tf.Replication.Tolerance > 0 && tf.Replication.MaxReplicates > per {
+		per = tf.Replication.MaxReplicates
+	}
+	tf.Offset = n * per
+	return tf
+}
+
+type NPFP struct {
+        Mu    float64
+        Sigma float64
+        Base Range
+        f PF
+}
+func (n*NPFP) Init() {
+        μ, σ := n.Mu, n.Sigma
+        n.f = func(r *rand.Rand) float64 {
+                return r.NormFloat64()*σ + μ
+        }
+}
+
+func (n*NPFP) Pf(rng *rand.Rand) float64 {
+        // sample from f until in range Base
+        s := -9999.0
+               // fmt.Printf("s:%f > %f < %f",s,n.Base.Min, n.Base.Max )
+               c:=0
+        for (s < n.Base.Min || s > n.Base.Max) {
+         s = n.f(rng)
+         c++
+        //fmt.Printf("jajaj %f",s)
+        }
+        //fmt.Printf("c:%d\n",c)
+        // normalize [0,1]
+        r := (s- n.Base.Min)/(n.Base.Max- n.Base.Min)
+        //fmt.Printf("pf()=%f\t",r)
+        return r
+}
+
+type BPFP struct {
+        Alpha DiscreteVarWithLimit
+        Beta  DiscreteVarWithLimit
+}
+
+type Results struct {
+  Best []SimRunRes
+	// the non-dominated results of several objectives
+	Front Front
+}
+
+func (r *Results) Init(n int) { 
+ r.Best = make([]SimRunRes,n)
+
+ for i:= range r.Best {
+  r.Best[i].Score = 9999.9
+ }
+  //fmt.Printf("b: %v , len %d\n",r, len(r.Best))
+}
+func (r *Results) Check(nr SimRunRes) { 
+//fmt.Printf("best: %v\n",r.Best)
+if nr.Score < r.Best[len(r.Best)-1].Score {
+// better score than worst
+r.Best[len(r.Best)-1] = nr
+sort.Sort(ByScore(r.Best))
+}
+	if nr.Objectives != nil {
+		r.Front.Add(nr)
+	}
+}
+
+type SimRunRes struct {
+ Parameters
+ Score float64
+	// distances to the objectives and the crowding distance in the front
+	Objectives []float64
+	Crowding   float64
+}
+
+type ByScore []SimRunRes
+
+func (a ByScore) Len() int           { return len(a) }
+func (a ByScore) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a ByScore) Less(i, j int) bool { return a[i].Score < a[j].Score }
+
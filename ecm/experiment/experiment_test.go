@@ -0,0 +1,148 @@
+package experiment
+
+import "context"
+import "fmt"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "testing"
+
+// a small experiment which runs in a few milliseconds
+func testExperiment() *Experiment {
+	x := DefaultExperiment()
+	x.Features, x.Traits = 5, 3
+	x.Size, x.Agents, x.Sight = 10, 40, 2
+	x.Steps = 40
+	x.PVeloc = 0.5
+	x.Stopping = Stopping{}
+	return x
+}
+
+func TestSimulateReproducible(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(x *Experiment)
+		// whether the agents meet offline
+		offline bool
+	}{
+		{"default", func(x *Experiment) {}, false},
+		{"everyone moves", func(x *Experiment) { x.PVeloc, x.Steplength = 1, 3 }, false},
+		{"offline", func(x *Experiment) { x.POnline = NormalPF{Mu: 0, Sigma: 0.1, Min: 0, Max: 10} }, true},
+		{"random discovery", func(x *Experiment) { x.Discovery, x.PRecommend = "random", 0.3 }, false},
+		{"transmission error", func(x *Experiment) { x.Rules["transmission_error"] = true }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := testExperiment()
+			tt.change(x)
+			tf := MyTarget{Experiment: x}
+			p := x.Parameters()
+
+			first := tf.Simulate(p, 3)
+			if first.OfflineInteraction+first.OnlineInteraction == 0 {
+				t.Fatalf("nothing happened: %+v", first)
+			}
+			if tt.offline && first.OfflineInteraction == 0 {
+				t.Fatalf("no agent met a neighbor: %+v", first)
+			}
+			// %v, SimRes may hold NaNs
+			if second := tf.Simulate(p, 3); fmt.Sprintf("%+v", second) != fmt.Sprintf("%+v", first) {
+				t.Errorf("same seed, different runs:\n%+v\n%+v", first, second)
+			}
+			if other := tf.Simulate(p, 4); fmt.Sprintf("%+v", other) == fmt.Sprintf("%+v", first) {
+				t.Errorf("another seed, the same run: %+v", other)
+			}
+		})
+	}
+}
+
+func TestRestoredRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	x := testExperiment()
+	x.Output.Checkpoints = Checkpoints{Dir: dir, Every: 10}
+	tf := MyTarget{Experiment: x}
+	p := x.Parameters()
+	full := tf.Simulate(p, 3)
+	if full.StopStep != x.Steps {
+		t.Fatalf("stopped at step %d, want %d", full.StopStep, x.Steps)
+	}
+
+	tests := []struct {
+		name string
+		step int
+	}{
+		{"first checkpoint", 10},
+		{"halfway", 20},
+		{"last step", 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			y := testExperiment()
+			y.Replicates = 1
+			y.Output.Checkpoints.Restore = filepath.Join(dir, fmt.Sprintf("ecm-%s-3-%06d.json", SetKey(p), tt.step))
+			if err := y.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			// the seed of the job gives way to the checkpoint's
+			r, err := MyTarget{Experiment: y}.SimulateContext(context.Background(), p, 42)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.StopStep != y.Steps {
+				t.Errorf("restored at step %d, stopped at step %d, want %d", tt.step, r.StopStep, y.Steps)
+			}
+			if r.Seed != 3 {
+				t.Errorf("seed %d, want the checkpoint's 3", r.Seed)
+			}
+			if fmt.Sprintf("%+v", r) != fmt.Sprintf("%+v", full) {
+				t.Errorf("the restored run went another way:\n%+v\n%+v", r, full)
+			}
+		})
+	}
+
+	y := testExperiment()
+	y.Output.Checkpoints.Restore = filepath.Join(dir, "any.json")
+	if err := y.Validate(); err == nil {
+		t.Errorf("restored into %d replicates", y.Replicates)
+	}
+}
+
+func TestRerun(t *testing.T) {
+	tests := []struct {
+		name        string
+		seed        int64
+		replication Replication
+		// the first replicate of the second evaluation
+		first int
+	}{
+		{"seeded", 1, Replication{}, 2},
+		{"random seeds", 0, Replication{}, 2},
+		{"adaptive", 1, Replication{Tolerance: 0.1, MaxReplicates: 6, Level: 0.95}, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := testExperiment()
+			x.Replicates, x.Seed, x.Replication = 2, tt.seed, tt.replication
+			p := x.Parameters()
+			tf := MyTarget{Experiment: x}
+
+			seeds := make(map[int64]bool)
+			for n := 0; n < 3; n++ {
+				for i, j := range tf.Rerun(n).(MyTarget).Jobs([]Parameters{p}) {
+					if want := n*tt.first + i; j.Replicate != want {
+						t.Errorf("evaluation %d: replicate %d, want %d", n, j.Replicate, want)
+					}
+					if seeds[j.Seed] {
+						t.Errorf("evaluation %d: seed %d again", n, j.Seed)
+					}
+					seeds[j.Seed] = true
+				}
+			}
+		})
+	}
+}
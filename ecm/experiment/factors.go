@@ -0,0 +1,134 @@
+package experiment
+
+import "fmt"
+import "math"
+import "reflect"
+import "sort"
+import "strings"
+
+import . "flache/ecm/model"
+
+// a value of an experiment or of its parameter set which a study varies
+type Setter func(x *Experiment, p *Parameters, v float64)
+
+// the setter of a factor by name: Probabilities[i].Alpha and .Beta of the
+// parameter set, the Mu and Sigma of POnline, PRead and PRespond, or a
+// numeric field of the experiment like PStartBlogging or Traits (rounded)
+func SetterOf(name string) (Setter, error) {
+	var i int
+	if _, err := fmt.Sscanf(name, "Probabilities[%d].Alpha", &i); err == nil && strings.HasSuffix(name, ".Alpha") {
+		return func(x *Experiment, p *Parameters, v float64) {
+			if i < len(p.Probabilities) {
+				p.Probabilities[i].Alpha.Var = v
+			}
+		}, nil
+	}
+	if _, err := fmt.Sscanf(name, "Probabilities[%d].Beta", &i); err == nil && strings.HasSuffix(name, ".Beta") {
+		return func(x *Experiment, p *Parameters, v float64) {
+			if i < len(p.Probabilities) {
+				p.Probabilities[i].Beta.Var = v
+			}
+		}, nil
+	}
+
+	pfs := map[string]func(x *Experiment) *NormalPF{
+		"POnline":  func(x *Experiment) *NormalPF { return &x.POnline },
+		"PRead":    func(x *Experiment) *NormalPF { return &x.PRead },
+		"PRespond": func(x *Experiment) *NormalPF { return &x.PRespond },
+	}
+	if dot := strings.Index(name, "."); dot > 0 {
+		if pf, ok := pfs[name[:dot]]; ok {
+			switch name[dot+1:] {
+			case "Mu":
+				return func(x *Experiment, p *Parameters, v float64) { pf(x).Mu = v }, nil
+			case "Sigma":
+				return func(x *Experiment, p *Parameters, v float64) { pf(x).Sigma = v }, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown factor %q", name)
+	}
+
+	f, ok := reflect.TypeOf(Experiment{}).FieldByName(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown factor %q", name)
+	}
+	switch f.Type.Kind() {
+	case reflect.Float64:
+		return func(x *Experiment, p *Parameters, v float64) {
+			reflect.ValueOf(x).Elem().FieldByIndex(f.Index).SetFloat(v)
+		}, nil
+	case reflect.Int:
+		return func(x *Experiment, p *Parameters, v float64) {
+			reflect.ValueOf(x).Elem().FieldByIndex(f.Index).SetInt(int64(math.Round(v)))
+		}, nil
+	}
+	return nil, fmt.Errorf("factor %q is not numeric", name)
+}
+
+// Factors set named values of a target
+type Factors struct {
+	Names   []string
+	setters []Setter
+}
+
+func NewFactors(names []string) (*Factors, error) {
+	f := &Factors{Names: names}
+	for _, name := range names {
+		s, err := SetterOf(name)
+		if err != nil {
+			return nil, err
+		}
+		f.setters = append(f.setters, s)
+	}
+	return f, nil
+}
+
+// a copy of the target and its parameter set with the values of the factors
+func (f *Factors) Apply(tf MyTarget, values []float64) (MyTarget, Parameters) {
+	x := *tf.Experiment
+	p := x.Parameters()
+	for i, set := range f.setters {
+		set(&x, &p, values[i])
+	}
+	tf.Experiment = &x
+	return tf, p
+}
+
+// ranges of factors on the command line: name=min:max, repeatable
+type FactorRanges map[string]FloatRange
+
+func (r FactorRanges) String() string {
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%g:%g", name, r[name][0], r[name][1])
+	}
+	return strings.Join(parts, ",")
+}
+
+func (r FactorRanges) Set(s string) error {
+	var min, max float64
+	eq := strings.Index(s, "=")
+	if eq < 0 {
+		return fmt.Errorf("factor range %q is not name=min:max", s)
+	}
+	if _, err := fmt.Sscanf(s[eq+1:], "%g:%g", &min, &max); err != nil {
+		return fmt.Errorf("factor range %q is not name=min:max", s)
+	}
+	r[s[:eq]] = FloatRange{min, max}
+	return nil
+}
+
+// the names in order
+func (r FactorRanges) Names() []string {
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,220 @@
+package experiment
+
+import "fmt"
+import "io"
+import "math"
+import "sort"
+import "strings"
+
+// a calibration target besides the echo chamber ratio: a statistic of the
+// runs (see Statistic) and the value it should reach. The distance is
+// divided by Scale (1 if 0), so counts like Cultures compare with ratios.
+type Objective struct {
+	Stat   string
+	Target float64
+	Scale  float64
+}
+
+// the scaled distance of a run to the target
+func (o Objective) Distance(r SimRes) float64 {
+	stat, err := Statistic(o.Stat)
+	if err != nil {
+		// the experiment was validated
+		return math.NaN()
+	}
+	scale := o.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	return math.Abs(o.Target-stat(r)) / scale
+}
+
+// objectives on the command line: stat=target or stat=target:scale,
+// repeatable, an objective replaces the one of the same statistic
+type Objectives []Objective
+
+func (l *Objectives) String() string {
+	parts := make([]string, len(*l))
+	for i, o := range *l {
+		parts[i] = fmt.Sprintf("%s=%g", o.Stat, o.Target)
+		if o.Scale != 0 {
+			parts[i] += fmt.Sprintf(":%g", o.Scale)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *Objectives) Set(s string) error {
+	eq := strings.Index(s, "=")
+	if eq < 0 {
+		return fmt.Errorf("objective %q is not stat=target[:scale]", s)
+	}
+	o := Objective{Stat: s[:eq]}
+	value := s[eq+1:]
+	if colon := strings.Index(value, ":"); colon >= 0 {
+		if _, err := fmt.Sscanf(value[colon+1:], "%g", &o.Scale); err != nil {
+			return fmt.Errorf("objective %q is not stat=target[:scale]", s)
+		}
+		value = value[:colon]
+	}
+	if _, err := fmt.Sscanf(value, "%g", &o.Target); err != nil {
+		return fmt.Errorf("objective %q is not stat=target[:scale]", s)
+	}
+	for i := range *l {
+		if (*l)[i].Stat == o.Stat {
+			(*l)[i] = o
+			return nil
+		}
+	}
+	*l = append(*l, o)
+	return nil
+}
+
+// a target of several objectives, Run is the mean of the distances RunAll
+// returns
+type MultiTargetFunction interface {
+	TargetFunction
+	ObjectiveNames() []string
+	RunAll(Parameters) []float64
+}
+
+// the target ratio of the echo chamber metric, then the Objectives of the
+// experiment
+func (tf MyTarget) objectives() []Objective {
+	stat := "EchoChamberRatio"
+	if tf.TargetMetric != "" {
+		stat = "EchoChamberRatios." + tf.TargetMetric
+	}
+	return append([]Objective{{Stat: stat, Target: tf.Target}}, tf.Experiment.Objectives...)
+}
+
+func (tf MyTarget) ObjectiveNames() []string {
+	var names []string
+	for _, o := range tf.objectives() {
+		names = append(names, o.Stat)
+	}
+	return names
+}
+
+// the distances of a run to the objectives
+func (tf MyTarget) objectiveValues(r SimRes) []float64 {
+	objectives := tf.objectives()
+	values := make([]float64, len(objectives))
+	for i, o := range objectives {
+		values[i] = o.Distance(r)
+	}
+	return values
+}
+
+// whether a is no worse than b in every objective and better in one
+func Dominates(a, b []float64) bool {
+	better := false
+	for i := range a {
+		if a[i] > b[i] {
+			return false
+		}
+		if a[i] < b[i] {
+			better = true
+		}
+	}
+	return better
+}
+
+// Front is an archive of the non-dominated results. If it grows beyond Size
+// members, the most crowded one is dropped, Size 0 keeps all.
+type Front struct {
+	Size    int
+	Members []SimRunRes
+}
+
+// adds the result unless a member dominates it and drops the members it
+// dominates, reports whether the result was added
+func (f *Front) Add(r SimRunRes) bool {
+	for _, v := range r.Objectives {
+		if math.IsNaN(v) {
+			return false
+		}
+	}
+	var members []SimRunRes
+	for _, m := range f.Members {
+		if Dominates(m.Objectives, r.Objectives) {
+			return false
+		}
+		if !Dominates(r.Objectives, m.Objectives) {
+			members = append(members, m)
+		}
+	}
+	f.Members = append(members, r)
+	f.crowding()
+	if f.Size > 0 && len(f.Members) > f.Size {
+		crowded := 0
+		for i, m := range f.Members {
+			if m.Crowding < f.Members[crowded].Crowding {
+				crowded = i
+			}
+		}
+		f.Members = append(f.Members[:crowded], f.Members[crowded+1:]...)
+		f.crowding()
+	}
+	return true
+}
+
+// sets the crowding distance of the members: the sum over the objectives of
+// the normalized distance between the two neighbours, +Inf at the ends
+func (f *Front) crowding() {
+	for i := range f.Members {
+		f.Members[i].Crowding = 0
+	}
+	if len(f.Members) == 0 {
+		return
+	}
+	idx := make([]int, len(f.Members))
+	for k := range f.Members[0].Objectives {
+		for i := range idx {
+			idx[i] = i
+		}
+		obj := func(i int) float64 { return f.Members[idx[i]].Objectives[k] }
+		sort.Slice(idx, func(i, j int) bool {
+			return f.Members[idx[i]].Objectives[k] < f.Members[idx[j]].Objectives[k]
+		})
+		last := len(idx) - 1
+		f.Members[idx[0]].Crowding = math.Inf(1)
+		f.Members[idx[last]].Crowding = math.Inf(1)
+		span := obj(last) - obj(0)
+		if span == 0 {
+			continue
+		}
+		for i := 1; i < last; i++ {
+			f.Members[idx[i]].Crowding += (obj(i+1) - obj(i-1)) / span
+		}
+	}
+}
+
+// writes the members by score as csv: score, the objectives, the crowding
+// distance and the variables of the space
+func (f *Front) Write(w io.Writer, objectives []string, space *Space) error {
+	members := append([]SimRunRes(nil), f.Members...)
+	sort.Stable(ByScore(members))
+
+	fmt.Fprintf(w, "score, %s, crowding", strings.Join(objectives, ", "))
+	for _, name := range space.Names() {
+		fmt.Fprintf(w, ", %s", name)
+	}
+	if _, err := fmt.Fprintf(w, "\n"); err != nil {
+		return err
+	}
+	for _, m := range members {
+		fmt.Fprintf(w, "%f", m.Score)
+		for _, v := range m.Objectives {
+			fmt.Fprintf(w, ", %f", v)
+		}
+		fmt.Fprintf(w, ", %f", m.Crowding)
+		for _, v := range space.Values(space.Point(m.Parameters)) {
+			fmt.Fprintf(w, ", %f", v)
+		}
+		if _, err := fmt.Fprintf(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package experiment
+
+import "math"
+import "reflect"
+import "sort"
+import "testing"
+
+func TestDominates(t *testing.T) {
+	tests := []struct {
+		a, b []float64
+		want bool
+	}{
+		{[]float64{1, 1}, []float64{2, 2}, true},
+		{[]float64{1, 2}, []float64{2, 2}, true},
+		{[]float64{1, 1}, []float64{1, 1}, false},
+		{[]float64{1, 3}, []float64{2, 2}, false},
+		{[]float64{2, 2}, []float64{1, 1}, false},
+	}
+	for _, tt := range tests {
+		if got := Dominates(tt.a, tt.b); got != tt.want {
+			t.Errorf("Dominates(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFrontAdd(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name string
+		size int
+		add  [][]float64
+		// whether each result was added
+		added []bool
+		// the objectives of the members at the end, in any order
+		front [][]float64
+	}{
+		{"non-dominated", 0, [][]float64{{1, 3}, {2, 2}, {3, 1}},
+			[]bool{true, true, true}, [][]float64{{1, 3}, {2, 2}, {3, 1}}},
+		{"dominated", 0, [][]float64{{1, 1}, {2, 2}, {1, 2}},
+			[]bool{true, false, false}, [][]float64{{1, 1}}},
+		{"dominating", 0, [][]float64{{2, 2}, {3, 1}, {1, 1}},
+			[]bool{true, true, true}, [][]float64{{1, 1}}},
+		{"equal", 0, [][]float64{{1, 2}, {1, 2}},
+			[]bool{true, true}, [][]float64{{1, 2}, {1, 2}}},
+		{"nan", 0, [][]float64{{1, nan}, {2, 2}},
+			[]bool{false, true}, [][]float64{{2, 2}}},
+		// the crowded middle member of the first three is dropped
+		{"size", 3, [][]float64{{0, 10}, {1, 9}, {10, 0}, {5, 5}},
+			[]bool{true, true, true, true}, [][]float64{{0, 10}, {5, 5}, {10, 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Front{Size: tt.size}
+			for i, o := range tt.add {
+				if got := f.Add(SimRunRes{Objectives: o}); got != tt.added[i] {
+					t.Errorf("Add(%v) = %v, want %v", o, got, tt.added[i])
+				}
+			}
+			var front [][]float64
+			for _, m := range f.Members {
+				front = append(front, m.Objectives)
+			}
+			sort.Slice(front, func(i, j int) bool { return front[i][0] < front[j][0] })
+			if !reflect.DeepEqual(front, tt.front) {
+				t.Errorf("front %v, want %v", front, tt.front)
+			}
+		})
+	}
+}
+
+func TestFrontCrowding(t *testing.T) {
+	inf := math.Inf(1)
+	tests := []struct {
+		name       string
+		objectives [][]float64
+		crowding   []float64
+	}{
+		{"single", [][]float64{{1, 1}}, []float64{inf}},
+		{"two", [][]float64{{0, 1}, {1, 0}}, []float64{inf, inf}},
+		// the middle one spans the whole range of both objectives
+		{"three", [][]float64{{0, 4}, {1, 1}, {4, 0}}, []float64{inf, 2, inf}},
+		{"four", [][]float64{{0, 4}, {1, 2}, {2, 1}, {4, 0}}, []float64{inf, 0.5 + 0.75, 0.75 + 0.5, inf}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Front{}
+			for _, o := range tt.objectives {
+				f.Add(SimRunRes{Objectives: o})
+			}
+			for i, m := range f.Members {
+				if m.Crowding != tt.crowding[i] && math.Abs(m.Crowding-tt.crowding[i]) > 1e-12 {
+					t.Errorf("member %v: crowding %g, want %g", m.Objectives, m.Crowding, tt.crowding[i])
+				}
+			}
+		})
+	}
+}
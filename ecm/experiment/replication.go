@@ -0,0 +1,194 @@
+package experiment
+
+import "context"
+import "log"
+import "math"
+
+// adaptive replication: a parameter set gets more replicates until the
+// half-width of the confidence interval of its mean score is at most
+// Tolerance or it has MaxReplicates. Tolerance 0 runs Replicates.
+type Replication struct {
+	Tolerance     float64
+	MaxReplicates int
+	// level of the confidence interval
+	Level float64
+}
+
+// Summary of the replicates of a parameter set
+type Summary struct {
+	// finished replicates
+	N int
+	// mean and standard deviation of the score and the confidence interval
+	// of the mean, NaN if too few replicates finished
+	Mean, SD float64
+	Lo, Hi   float64
+	// the mean distance to every objective
+	Objectives []float64
+	// the mean share of subscriptions ending in an echo chamber by their
+	// origin, recommended or searched
+	RecommendedEchoChamberRatio, SearchedEchoChamberRatio float64
+}
+
+func (s Summary) HalfWidth() float64 {
+	return (s.Hi - s.Lo) / 2
+}
+
+// the replicates of a parameter set so far
+type replicates struct {
+	started    int
+	running    int
+	canceled   bool
+	scores     []float64
+	objectives [][]float64
+	recEC      float64
+	searchEC   float64
+}
+
+func (r *replicates) add(tf MyTarget, res SimRes) {
+	r.scores = append(r.scores, tf.score(res))
+	r.objectives = append(r.objectives, tf.objectiveValues(res))
+	r.recEC += res.RecommendedEchoChamberRatio
+	r.searchEC += res.SearchedEchoChamberRatio
+}
+
+// the summary of every parameter set: Replicates runs and, with a
+// Replication.Tolerance, more runs until the score is precise enough. Failed
+// runs are logged and left out, a set without a finished run or with a
+// canceled one has a NaN mean. Runs found in the Store are not run again.
+// done, if not nil, gets the summary of a set as soon as it needs no more
+// runs, never concurrently. Canceled sets are not passed to done.
+func (tf MyTarget) Summaries(ctx context.Context, ps []Parameters, done func(set int, s Summary)) []Summary {
+	sets := make([]replicates, len(ps))
+	jobs := tf.Jobs(ps)
+	for len(jobs) > 0 {
+		for _, j := range jobs {
+			sets[j.Set].started++
+			sets[j.Set].running++
+		}
+		tf.run(ctx, jobs, func(r JobResult) {
+			set := &sets[r.Set]
+			set.running--
+			if r.Err != nil && r.Err == ctx.Err() {
+				// canceled, the set is incomplete
+				set.canceled = true
+			}
+			if r.Err != nil {
+				if r.Err != ctx.Err() {
+					log.Printf("run %d of set %d (seed %d): %v", r.Replicate, r.Set, r.Seed, r.Err)
+				}
+			} else {
+				set.add(tf, r.Res)
+			}
+			if done != nil && set.running == 0 && !set.canceled && tf.more(*set) == 0 {
+				done(r.Set, tf.summarize(*set))
+			}
+		})
+		if ctx.Err() != nil {
+			break
+		}
+
+		jobs = nil
+		for set, p := range ps {
+			for i := 0; i < tf.more(sets[set]); i++ {
+				jobs = append(jobs, tf.job(set, p, sets[set].started+i))
+			}
+		}
+	}
+
+	summaries := make([]Summary, len(ps))
+	for set, r := range sets {
+		summaries[set] = tf.summarize(r)
+	}
+	return summaries
+}
+
+func (tf MyTarget) summarize(r replicates) Summary {
+	nan := math.NaN()
+	s := Summary{N: len(r.scores), Mean: nan, SD: nan, Lo: nan, Hi: nan,
+		Objectives:                  make([]float64, len(tf.objectives())),
+		RecommendedEchoChamberRatio: nan, SearchedEchoChamberRatio: nan}
+	if s.N == 0 || r.canceled {
+		for i := range s.Objectives {
+			s.Objectives[i] = nan
+		}
+		return s
+	}
+	n := float64(s.N)
+	s.RecommendedEchoChamberRatio, s.SearchedEchoChamberRatio = r.recEC/n, r.searchEC/n
+	s.Mean = 0
+	for i, score := range r.scores {
+		s.Mean += score / n
+		for k, v := range r.objectives[i] {
+			s.Objectives[k] += v / n
+		}
+	}
+	if s.N < 2 {
+		return s
+	}
+	ss := 0.0
+	for _, score := range r.scores {
+		ss += (score - s.Mean) * (score - s.Mean)
+	}
+	s.SD = math.Sqrt(ss / (n - 1))
+	h := studentT(tf.level(), s.N-1) * s.SD / math.Sqrt(n)
+	s.Lo, s.Hi = s.Mean-h, s.Mean+h
+	return s
+}
+
+func (tf MyTarget) level() float64 {
+	if tf.Replication.Level <= 0 || tf.Replication.Level >= 1 {
+		return 0.95
+	}
+	return tf.Replication.Level
+}
+
+// the number of replicates to add to a set, 0 if it is precise enough. The
+// number is estimated from the standard deviation so far, at most the set's
+// replicates are added at once.
+func (tf MyTarget) more(r replicates) int {
+	tol := tf.Replication.Tolerance
+	left := tf.Replication.MaxReplicates - r.started
+	if tol <= 0 || left <= 0 || r.canceled || len(r.scores) == 0 {
+		return 0
+	}
+	if len(r.scores) == 1 {
+		// too few runs for a standard deviation
+		return 1
+	}
+	s := tf.summarize(r)
+	if s.HalfWidth() <= tol {
+		return 0
+	}
+	t := studentT(tf.level(), s.N-1)
+	need := int(math.Ceil(t*t*s.SD*s.SD/(tol*tol))) - s.N
+	if need > r.started {
+		need = r.started
+	}
+	if need > left {
+		need = left
+	}
+	if need < 1 {
+		need = 1
+	}
+	return need
+}
+
+// the two-sided quantile of Student's t distribution with df degrees of
+// freedom for a confidence level. Exact for 1 and 2 degrees of freedom,
+// beyond the Cornish-Fisher expansion around the normal quantile.
+func studentT(level float64, df int) float64 {
+	p := (1 + level) / 2
+	switch df {
+	case 1:
+		return math.Tan(math.Pi * (p - 0.5))
+	case 2:
+		return (2*p - 1) / math.Sqrt(2*p*(1-p))
+	}
+	z := math.Sqrt2 * math.Erfinv(level)
+	v := float64(df)
+	z3, z5, z7, z9 := z*z*z, math.Pow(z, 5), math.Pow(z, 7), math.Pow(z, 9)
+	return z + (z3+z)/(4*v) +
+		(5*z5+16*z3+3*z)/(96*v*v) +
+		(3*z7+19*z5+17*z3-15*z)/(384*v*v*v) +
+		(79*z9+776*z7+1482*z5-1920*z3-945*z)/(92160*v*v*v*v)
+}
@@ -0,0 +1,97 @@
+package experiment
+
+import "context"
+import "fmt"
+import "math"
+import "testing"
+
+func TestStudentT(t *testing.T) {
+	// the 0.975 quantiles of the tables
+	tests := []struct {
+		df   int
+		want float64
+	}{
+		{1, 12.7062},
+		{2, 4.3027},
+		{5, 2.5706},
+		{30, 2.0423},
+	}
+	for _, tt := range tests {
+		if got := studentT(0.95, tt.df); math.Abs(got-tt.want) > 1e-3 {
+			t.Errorf("studentT(0.95, %d) = %.4f, want %.4f", tt.df, got, tt.want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tf := MyTarget{Experiment: DefaultExperiment()}
+	tests := []struct {
+		name     string
+		r        replicates
+		mean, sd float64
+	}{
+		{"none", replicates{}, math.NaN(), math.NaN()},
+		{"one", replicates{scores: []float64{0.5}, objectives: [][]float64{{0.5}}}, 0.5, math.NaN()},
+		{"two", replicates{scores: []float64{0.4, 0.6}, objectives: [][]float64{{0.4}, {0.6}}}, 0.5, math.Sqrt(0.02)},
+		{"canceled", replicates{canceled: true, scores: []float64{0.4, 0.6},
+			objectives: [][]float64{{0.4}, {0.6}}}, math.NaN(), math.NaN()},
+	}
+	same := func(a, b float64) bool {
+		return math.IsNaN(a) && math.IsNaN(b) || math.Abs(a-b) < 1e-12
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tf.summarize(tt.r)
+			if !same(s.Mean, tt.mean) || !same(s.SD, tt.sd) {
+				t.Errorf("mean %g, sd %g, want %g, %g", s.Mean, s.SD, tt.mean, tt.sd)
+			}
+			if h := studentT(0.95, 1) * tt.sd / math.Sqrt(2); tt.name == "two" && !same(s.HalfWidth(), h) {
+				t.Errorf("half-width %g, want %g", s.HalfWidth(), h)
+			}
+		})
+	}
+}
+
+func TestSummariesDone(t *testing.T) {
+	tests := []struct {
+		name        string
+		replication Replication
+	}{
+		{"replicates", Replication{}},
+		// every set runs until MaxReplicates
+		{"adaptive", Replication{Tolerance: 1e-9, MaxReplicates: 5, Level: 0.95}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := testExperiment()
+			x.Steps, x.Seed, x.Replication = 10, 1, tt.replication
+			tf := MyTarget{Experiment: x}
+			var ps []Parameters
+			for _, alpha := range []float64{1, 2, 3} {
+				p := x.Parameters()
+				p.Probabilities[0].Alpha.Var = alpha
+				ps = append(ps, p)
+			}
+
+			done := make(map[int]Summary)
+			summaries := tf.Summaries(context.Background(), ps, func(set int, s Summary) {
+				if _, ok := done[set]; ok {
+					t.Errorf("set %d done twice", set)
+				}
+				done[set] = s
+			})
+			for set, s := range summaries {
+				if fmt.Sprintf("%+v", done[set]) != fmt.Sprintf("%+v", s) {
+					t.Errorf("set %d done with %+v, summarized %+v", set, done[set], s)
+				}
+				want := x.Replicates
+				if tt.replication.Tolerance > 0 {
+					want = tt.replication.MaxReplicates
+				}
+				if s.N != want {
+					t.Errorf("set %d: %d runs, want %d", set, s.N, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package experiment
+
+import "fmt"
+import "math"
+
+import . "flache/ecm/model"
+
+// a variable of the parameter set
+type dim struct {
+	name     string
+	min, max float64
+	value    func(p *Parameters) *float64
+}
+
+// Space maps the variables of a parameter set with Min < Max to the unit cube,
+// variables with Min == Max keep their value. Only the α and β of the
+// probability functions are variables, a run reads neither Discrete nor
+// Ranges.
+type Space struct {
+	Initial Parameters
+	dims    []dim
+}
+
+func NewSpace(p Parameters) *Space {
+	s := &Space{Initial: copyParameters(p)}
+	add := func(name string, min, max float64, value func(p *Parameters) *float64) {
+		if max > min {
+			s.dims = append(s.dims, dim{name, min, max, value})
+		}
+	}
+	for i, b := range p.Probabilities {
+		i := i
+		add(fmt.Sprintf("Probabilities[%d].Alpha", i), b.Alpha.Min, b.Alpha.Max,
+			func(p *Parameters) *float64 { return &p.Probabilities[i].Alpha.Var })
+		add(fmt.Sprintf("Probabilities[%d].Beta", i), b.Beta.Min, b.Beta.Max,
+			func(p *Parameters) *float64 { return &p.Probabilities[i].Beta.Var })
+	}
+	return s
+}
+
+// number of variables
+func (s *Space) Dim() int {
+	return len(s.dims)
+}
+
+func (s *Space) Names() []string {
+	names := make([]string, len(s.dims))
+	for i, d := range s.dims {
+		names[i] = d.name
+	}
+	return names
+}
+
+// the point of a parameter set, values outside their limits are clamped
+func (s *Space) Point(p Parameters) []float64 {
+	x := make([]float64, len(s.dims))
+	for i, d := range s.dims {
+		x[i] = clamp((*d.value(&p) - d.min) / (d.max - d.min))
+	}
+	return x
+}
+
+// the parameter set of a point, coordinates outside the cube are clamped
+func (s *Space) Parameters(x []float64) Parameters {
+	p := copyParameters(s.Initial)
+	for i, d := range s.dims {
+		*d.value(&p) = d.min + clamp(x[i])*(d.max-d.min)
+	}
+	return p
+}
+
+// the values of the variables of a point
+func (s *Space) Values(x []float64) []float64 {
+	p := s.Parameters(x)
+	v := make([]float64, len(s.dims))
+	for i, d := range s.dims {
+		v[i] = *d.value(&p)
+	}
+	return v
+}
+
+// the rules are shared, the optimizers never change them
+func copyParameters(p Parameters) Parameters {
+	c := p
+	c.Probabilities = append([]BPFP(nil), p.Probabilities...)
+	c.Discrete = append([]DiscreteVarWithLimit(nil), p.Discrete...)
+	c.Ranges = append([]Range(nil), p.Ranges...)
+	return c
+}
+
+func clamp(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// the point moved into the cube
+func Clamp(x []float64) []float64 {
+	c := make([]float64, len(x))
+	for i, v := range x {
+		c[i] = clamp(v)
+	}
+	return c
+}
@@ -0,0 +1,50 @@
+package experiment
+
+import "fmt"
+import "strings"
+
+import . "flache/ecm/model"
+
+// the numeric fields of SimRes which can be used as summary statistics
+var simResStats = map[string]func(SimRes) float64{
+	"Cultures":                    func(r SimRes) float64 { return float64(r.Cultures) },
+	"OnlineInteraction":           func(r SimRes) float64 { return float64(r.OnlineInteraction) },
+	"OfflineInteraction":          func(r SimRes) float64 { return float64(r.OfflineInteraction) },
+	"TotalEchoChambers":           func(r SimRes) float64 { return float64(r.TotalEchoChambers) },
+	"EchoChamberRatio":            func(r SimRes) float64 { return r.EchoChamberRatio },
+	"Events":                      func(r SimRes) float64 { return float64(r.Events) },
+	"Regions":                     func(r SimRes) float64 { return float64(r.Regions) },
+	"LargestRegion":               func(r SimRes) float64 { return r.LargestRegion },
+	"RecommendedSubscriptions":    func(r SimRes) float64 { return float64(r.RecommendedSubscriptions) },
+	"RecommendedEchoChamberRatio": func(r SimRes) float64 { return r.RecommendedEchoChamberRatio },
+	"SearchedEchoChamberRatio":    func(r SimRes) float64 { return r.SearchedEchoChamberRatio },
+	"TotalBlogs":                  func(r SimRes) float64 { return float64(r.TotalBlogs) },
+	"BlogAdoption":                func(r SimRes) float64 { return r.BlogAdoption },
+	"InteractionRatio":            interactionRatio,
+}
+
+// online per offline interaction, 0 without offline interaction
+func interactionRatio(r SimRes) float64 {
+	if r.OfflineInteraction == 0 {
+		return 0
+	}
+	return float64(r.OnlineInteraction) / float64(r.OfflineInteraction)
+}
+
+// the summary statistic of a run by name: a numeric field of SimRes,
+// InteractionRatio or EchoChamberRatios.<metric> and MetricMeans.<metric>
+func Statistic(name string) (func(SimRes) float64, error) {
+	if f, ok := simResStats[name]; ok {
+		return f, nil
+	}
+	if i := strings.Index(name, "."); i > 0 && contains(MetricNames, name[i+1:]) {
+		metric := name[i+1:]
+		switch name[:i] {
+		case "EchoChamberRatios":
+			return func(r SimRes) float64 { return r.EchoChamberRatios[metric] }, nil
+		case "MetricMeans":
+			return func(r SimRes) float64 { return r.MetricMeans[metric] }, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown statistic %q", name)
+}
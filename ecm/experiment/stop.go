@@ -0,0 +1,192 @@
+package experiment
+
+import "fmt"
+import "math"
+import "time"
+
+import . "flache/ecm/model"
+import "github.com/GaryBoone/GoStats/stats"
+
+// the stop reasons of a run which no condition ended
+const (
+	StopSteps    = "steps"
+	StopCanceled = "canceled"
+)
+
+// StopCondition ends a run before its last step. A condition keeps the
+// state of a single run.
+type StopCondition interface {
+	// the reason to stop after a step of the model, "" to go on
+	Stop(m *EchoChamberModel, r SimRes) string
+	// whether a stop means the model is stable
+	Converged() bool
+}
+
+// when a run stops before Steps
+type Stopping struct {
+	// the variance of statistics in a sliding window
+	Variance []VarianceStop
+	// check every Absorbing steps whether no feature can change any more,
+	// 0 never
+	Absorbing int
+	// the drift of statistics in a sliding window
+	Geweke []GewekeStop
+	// seconds of wall time a run may take, 0 for no limit. Unlike Timeout
+	// the run keeps its results.
+	WallTime float64
+}
+
+// fresh conditions for a run
+func (s Stopping) Conditions() ([]StopCondition, error) {
+	var conds []StopCondition
+	for _, v := range s.Variance {
+		c := v
+		var err error
+		if c.stat, err = Statistic(c.Stat); err != nil {
+			return nil, err
+		}
+		conds = append(conds, &c)
+	}
+	if s.Absorbing > 0 {
+		conds = append(conds, AbsorbingStop{Every: s.Absorbing})
+	}
+	for _, g := range s.Geweke {
+		c := g
+		var err error
+		if c.stat, err = Statistic(c.Stat); err != nil {
+			return nil, err
+		}
+		conds = append(conds, &c)
+	}
+	if s.WallTime > 0 {
+		conds = append(conds, &WallTimeStop{Limit: time.Duration(s.WallTime * float64(time.Second)),
+			start: time.Now()})
+	}
+	return conds, nil
+}
+
+func (s Stopping) validate() error {
+	for i, v := range s.Variance {
+		if _, err := Statistic(v.Stat); err != nil {
+			return fmt.Errorf("Stopping.Variance[%d]: %v", i, err)
+		}
+		if v.Window < 2 || v.Threshold < 0 {
+			return fmt.Errorf("Stopping.Variance[%d]: needs Window >= 2 and Threshold >= 0", i)
+		}
+	}
+	for i, g := range s.Geweke {
+		if _, err := Statistic(g.Stat); err != nil {
+			return fmt.Errorf("Stopping.Geweke[%d]: %v", i, err)
+		}
+		if g.First*float64(g.Window) < 2 || g.Last*float64(g.Window) < 2 || g.First+g.Last > 1 || g.Z <= 0 {
+			return fmt.Errorf("Stopping.Geweke[%d]: needs 2 steps in First and Last of the Window, First+Last <= 1 and Z > 0", i)
+		}
+	}
+	if s.Absorbing < 0 || s.WallTime < 0 {
+		return fmt.Errorf("Stopping: Absorbing and WallTime must not be negative")
+	}
+	return nil
+}
+
+// VarianceStop stops once the sample variance of a statistic over the
+// Window steps before the last one is below Threshold
+type VarianceStop struct {
+	Stat      string
+	Window    int
+	Threshold float64
+
+	stat   func(SimRes) float64
+	values []float64
+}
+
+func (v *VarianceStop) Stop(m *EchoChamberModel, r SimRes) string {
+	v.values = append(v.values, v.stat(r))
+	n := len(v.values)
+	if n-1 <= v.Window {
+		return ""
+	}
+	if stats.StatsSampleVariance(v.values[n-1-v.Window:n-1]) < v.Threshold {
+		return "variance:" + v.Stat
+	}
+	return ""
+}
+
+func (v *VarianceStop) Converged() bool { return true }
+
+// AbsorbingStop stops once no interaction, offline or online, can change a
+// feature any more (see EchoChamberModel.Absorbing), checked every Every
+// steps
+type AbsorbingStop struct {
+	Every int
+}
+
+func (a AbsorbingStop) Stop(m *EchoChamberModel, r SimRes) string {
+	if m.Step%a.Every == 0 && m.Absorbing() {
+		return "absorbing"
+	}
+	return ""
+}
+
+func (a AbsorbingStop) Converged() bool { return true }
+
+// GewekeStop compares the mean of a statistic over the First and the Last
+// share of a sliding window of Window steps and stops once their z-score is
+// below Z, the statistic no longer drifts
+type GewekeStop struct {
+	Stat   string
+	Window int
+	First  float64
+	Last   float64
+	Z      float64
+
+	stat   func(SimRes) float64
+	values []float64
+}
+
+func (g *GewekeStop) Stop(m *EchoChamberModel, r SimRes) string {
+	g.values = append(g.values, g.stat(r))
+	if len(g.values) < g.Window {
+		return ""
+	}
+	window := g.values[len(g.values)-g.Window:]
+	a := window[:int(math.Ceil(g.First*float64(g.Window)))]
+	b := window[g.Window-int(math.Ceil(g.Last*float64(g.Window))):]
+	meanA, meanB := mean(a), mean(b)
+	if meanA == meanB {
+		return "geweke:" + g.Stat
+	}
+	se := math.Sqrt(stats.StatsSampleVariance(a)/float64(len(a)) + stats.StatsSampleVariance(b)/float64(len(b)))
+	if math.Abs(meanA-meanB)/se < g.Z {
+		return "geweke:" + g.Stat
+	}
+	return ""
+}
+
+func (g *GewekeStop) Converged() bool { return true }
+
+func mean(v []float64) float64 {
+	sum := 0.0
+	for _, x := range v {
+		sum += x
+	}
+	return sum / float64(len(v))
+}
+
+// WallTimeStop stops a run after Limit, the clock starts with Conditions or
+// else at the first step
+type WallTimeStop struct {
+	Limit time.Duration
+	start time.Time
+}
+
+func (w *WallTimeStop) Stop(m *EchoChamberModel, r SimRes) string {
+	if w.start.IsZero() {
+		w.start = time.Now()
+	}
+	if time.Since(w.start) >= w.Limit {
+		return "walltime"
+	}
+	return ""
+}
+
+func (w *WallTimeStop) Converged() bool { return false }
@@ -0,0 +1,117 @@
+package experiment
+
+import "math"
+import "testing"
+
+// feeds the echo chamber ratios to the conditions of s and returns the step
+// of the first stop and its reason, 0 if none stops
+func stopAt(t *testing.T, s Stopping, series []float64) (int, string) {
+	t.Helper()
+	conds, err := s.Conditions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range series {
+		for _, c := range conds {
+			if reason := c.Stop(nil, SimRes{EchoChamberRatio: v}); reason != "" {
+				return i + 1, reason
+			}
+		}
+	}
+	return 0, ""
+}
+
+func TestVarianceStop(t *testing.T) {
+	s := Stopping{Variance: []VarianceStop{{Stat: "EchoChamberRatio", Window: 3, Threshold: 0.01}}}
+	// the window before the last step is flat after step 7
+	step, reason := stopAt(t, s, []float64{0, 1, 0, 1, 5, 5, 5, 9, 9, 9})
+	if step != 8 || reason != "variance:EchoChamberRatio" {
+		t.Errorf("stopped at step %d (%q), want step 8", step, reason)
+	}
+	if step, _ := stopAt(t, s, []float64{0, 1, 0, 1, 0, 1, 0, 1}); step != 0 {
+		t.Errorf("an oscillating series stopped at step %d", step)
+	}
+}
+
+func TestGewekeStop(t *testing.T) {
+	s := Stopping{Geweke: []GewekeStop{{Stat: "EchoChamberRatio", Window: 10, First: 0.3, Last: 0.5, Z: 2}}}
+	flat, drift, constant, levels := make([]float64, 50), make([]float64, 50), make([]float64, 50), make([]float64, 50)
+	for i := range flat {
+		flat[i] = float64(1 + i%2)
+		drift[i] = float64(i)
+		constant[i] = 0.5
+		levels[i] = math.Min(float64(i), 20)
+	}
+	tests := []struct {
+		name   string
+		series []float64
+		step   int
+	}{
+		// a full window is needed
+		{"flat", flat, 10},
+		{"constant", constant, 10},
+		{"drift", drift, 0},
+		// the window 18, 19, 20, ... of step 28 has the z-score √3
+		{"drift levels off", levels, 28},
+	}
+	for _, tt := range tests {
+		step, reason := stopAt(t, s, tt.series)
+		if step != tt.step || step > 0 && reason != "geweke:EchoChamberRatio" {
+			t.Errorf("%s: stopped at step %d (%q), want step %d", tt.name, step, reason, tt.step)
+		}
+	}
+}
+
+func TestStoppingConditions(t *testing.T) {
+	s := Stopping{
+		Variance:  []VarianceStop{{Stat: "Cultures", Window: 3}},
+		Absorbing: 10,
+		Geweke:    []GewekeStop{{Stat: "Regions", Window: 10, First: 0.3, Last: 0.5, Z: 2}},
+		WallTime:  1,
+	}
+	conds, err := s.Conditions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conds) != 4 || conds[3].Converged() {
+		t.Errorf("%d conditions, want 4 and the wall time not converged", len(conds))
+	}
+	// every run gets fresh conditions
+	again, _ := s.Conditions()
+	conds[0].Stop(nil, SimRes{})
+	if len(again[0].(*VarianceStop).values) != 0 {
+		t.Errorf("conditions share their state")
+	}
+
+	s.Variance[0].Stat = "Nothing"
+	if _, err := s.Conditions(); err == nil {
+		t.Errorf("conditions of an unknown statistic")
+	}
+}
+
+func TestStoppingValidate(t *testing.T) {
+	variance := func(v VarianceStop) Stopping { return Stopping{Variance: []VarianceStop{v}} }
+	geweke := func(g GewekeStop) Stopping { return Stopping{Geweke: []GewekeStop{g}} }
+	tests := []struct {
+		name  string
+		s     Stopping
+		valid bool
+	}{
+		{"none", Stopping{}, true},
+		{"variance", variance(VarianceStop{Stat: "Cultures", Window: 2}), true},
+		{"variance of an unknown statistic", variance(VarianceStop{Stat: "Nothing", Window: 2}), false},
+		{"variance window", variance(VarianceStop{Stat: "Cultures", Window: 1}), false},
+		{"negative threshold", variance(VarianceStop{Stat: "Cultures", Window: 2, Threshold: -1}), false},
+		{"geweke", geweke(GewekeStop{Stat: "Cultures", Window: 10, First: 0.2, Last: 0.5, Z: 2}), true},
+		{"geweke first of one step", geweke(GewekeStop{Stat: "Cultures", Window: 10, First: 0.1, Last: 0.5, Z: 2}), false},
+		{"geweke overlap", geweke(GewekeStop{Stat: "Cultures", Window: 10, First: 0.6, Last: 0.5, Z: 2}), false},
+		{"geweke z", geweke(GewekeStop{Stat: "Cultures", Window: 10, First: 0.2, Last: 0.5}), false},
+		{"negative absorbing", Stopping{Absorbing: -1}, false},
+		{"negative wall time", Stopping{WallTime: -1}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.s.validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
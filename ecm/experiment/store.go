@@ -0,0 +1,284 @@
+package experiment
+
+import "bufio"
+import "bytes"
+import "crypto/sha256"
+import "encoding/hex"
+import "encoding/json"
+import "fmt"
+import "io"
+import "math"
+import "os"
+import "sync"
+import "time"
+
+// Row is a finished run in the store
+type Row struct {
+	// hash of the parameter set, replicate and seed
+	Key string
+	// hash of the parameter set
+	Set        string
+	Parameters Parameters
+	// hash of the experiment, see Experiment.RunKey
+	Config    string
+	Replicate int
+	Seed      int64
+
+	Score float64
+	Res   SimRes
+	Time  time.Time
+}
+
+// Store is an append-only file of finished runs, one json row per line.
+// Every row is synced to disk before Append returns, a row cut off by a
+// crash is dropped when the store is opened again. The keys only cover the
+// swept parameters, a store holds the runs of a single experiment (see
+// Check).
+type Store struct {
+	mu   sync.Mutex
+	f    *os.File
+	rows []Row
+	keys map[string]int
+	// (set, replicate) -> row, to find the seed of a run
+	replicates map[string]int
+}
+
+// hash of a parameter set, equal parameters have equal hashes
+func SetKey(p Parameters) string {
+	// maps are encoded with sorted keys, the encoding is canonical
+	data, err := json.Marshal(p)
+	if err != nil {
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// hash of the settings which change the result of a run of a parameter set
+// with a seed. The settings which only pick the runs (sweep, seeds,
+// replicates), score them or write them out are left out, so a store serves
+// e.g. a sweep with more samples or another target.
+func (x *Experiment) RunKey() string {
+	y := *x
+	y.TargetMetric, y.Target, y.Objectives = "", 0, nil
+	y.Sweep, y.Optimizer, y.ABC, y.Sensitivity = Sweep{}, Optimizer{}, ABC{}, Sensitivity{}
+	y.Replicates, y.CPUs, y.Replication, y.Timeout, y.Seed = 0, 0, Replication{}, 0, 0
+	y.Output = Output{Checkpoints: Checkpoints{Restore: x.Output.Checkpoints.Restore}}
+	data, err := json.Marshal(y)
+	if err != nil {
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+func RowKey(set string, replicate int, seed int64) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%d/%d", set, replicate, seed)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// opens or creates the store at path
+func OpenStore(path string) (*Store, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s := newStore()
+	good, err := s.load(f)
+	if err == nil {
+		// drop a row cut off by a crash
+		if err = f.Truncate(good); err == nil {
+			_, err = f.Seek(good, io.SeekStart)
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	s.f = f
+	return s, nil
+}
+
+// reads the runs of a store, the returned store is read-only
+func ReadStore(r io.Reader) (*Store, error) {
+	s := newStore()
+	if _, err := s.load(r); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func newStore() *Store {
+	return &Store{keys: make(map[string]int), replicates: make(map[string]int)}
+}
+
+// reads the rows and returns the length of the complete ones
+func (s *Store) load(rd io.Reader) (int64, error) {
+	r := bufio.NewReader(rd)
+	good := int64(0)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// an unterminated last line is an interrupted write
+			return good, nil
+		}
+		if err != nil {
+			return good, err
+		}
+		var row Row
+		if err := json.Unmarshal(bytes.TrimSpace(line), &row); err != nil {
+			if _, e := r.Peek(1); e == io.EOF {
+				// the last line is broken, drop it
+				return good, nil
+			}
+			return good, fmt.Errorf("corrupt row at offset %d: %v", good, err)
+		}
+		s.add(row)
+		good += int64(len(line))
+	}
+}
+
+// fails if a run of the store was made with another experiment, its
+// results would be reused for this one
+func (s *Store) Check(config string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, row := range s.rows {
+		if row.Config != config {
+			return fmt.Errorf("the store holds runs of another experiment (%s, this one is %s)", row.Config, config)
+		}
+	}
+	return nil
+}
+
+func (s *Store) add(row Row) {
+	if _, ok := s.keys[row.Key]; ok {
+		return
+	}
+	s.keys[row.Key] = len(s.rows)
+	s.replicates[fmt.Sprintf("%s/%d", row.Set, row.Replicate)] = len(s.rows)
+	s.rows = append(s.rows, row)
+}
+
+// the stored run with the key
+func (s *Store) Get(key string) (Row, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.keys[key]
+	if !ok {
+		return Row{}, false
+	}
+	return s.rows[i], true
+}
+
+// the stored run of a replicate of the set, whatever its seed
+func (s *Store) Replicate(set string, replicate int) (Row, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.replicates[fmt.Sprintf("%s/%d", set, replicate)]
+	if !ok {
+		return Row{}, false
+	}
+	return s.rows[i], true
+}
+
+// appends a run and syncs it to disk, a run already stored is ignored
+func (s *Store) Append(row Row) error {
+	if row.Set == "" {
+		row.Set = SetKey(row.Parameters)
+	}
+	if row.Key == "" {
+		row.Key = RowKey(row.Set, row.Replicate, row.Seed)
+	}
+	if row.Time.IsZero() {
+		row.Time = time.Now()
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[row.Key]; ok {
+		return nil
+	}
+	if s.f == nil {
+		return fmt.Errorf("store is read-only")
+	}
+	if _, err := s.f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if err := s.f.Sync(); err != nil {
+		return err
+	}
+	s.add(row)
+	return nil
+}
+
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.rows)
+}
+
+func (s *Store) Close() error {
+	if s.f == nil {
+		return nil
+	}
+	return s.f.Close()
+}
+
+// the runs of one parameter set
+type Group struct {
+	Set        string
+	Parameters Parameters
+	Rows       []Row
+}
+
+// all runs grouped by parameter set, in the order the sets were first stored
+func (s *Store) Groups() []Group {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var groups []Group
+	index := make(map[string]int)
+	for _, row := range s.rows {
+		i, ok := index[row.Set]
+		if !ok {
+			i = len(groups)
+			index[row.Set] = i
+			groups = append(groups, Group{Set: row.Set, Parameters: row.Parameters})
+		}
+		groups[i].Rows = append(groups[i].Rows, row)
+	}
+	return groups
+}
+
+// the value of every run of the group
+func (g Group) Values(f func(Row) float64) []float64 {
+	v := make([]float64, len(g.Rows))
+	for i, row := range g.Rows {
+		v[i] = f(row)
+	}
+	return v
+}
+
+// mean and standard deviation of the scores
+func (g Group) Score() (mean, sd float64) {
+	scores := g.Values(func(r Row) float64 { return r.Score })
+	if len(scores) == 0 {
+		return math.NaN(), math.NaN()
+	}
+	for _, v := range scores {
+		mean += v
+	}
+	mean /= float64(len(scores))
+	if len(scores) < 2 {
+		return mean, 0
+	}
+	for _, v := range scores {
+		sd += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sd / float64(len(scores)-1))
+}
@@ -0,0 +1,164 @@
+package experiment
+
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "testing"
+
+func testRows(n int) []Row {
+	rows := make([]Row, n)
+	for i := range rows {
+		rows[i] = Row{Parameters: Parameters{Probabilities: []BPFP{{}}}, Config: "c",
+			Replicate: i, Seed: int64(100 + i), Score: float64(i) / 10}
+		rows[i].Parameters.Probabilities[0].Alpha.Var = float64(i % 2)
+	}
+	return rows
+}
+
+func TestStoreRecovery(t *testing.T) {
+	tests := []struct {
+		name string
+		// changes the file of three stored rows
+		damage func(data []byte) []byte
+		// rows found when the store is opened again, -1 if it fails
+		rows int
+	}{
+		{"intact", func(d []byte) []byte { return d }, 3},
+		{"truncated last line", func(d []byte) []byte { return d[:len(d)-10] }, 2},
+		{"cut at the newline", func(d []byte) []byte { return d[:len(d)-1] }, 2},
+		{"broken last line", func(d []byte) []byte { return append(d, "{\"Key\": 1x\n"...) }, 3},
+		{"corrupt middle row", func(d []byte) []byte {
+			d = append([]byte(nil), d...)
+			d[1] = 'x'
+			return d
+		}, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "store")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "runs.jsonl")
+
+			s, err := OpenStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rows := testRows(3)
+			for _, row := range rows {
+				if err := s.Append(row); err != nil {
+					t.Fatal(err)
+				}
+			}
+			s.Close()
+
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(path, tt.damage(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			s, err = OpenStore(path)
+			if tt.rows < 0 {
+				if err == nil {
+					s.Close()
+					t.Fatal("opened a corrupt store")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Len() != tt.rows {
+				t.Fatalf("%d rows, want %d", s.Len(), tt.rows)
+			}
+
+			// resume: the lost runs are run again, the stored ones are found
+			for _, row := range rows {
+				set := SetKey(row.Parameters)
+				if _, ok := s.Get(RowKey(set, row.Replicate, row.Seed)); !ok {
+					if err := s.Append(row); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			s.Close()
+
+			s, err = OpenStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			if s.Len() != len(rows) {
+				t.Fatalf("%d rows after the resume, want %d", s.Len(), len(rows))
+			}
+			for _, row := range rows {
+				got, ok := s.Replicate(SetKey(row.Parameters), row.Replicate)
+				if !ok || got.Seed != row.Seed || got.Score != row.Score {
+					t.Errorf("replicate %d: %+v", row.Replicate, got)
+				}
+			}
+		})
+	}
+}
+
+func TestStoreAppendTwice(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := OpenStore(filepath.Join(dir, "runs.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	row := testRows(1)[0]
+	for i := 0; i < 2; i++ {
+		if err := s.Append(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.Len() != 1 {
+		t.Errorf("%d rows, want 1", s.Len())
+	}
+}
+
+func TestStoreCheck(t *testing.T) {
+	x := DefaultExperiment()
+	key := x.RunKey()
+
+	tests := []struct {
+		name   string
+		change func(x *Experiment)
+		same   bool
+	}{
+		{"unchanged", func(x *Experiment) {}, true},
+		{"more samples", func(x *Experiment) { x.Sweep.Samples *= 2 }, true},
+		{"other target", func(x *Experiment) { x.Target = 0.5 }, true},
+		{"other seed", func(x *Experiment) { x.Seed = 42 }, true},
+		{"more cpus", func(x *Experiment) { x.CPUs++ }, true},
+		{"other scores file", func(x *Experiment) { x.Output.Scores = "other.csv" }, true},
+		{"more agents", func(x *Experiment) { x.Agents++ }, false},
+		{"other metric", func(x *Experiment) { x.Similarity = "jaccard" }, false},
+		{"a stop condition", func(x *Experiment) { x.Stopping.Absorbing = 10 }, false},
+		{"a rule", func(x *Experiment) { x.Rules["threaded_replies"] = true }, false},
+		{"restored", func(x *Experiment) { x.Output.Checkpoints.Restore = "ecm.json" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			y := DefaultExperiment()
+			tt.change(y)
+			s := newStore()
+			s.add(Row{Key: "k", Config: key})
+			err := s.Check(y.RunKey())
+			if (err == nil) != tt.same {
+				t.Errorf("Check: %v, want the same experiment: %v", err, tt.same)
+			}
+		})
+	}
+}
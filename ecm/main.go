@@ -0,0 +1,169 @@
+/*
+ecm runs the echo chamber models.
+
+	ecm run         [variant] [flags]   a single simulation
+	ecm sweep       [variant] [flags]   a parameter study
+	ecm calibrate   [variant] [flags]   search the parameters closest to the target
+	ecm sensitivity [variant] [flags]   which factors drive an output statistic
+	ecm analyze     files...            summarize result files
+
+The variant defaults to ecm2, `ecm <command> <variant> -h` lists its flags.
+*/
+package main
+
+import "goabm"
+import ghgoabm "github.com/nairboon/goabm"
+import "flag"
+import "fmt"
+import "io"
+import "log"
+import "os"
+import "runtime/pprof"
+import "sort"
+import "strings"
+
+// Variant is a model the ecm tool can run
+type Variant interface {
+	// one line for the usage
+	Description() string
+	// registers the parameters of the variant for the command (run, sweep
+	// or calibrate)
+	Flags(cmd string, fs *flag.FlagSet)
+	// called after the flags are parsed with the arguments of the command
+	Init(args []string) error
+	// a single simulation
+	Run(w io.Writer) error
+	// a parameter study
+	Sweep(w io.Writer) error
+}
+
+// variants with a target function to calibrate against
+type Calibrator interface {
+	Calibrate(w io.Writer) error
+}
+
+// variants with factors to analyze the sensitivity of
+type SensitivityAnalyzer interface {
+	Sensitivity(w io.Writer) error
+}
+
+var variants = make(map[string]Variant)
+
+func register(name string, v Variant) {
+	if _, ok := variants[name]; ok {
+		panic("variant registered twice: " + name)
+	}
+	variants[name] = v
+}
+
+const defaultVariant = "ecm2"
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: ecm <command> [variant] [flags]\n\n")
+	fmt.Fprintf(os.Stderr, "commands:\n")
+	fmt.Fprintf(os.Stderr, "  run          a single simulation\n")
+	fmt.Fprintf(os.Stderr, "  sweep        a parameter study\n")
+	fmt.Fprintf(os.Stderr, "  calibrate    search the parameters closest to the target\n")
+	fmt.Fprintf(os.Stderr, "  sensitivity  which factors drive an output statistic\n")
+	fmt.Fprintf(os.Stderr, "  analyze      summarize result files\n\n")
+	fmt.Fprintf(os.Stderr, "variants (default %s):\n", defaultVariant)
+	names := make([]string, 0, len(variants))
+	for name := range variants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, variants[name].Description())
+	}
+}
+
+// runs the command of a variant, args are the arguments after the command
+func runVariant(cmd string, args []string) error {
+	name := defaultVariant
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	v, ok := variants[name]
+	if !ok {
+		return fmt.Errorf("unknown variant: %s", name)
+	}
+	c, calibrates := v.(Calibrator)
+	if cmd == "calibrate" && !calibrates {
+		return fmt.Errorf("%s can not be calibrated", name)
+	}
+	sa, analyzes := v.(SensitivityAnalyzer)
+	if cmd == "sensitivity" && !analyzes {
+		return fmt.Errorf("%s has no sensitivity analysis", name)
+	}
+
+	fs := flag.NewFlagSet("ecm "+cmd+" "+name, flag.ExitOnError)
+	cpuprofile := fs.String("cpuprofile", "", "write cpu profile to file")
+	memprofile := fs.String("memprofile", "", "write memory profile to this file")
+	v.Flags(cmd, fs)
+	fs.Parse(args)
+	if err := v.Init(args); err != nil {
+		return err
+	}
+
+	if *cpuprofile != "" {
+		f, err := os.Create(*cpuprofile)
+		if err != nil {
+			return err
+		}
+		pprof.StartCPUProfile(f)
+		defer pprof.StopCPUProfile()
+	}
+
+	var err error
+	switch cmd {
+	case "run":
+		err = v.Run(os.Stdout)
+	case "sweep":
+		err = v.Sweep(os.Stdout)
+	case "calibrate":
+		err = c.Calibrate(os.Stdout)
+	case "sensitivity":
+		err = sa.Sensitivity(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *memprofile != "" {
+		f, err := os.Create(*memprofile)
+		if err != nil {
+			return err
+		}
+		pprof.WriteHeapProfile(f)
+		f.Close()
+	}
+	return nil
+}
+
+func main() {
+	//initialize the goabm library (logs & flags), ra-ec is built on the
+	//github version of it which needs its own initialization
+	goabm.Init()
+	ghgoabm.Init()
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "run", "sweep", "calibrate", "sensitivity":
+		err = runVariant(cmd, args)
+	case "analyze":
+		err = analyze(os.Stdout, args)
+	case "help", "-h", "-help", "--help":
+		usage()
+	default:
+		usage()
+		err = fmt.Errorf("unknown command: %s", cmd)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,502 @@
+package model
+
+import "encoding/json"
+import "fmt"
+import "goabm"
+import "io"
+import "os"
+import "sort"
+
+// version of the checkpoint file format, bump it whenever the layout of
+// Snapshot changes
+const SnapshotVersion = 2
+
+const snapshotFormat = "ecm-checkpoint"
+
+// Snapshot holds the complete state of a running EchoChamberModel. The
+// parameters (probability functions, policies, metrics) are not part of
+// it, a snapshot is restored into a model which was set up the same way
+// (or differently, to branch a counterfactual run). The landscape has to
+// match, of goabm's state only the agents are restored.
+type Snapshot struct {
+	Format  string
+	Version int
+
+	Step      int
+	Seed      int64
+	RandState uint64
+
+	// the landscape with movement, 0 for other landscapes
+	Size  int
+	Sight float64
+
+	Stats           ModelStats
+	Agents          []AgentState
+	Blogs           []BlogState
+	SubscriptionLog []SubscriptionEvent
+}
+
+// the stats of the model at the time of the snapshot
+type ModelStats struct {
+	Cultures           int
+	OnlineInteraction  int
+	OfflineInteraction int
+	TotalComments      int
+	TotalBlogPosts     int
+	TotalBlogs         int
+	TotalEchoChambers  int
+	EchoChamberRatio   float64
+
+	Subscriptions   int
+	Unsubscriptions int
+	AvgAudience     float64
+
+	MaxThreadDepth int
+	AvgThreadDepth float64
+
+	RecommendedSubscriptions    int
+	RecommendedEchoChamberRatio float64
+	SearchedEchoChamberRatio    float64
+
+	Regions       int
+	LargestRegion float64
+	Metrics       *MetricsReport
+}
+
+// the state of an agent. The parameters are informational, Restore draws
+// them again from the model.
+type AgentState struct {
+	ID goabm.AgentID
+	// the goabm agent (position etc.) as goabm encodes it
+	Agent json.RawMessage
+
+	OnlineInteraction  int
+	OfflineInteraction int
+
+	PStartBlogging   float64
+	PWriteBlogPost   float64
+	PRespondBlogPost float64
+	PUnderstanding   float64
+	POnline          float64
+
+	RSubscribedBlogs        IntRange
+	RSimilarityConfortLevel FloatRange
+
+	Steplength float64
+	PVeloc     float64
+
+	Features Feature
+
+	// -1 if the agent has no blog
+	Blog int
+	// followed blog ids in subscription order
+	Followed    []int
+	Recommended []int
+	ReadPosts   map[int][]int
+}
+
+type BlogState struct {
+	ID        int
+	Owner     goabm.AgentID
+	Posts     []*Comment
+	Followers []goabm.AgentID
+}
+
+// captures the current state of the model
+func (e *EchoChamberModel) Snapshot() (*Snapshot, error) {
+	e.Rng()
+	s := &Snapshot{Format: snapshotFormat, Version: SnapshotVersion,
+		Step: e.Step, Seed: e.Seed, RandState: e.Source.State,
+		SubscriptionLog: append([]SubscriptionEvent(nil), e.SubscriptionLog...)}
+
+	s.Stats = ModelStats{
+		Cultures:                    e.Cultures,
+		OnlineInteraction:           e.OnlineInteraction,
+		OfflineInteraction:          e.OfflineInteraction,
+		TotalComments:               e.TotalComments,
+		TotalBlogPosts:              e.TotalBlogPosts,
+		TotalBlogs:                  e.TotalBlogs,
+		TotalEchoChambers:           e.TotalEchoChambers,
+		EchoChamberRatio:            e.EchoChamberRatio,
+		Subscriptions:               e.Subscriptions,
+		Unsubscriptions:             e.Unsubscriptions,
+		AvgAudience:                 e.AvgAudience,
+		MaxThreadDepth:              e.MaxThreadDepth,
+		AvgThreadDepth:              e.AvgThreadDepth,
+		RecommendedSubscriptions:    e.RecommendedSubscriptions,
+		RecommendedEchoChamberRatio: e.RecommendedEchoChamberRatio,
+		SearchedEchoChamberRatio:    e.SearchedEchoChamberRatio,
+		Regions:                     e.Regions,
+		LargestRegion:               e.LargestRegion,
+		Metrics:                     e.Metrics.clone(),
+	}
+	if l, ok := e.Landscape.(*goabm.FixedLandscapeWithMovement); ok {
+		s.Size, s.Sight = l.Size, l.Sight
+	}
+
+	for _, b := range *e.Landscape.GetAgents() {
+		a := b.(*EchoChamberAgent)
+		ga, err := json.Marshal(a.FLWMAgent)
+		if err != nil {
+			return nil, err
+		}
+
+		as := AgentState{ID: a.ID(), Agent: ga,
+			OnlineInteraction:       a.OnlineInteraction,
+			OfflineInteraction:      a.OfflineInteraction,
+			PStartBlogging:          a.PStartBlogging,
+			PWriteBlogPost:          a.PWriteBlogPost,
+			PRespondBlogPost:        a.PRespondBlogPost,
+			PUnderstanding:          a.PUnderstanding,
+			POnline:                 a.POnline,
+			RSubscribedBlogs:        a.RSubscribedBlogs,
+			RSimilarityConfortLevel: a.RSimilarityConfortLevel,
+			Steplength:              a.Steplength,
+			PVeloc:                  a.PVeloc,
+			Features:                append(Feature(nil), a.Features...),
+			Blog:                    -1,
+			ReadPosts:               make(map[int][]int),
+		}
+		if a.MyBlog != nil {
+			as.Blog = a.MyBlog.ID
+		}
+		for _, blog := range a.MySubscriptions.Blogs() {
+			as.Followed = append(as.Followed, blog.ID)
+			if a.MySubscriptions.Recommended[blog.ID] {
+				as.Recommended = append(as.Recommended, blog.ID)
+			}
+		}
+		for id, read := range a.MySubscriptions.ReadPosts {
+			posts := make([]int, 0, len(read))
+			for p := range read {
+				posts = append(posts, p)
+			}
+			sort.Ints(posts)
+			as.ReadPosts[id] = posts
+		}
+		s.Agents = append(s.Agents, as)
+	}
+
+	for _, blog := range e.Blogger {
+		s.Blogs = append(s.Blogs, BlogState{ID: blog.ID, Owner: blog.Owner,
+			Posts:     clonePosts(blog.Posts),
+			Followers: append([]goabm.AgentID(nil), blog.Followers...)})
+	}
+	sort.Sort(byBlogID(s.Blogs))
+	return s, nil
+}
+
+// restores the model to the state of the snapshot. The model has to be
+// initialized (sim.Init) with the same number of agents on the same
+// landscape. The parameters of the agents are drawn again the way
+// CreateAgent drew them from Seed, so a model set up like the checkpointed
+// run gets the same values and one set up differently its own. The whole
+// snapshot is checked first, a snapshot which does not fit leaves the model
+// as it was.
+func (e *EchoChamberModel) Restore(s *Snapshot) error {
+	if s.Format != snapshotFormat {
+		return fmt.Errorf("not an ecm checkpoint: %q", s.Format)
+	}
+	if s.Version != SnapshotVersion {
+		return fmt.Errorf("unsupported checkpoint version %d, expected %d", s.Version, SnapshotVersion)
+	}
+	if l, ok := e.Landscape.(*goabm.FixedLandscapeWithMovement); ok && (l.Size != s.Size || l.Sight != s.Sight) {
+		return fmt.Errorf("checkpoint of a %d landscape with sight %g, the model has %d and %g",
+			s.Size, s.Sight, l.Size, l.Sight)
+	}
+
+	agents := make(map[goabm.AgentID]*EchoChamberAgent)
+	for _, b := range *e.Landscape.GetAgents() {
+		a := b.(*EchoChamberAgent)
+		agents[a.ID()] = a
+	}
+	if len(agents) != len(s.Agents) {
+		return fmt.Errorf("checkpoint has %d agents, the model %d", len(s.Agents), len(agents))
+	}
+
+	// blogs
+	blogger := make(map[goabm.AgentID]*Blog)
+	index := NewBlogIndex()
+	blogs := make(map[int]*Blog)
+	for _, bs := range s.Blogs {
+		if _, ok := blogs[bs.ID]; ok {
+			return fmt.Errorf("blog %d is in the checkpoint twice", bs.ID)
+		}
+		if _, ok := agents[bs.Owner]; !ok {
+			return fmt.Errorf("owner %d of blog %d is not in the model", bs.Owner, bs.ID)
+		}
+		if _, ok := blogger[bs.Owner]; ok {
+			return fmt.Errorf("agent %d owns two blogs", bs.Owner)
+		}
+		blog := &Blog{ID: bs.ID, Owner: bs.Owner, Posts: clonePosts(bs.Posts),
+			Followers: append([]goabm.AgentID(nil), bs.Followers...), index: index}
+		for _, p := range blog.Posts {
+			if err := e.checkThread(p); err != nil {
+				return fmt.Errorf("blog %d: %v", bs.ID, err)
+			}
+		}
+		blogger[blog.Owner] = blog
+		blogs[blog.ID] = blog
+		index.Update(blog)
+	}
+	blog := func(id int) (*Blog, error) {
+		if b, ok := blogs[id]; ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("unknown blog %d", id)
+	}
+
+	// agents, goabm's part is decoded into a copy until everything fits
+	seen := make(map[goabm.AgentID]bool)
+	positions := make([]goabm.FLWMAgent, len(s.Agents))
+	for i, as := range s.Agents {
+		a, ok := agents[as.ID]
+		if !ok {
+			return fmt.Errorf("agent %d of the checkpoint is not in the model", as.ID)
+		}
+		if seen[as.ID] {
+			return fmt.Errorf("agent %d is in the checkpoint twice", as.ID)
+		}
+		seen[as.ID] = true
+		if len(as.Features) != e.NFeatures {
+			return fmt.Errorf("agent %d has %d features, the model %d", as.ID, len(as.Features), e.NFeatures)
+		}
+		if a.FLWMAgent != nil {
+			positions[i] = *a.FLWMAgent
+			if err := json.Unmarshal(as.Agent, &positions[i]); err != nil {
+				return fmt.Errorf("agent %d: %v", as.ID, err)
+			}
+		}
+		if as.Blog >= 0 {
+			b, err := blog(as.Blog)
+			if err != nil {
+				return fmt.Errorf("agent %d: %v", as.ID, err)
+			}
+			if b.Owner != as.ID {
+				return fmt.Errorf("agent %d: blog %d is owned by %d", as.ID, b.ID, b.Owner)
+			}
+		}
+		for _, ids := range [][]int{as.Followed, as.Recommended} {
+			for _, id := range ids {
+				if _, err := blog(id); err != nil {
+					return fmt.Errorf("agent %d: %v", as.ID, err)
+				}
+			}
+		}
+		for id := range as.ReadPosts {
+			if _, err := blog(id); err != nil {
+				return fmt.Errorf("agent %d: %v", as.ID, err)
+			}
+		}
+	}
+
+	e.Step = s.Step
+	e.Seed = s.Seed
+	e.Rng()
+
+	st := s.Stats
+	e.Cultures = st.Cultures
+	e.OnlineInteraction = st.OnlineInteraction
+	e.OfflineInteraction = st.OfflineInteraction
+	e.TotalComments = st.TotalComments
+	e.TotalBlogPosts = st.TotalBlogPosts
+	e.TotalBlogs = st.TotalBlogs
+	e.TotalEchoChambers = st.TotalEchoChambers
+	e.EchoChamberRatio = st.EchoChamberRatio
+	e.Subscriptions = st.Subscriptions
+	e.Unsubscriptions = st.Unsubscriptions
+	e.AvgAudience = st.AvgAudience
+	e.MaxThreadDepth = st.MaxThreadDepth
+	e.AvgThreadDepth = st.AvgThreadDepth
+	e.RecommendedSubscriptions = st.RecommendedSubscriptions
+	e.RecommendedEchoChamberRatio = st.RecommendedEchoChamberRatio
+	e.SearchedEchoChamberRatio = st.SearchedEchoChamberRatio
+	e.Regions = st.Regions
+	e.LargestRegion = st.LargestRegion
+	e.Metrics = st.Metrics.clone()
+
+	e.SubscriptionLog = append([]SubscriptionEvent(nil), s.SubscriptionLog...)
+
+	e.Blogger = blogger
+	e.Search = index
+
+	for i, as := range s.Agents {
+		a := agents[as.ID]
+		if a.FLWMAgent != nil {
+			*a.FLWMAgent = positions[i]
+		}
+
+		a.OnlineInteraction = as.OnlineInteraction
+		a.OfflineInteraction = as.OfflineInteraction
+		a.Features = append(Feature(nil), as.Features...)
+
+		a.MyBlog = nil
+		if as.Blog >= 0 {
+			a.MyBlog = blogs[as.Blog]
+		}
+
+		// rebuild the subscriptions without triggering the follower
+		// bookkeeping, the followers are part of the blog state
+		sub := &a.MySubscriptions
+		sub.FollowedBlogs = make(map[int]*Blog)
+		sub.ReadPosts = make(map[int]map[int]bool)
+		sub.Recommended = make(map[int]bool)
+		for _, id := range as.Followed {
+			sub.FollowedBlogs[len(sub.FollowedBlogs)] = blogs[id]
+		}
+		for _, id := range as.Recommended {
+			sub.Recommended[id] = true
+		}
+		for id, posts := range as.ReadPosts {
+			read := make(map[int]bool, len(posts))
+			for _, p := range posts {
+				read[p] = true
+			}
+			sub.ReadPosts[id] = read
+		}
+	}
+
+	e.redrawAgents(agents)
+	e.Source.State = s.RandState
+	// the agents moved
+	e.located = nil
+	return nil
+}
+
+// checks that the messages of a thread fit the model
+func (e *EchoChamberModel) checkThread(c *Comment) error {
+	if len(c.Message) != e.NFeatures {
+		return fmt.Errorf("a message of %d features, the model has %d", len(c.Message), e.NFeatures)
+	}
+	for _, r := range c.Responses {
+		if err := e.checkThread(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// draws the parameters of the agents again, replaying CreateAgent from Seed
+// in the order of the agent ids. Their features are kept.
+func (e *EchoChamberModel) redrawAgents(agents map[goabm.AgentID]*EchoChamberAgent) {
+	ids := make([]int, 0, len(agents))
+	for id := range agents {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+
+	e.Rng()
+	e.Source.Seed(e.Seed)
+	for _, id := range ids {
+		a := agents[goabm.AgentID(id)]
+		features := a.Features
+		e.drawAgent(a)
+		a.Features = features
+	}
+}
+
+// deep copy of the report, nil stays nil
+func (r *MetricsReport) clone() *MetricsReport {
+	if r == nil {
+		return nil
+	}
+	c := &MetricsReport{EchoChambers: make(map[string]int, len(r.EchoChambers)),
+		Ratios: make(map[string]float64, len(r.Ratios)),
+		Means:  make(map[string]float64, len(r.Means))}
+	for k, v := range r.EchoChambers {
+		c.EchoChambers[k] = v
+	}
+	for k, v := range r.Ratios {
+		c.Ratios[k] = v
+	}
+	for k, v := range r.Means {
+		c.Means[k] = v
+	}
+	for _, b := range r.Blogs {
+		cb := BlogMetrics{Blog: b.Blog, Responses: b.Responses, Followers: b.Followers,
+			Values:      make(map[string]float64, len(b.Values)),
+			Valid:       make(map[string]bool, len(b.Valid)),
+			EchoChamber: make(map[string]bool, len(b.EchoChamber))}
+		for k, v := range b.Values {
+			cb.Values[k] = v
+		}
+		for k, v := range b.Valid {
+			cb.Valid[k] = v
+		}
+		for k, v := range b.EchoChamber {
+			cb.EchoChamber[k] = v
+		}
+		c.Blogs = append(c.Blogs, cb)
+	}
+	return c
+}
+
+// deep copy of the posts, snapshots must not share state with a running
+// model
+func clonePosts(posts []*Comment) []*Comment {
+	c := make([]*Comment, len(posts))
+	for i, p := range posts {
+		c[i] = p.clone(nil)
+	}
+	return c
+}
+
+// deep copy of a comment and its thread, Parent links are rebuilt
+func (c *Comment) clone(parent *Comment) *Comment {
+	n := &Comment{Message: append(Feature(nil), c.Message...),
+		Author: c.Author, Step: c.Step, Parent: parent}
+	for _, r := range c.Responses {
+		n.Responses = append(n.Responses, r.clone(n))
+	}
+	return n
+}
+
+func (s *Snapshot) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(s)
+}
+
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	s := &Snapshot{}
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// writes a snapshot of the model to a file
+func (e *EchoChamberModel) SaveCheckpoint(path string) error {
+	s, err := e.Snapshot()
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := s.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// restores the model from a checkpoint file
+func (e *EchoChamberModel) LoadCheckpoint(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s, err := ReadSnapshot(f)
+	if err != nil {
+		return err
+	}
+	return e.Restore(s)
+}
+
+type byBlogID []BlogState
+
+func (a byBlogID) Len() int           { return len(a) }
+func (a byBlogID) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byBlogID) Less(i, j int) bool { return a[i].ID < a[j].ID }
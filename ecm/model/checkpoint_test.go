@@ -0,0 +1,196 @@
+package model
+
+import "bytes"
+import "goabm"
+import "math/rand"
+import "testing"
+
+// a landscape of agents at fixed positions, enough for the model's own
+// bookkeeping
+type testLandscape struct {
+	agents []goabm.Agenter
+}
+
+func (l *testLandscape) Init(goabm.Modeler)                          {}
+func (l *testLandscape) GetAgents() *[]goabm.Agenter                 { return &l.agents }
+func (l *testLandscape) GetAgentById(id goabm.AgentID) goabm.Agenter { return l.agents[id] }
+func (l *testLandscape) Dump() goabm.NetworkDump                     { return goabm.NetworkDump{} }
+func (l *testLandscape) RandomAgent() goabm.Agenter                  { return nil }
+
+// a model of n agents in a row, set up the way SimRun does
+func testModel(n int, seed int64, pStartBlogging float64, dists map[string]*Distribution) *EchoChamberModel {
+	constant := func(v float64) PF { return func(*rand.Rand) float64 { return v } }
+	e := &EchoChamberModel{NTraits: 3, NFeatures: 5, PStartBlogging: pStartBlogging,
+		RSubscribedBlogs: IntRange{1, 3}, RSimilarityConfortLevel: FloatRange{0.2, 1},
+		PFOnline: constant(0.8), PFConsumptive: constant(0.5), PFExpressive: constant(0.3),
+		PFU: Beta(2, 2), Sight: 1.5, Seed: seed, Distributions: dists,
+		Recommender: CollaborativeFilter{}, PRecommend: 0.2}
+	e.Ruleset = goabm.Ruleset{}
+	l := &testLandscape{}
+	e.Init(l)
+	for i := 0; i < n; i++ {
+		a := e.CreateAgent(&goabm.FLWMAgent{Seqnr: goabm.AgentID(i), X: float64(i), Y: 0})
+		l.agents = append(l.agents, a)
+	}
+	return e
+}
+
+// steps the online part of the model, goabm's movement and neighbors are
+// left out
+func step(e *EchoChamberModel) {
+	for _, b := range *e.Landscape.GetAgents() {
+		a := b.(*EchoChamberAgent)
+		if e.RollDice(a.POnline) {
+			a.VirtualInteraction()
+		} else if i := int(a.ID()) + 1; i < len(*e.Landscape.GetAgents()) {
+			a.PhysicalInteraction((*e.Landscape.GetAgents())[i].(*EchoChamberAgent))
+		}
+	}
+	e.LandscapeAction()
+}
+
+func encode(t *testing.T, e *EchoChamberModel) []byte {
+	s, err := e.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := s.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps int
+		dists map[string]*Distribution
+	}{
+		{"new model", 0, nil},
+		{"one step", 1, nil},
+		{"many steps", 30, nil},
+		{"distributions", 30, map[string]*Distribution{
+			ParamPVeloc:  {Kind: DistBeta, Alpha: 2, Beta: 5},
+			ParamPOnline: {Kind: DistTruncNormal, Mu: 0.7, Sigma: 0.2, Min: 0, Max: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := testModel(20, 7, 0.3, tt.dists)
+			for i := 0; i < tt.steps; i++ {
+				step(e)
+			}
+			first := encode(t, e)
+
+			s, err := ReadSnapshot(bytes.NewReader(first))
+			if err != nil {
+				t.Fatal(err)
+			}
+			restored := testModel(20, 99, 0.3, tt.dists)
+			if err := restored.Restore(s); err != nil {
+				t.Fatal(err)
+			}
+			if second := encode(t, restored); !bytes.Equal(first, second) {
+				t.Fatalf("snapshots differ:\n%s\n%s", first, second)
+			}
+
+			// both go on the same way
+			for i := 0; i < 5; i++ {
+				step(e)
+				step(restored)
+			}
+			if !bytes.Equal(encode(t, e), encode(t, restored)) {
+				t.Errorf("the restored model went another way")
+			}
+		})
+	}
+}
+
+func TestRestoreRedrawsParameters(t *testing.T) {
+	e := testModel(10, 7, 0.3, nil)
+	for i := 0; i < 10; i++ {
+		step(e)
+	}
+	s, err := e.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a counterfactual run with another probability to start a blog
+	other := testModel(10, 7, 0.9, nil)
+	if err := other.Restore(s); err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range *other.Landscape.GetAgents() {
+		a := b.(*EchoChamberAgent)
+		if a.PStartBlogging != 0.9 {
+			t.Errorf("agent %d: PStartBlogging %g, want the model's 0.9", i, a.PStartBlogging)
+		}
+		if !a.Features.Equal(s.Agents[i].Features) {
+			t.Errorf("agent %d: features %v, want the checkpoint's %v", i, a.Features, s.Agents[i].Features)
+		}
+	}
+	if other.Source.State != s.RandState {
+		t.Errorf("random state %d, want %d", other.Source.State, s.RandState)
+	}
+}
+
+func TestRestoreRejects(t *testing.T) {
+	e := testModel(10, 7, 0.9, nil)
+	for i := 0; i < 10; i++ {
+		step(e)
+	}
+	// an agent which follows a blog
+	follower := -1
+	for _, b := range *e.Landscape.GetAgents() {
+		if a := b.(*EchoChamberAgent); len(a.MySubscriptions.FollowedBlogs) > 0 && follower < 0 {
+			follower = int(a.ID())
+		}
+	}
+	if follower < 0 || len(e.Blogger) == 0 {
+		t.Fatal("no blogs to test with")
+	}
+
+	tests := []struct {
+		name   string
+		change func(s *Snapshot)
+		agents int
+	}{
+		{"format", func(s *Snapshot) { s.Format = "other" }, 10},
+		{"version", func(s *Snapshot) { s.Version = SnapshotVersion + 1 }, 10},
+		{"fewer agents", func(s *Snapshot) {}, 9},
+		{"unknown agent", func(s *Snapshot) { s.Agents[9].ID = 42 }, 10},
+		{"agent twice", func(s *Snapshot) { s.Agents[9].ID = 0 }, 10},
+		{"unknown followed blog", func(s *Snapshot) { s.Agents[follower].Followed[0] = 42 }, 10},
+		{"unknown recommended blog", func(s *Snapshot) { s.Agents[9].Recommended = []int{42} }, 10},
+		{"unknown read blog", func(s *Snapshot) { s.Agents[9].ReadPosts[42] = []int{0} }, 10},
+		{"unknown own blog", func(s *Snapshot) { s.Agents[9].Blog = 42 }, 10},
+		{"another's blog", func(s *Snapshot) {
+			b := s.Blogs[0]
+			s.Agents[(int(b.Owner)+1)%10].Blog = b.ID
+		}, 10},
+		{"unknown owner", func(s *Snapshot) { s.Blogs[0].Owner = 42 }, 10},
+		{"blog twice", func(s *Snapshot) { s.Blogs = append(s.Blogs, s.Blogs[0]) }, 10},
+		{"features", func(s *Snapshot) { s.Agents[9].Features = Feature{1} }, 10},
+		{"message", func(s *Snapshot) { s.Blogs[0].Posts[0].Message = Feature{1} }, 10},
+		{"agent", func(s *Snapshot) { s.Agents[9].Agent = []byte("{") }, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := e.Snapshot()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.change(s)
+			m := testModel(tt.agents, 3, 0.3, nil)
+			step(m)
+			before := encode(t, m)
+			if err := m.Restore(s); err == nil {
+				t.Fatalf("restored")
+			}
+			if !bytes.Equal(before, encode(t, m)) {
+				t.Errorf("a failed restore changed the model")
+			}
+		})
+	}
+}
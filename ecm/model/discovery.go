@@ -0,0 +1,121 @@
+package model
+
+import "math/rand"
+
+// DiscoveryPolicy decides which blog an agent finds when searching for a new
+// one. It models the search/exposure algorithm of the platform, from a perfect
+// filter bubble (MostSimilar) to pure serendipity (UniformRandom).
+type DiscoveryPolicy interface {
+	Discover(a *EchoChamberAgent) *Blog
+}
+
+// always the most similar blog, the maximal filter bubble
+type MostSimilar struct{}
+
+func (p MostSimilar) Discover(a *EchoChamberAgent) *Blog {
+	return a.Model.GoogleBlog(a.Features, a.MySubscriptions.Follows)
+}
+
+// a random blog, chances are proportional to the similarity
+type SimilarityWeighted struct{}
+
+func (p SimilarityWeighted) Discover(a *EchoChamberAgent) *Blog {
+	candidates := a.Model.candidateBlogs(a)
+	return pickWeighted(a.Model.Rng(), candidates, func(r SearchResult) float64 {
+		return r.Score
+	})
+}
+
+// a random blog, chances are proportional to the number of followers. Every
+// blog gets one extra follower, otherwise a new blog could never be found.
+type PopularityWeighted struct{}
+
+func (p PopularityWeighted) Discover(a *EchoChamberAgent) *Blog {
+	candidates := a.Model.candidateBlogs(a)
+	followers := a.Model.FollowerCounts()
+	return pickWeighted(a.Model.Rng(), candidates, func(r SearchResult) float64 {
+		return float64(followers[r.Blog.ID] + 1)
+	})
+}
+
+// any blog with the same chance
+type UniformRandom struct{}
+
+func (p UniformRandom) Discover(a *EchoChamberAgent) *Blog {
+	candidates := a.Model.candidateBlogs(a)
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates[a.Model.Rng().Intn(len(candidates))].Blog
+}
+
+// sends a share of the searches to dissimilar blogs (similarity below
+// Threshold), the rest is handled by Base
+type Serendipity struct {
+	Share     float64
+	Threshold float64
+	Base      DiscoveryPolicy
+}
+
+func (p Serendipity) Discover(a *EchoChamberAgent) *Blog {
+	if !a.Model.RollDice(p.Share) {
+		base := p.Base
+		if base == nil {
+			base = MostSimilar{}
+		}
+		return base.Discover(a)
+	}
+
+	candidates := a.Model.candidateBlogs(a)
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	// candidates are sorted, best first
+	first := len(candidates)
+	for first > 0 && candidates[first-1].Score < p.Threshold {
+		first--
+	}
+	dissimilar := candidates[first:]
+	if len(dissimilar) == 0 {
+		// nothing is dissimilar enough, take the least similar one
+		return candidates[len(candidates)-1].Blog
+	}
+	return dissimilar[a.Model.Rng().Intn(len(dissimilar))].Blog
+}
+
+// all blogs the agent doesn't follow yet, ranked by similarity
+func (e *EchoChamberModel) candidateBlogs(a *EchoChamberAgent) []SearchResult {
+	return e.SearchBlogs(a.Features, e.Search.Len(), a.MySubscriptions.Follows)
+}
+
+// number of followers per blog id
+func (e *EchoChamberModel) FollowerCounts() map[int]int {
+	followers := make(map[int]int, len(e.Blogger))
+	for _, blog := range e.Blogger {
+		followers[blog.ID] = len(blog.Followers)
+	}
+	return followers
+}
+
+func pickWeighted(r *rand.Rand, candidates []SearchResult, weight func(SearchResult) float64) *Blog {
+	if len(candidates) == 0 {
+		return nil
+	}
+	total := 0.0
+	for _, c := range candidates {
+		total += weight(c)
+	}
+	if total <= 0 {
+		return candidates[r.Intn(len(candidates))].Blog
+	}
+
+	dice := r.Float64() * total
+	for _, c := range candidates {
+		dice -= weight(c)
+		if dice < 0 {
+			return c.Blog
+		}
+	}
+	return candidates[len(candidates)-1].Blog
+}
@@ -0,0 +1,95 @@
+package model
+
+import "math"
+import "testing"
+
+// a blog of agent i+1 for every message, each with the message as its post
+func testBlogs(e *EchoChamberModel, messages ...Feature) []*Blog {
+	var blogs []*Blog
+	for i, m := range messages {
+		a := (*e.Landscape.GetAgents())[i+1].(*EchoChamberAgent)
+		a.MyBlog = e.CreateBlog(a)
+		a.MyBlog.Publish(e.Step, m)
+		blogs = append(blogs, a.MyBlog)
+	}
+	return blogs
+}
+
+// agent 0 with the features 00000 and four blogs of the similarities 1, 0.6,
+// 0.2 and 0 to it
+func testDiscovery() (*EchoChamberAgent, []*Blog) {
+	e := testModel(10, 7, 0.3, nil)
+	a := (*e.Landscape.GetAgents())[0].(*EchoChamberAgent)
+	a.Features = Feature{0, 0, 0, 0, 0}
+	blogs := testBlogs(e, Feature{0, 0, 0, 0, 0}, Feature{0, 0, 0, 1, 1},
+		Feature{0, 1, 1, 1, 1}, Feature{1, 1, 1, 1, 1})
+	return a, blogs
+}
+
+// the share of n discoveries of every blog, by blog id
+func shares(a *EchoChamberAgent, p DiscoveryPolicy, n int) map[int]float64 {
+	s := make(map[int]float64)
+	for i := 0; i < n; i++ {
+		if b := p.Discover(a); b != nil {
+			s[b.ID] += 1 / float64(n)
+		}
+	}
+	return s
+}
+
+func TestDiscover(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy DiscoveryPolicy
+		// the expected share of each blog
+		want []float64
+		// agent 0 follows blog 0 already
+		following bool
+		// followers of every blog
+		followers []int
+	}{
+		{"most similar", MostSimilar{}, []float64{1, 0, 0, 0}, false, nil},
+		{"most similar not followed", MostSimilar{}, []float64{0, 1, 0, 0}, true, nil},
+		{"uniform", UniformRandom{}, []float64{0.25, 0.25, 0.25, 0.25}, false, nil},
+		{"uniform not followed", UniformRandom{}, []float64{0, 1. / 3, 1. / 3, 1. / 3}, true, nil},
+		// 1 : 0.6 : 0.2 : 0
+		{"similarity weighted", SimilarityWeighted{}, []float64{1 / 1.8, 0.6 / 1.8, 0.2 / 1.8, 0}, false, nil},
+		// one extra follower each: 1 : 6 : 1 : 2
+		{"popularity", PopularityWeighted{}, []float64{0.1, 0.6, 0.1, 0.2}, false, []int{0, 5, 0, 1}},
+		{"serendipity", Serendipity{Share: 1, Threshold: 0.5}, []float64{0, 0, 0.5, 0.5}, false, nil},
+		{"serendipity base", Serendipity{Share: 0, Threshold: 0.5}, []float64{1, 0, 0, 0}, false, nil},
+		// every blog is above the threshold, the least similar one it is
+		{"serendipity least similar", Serendipity{Share: 1, Threshold: -1}, []float64{0, 0, 0, 1}, false, nil},
+		{"half serendipity", Serendipity{Share: 0.5, Threshold: 0.5}, []float64{0.5, 0, 0.25, 0.25}, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, blogs := testDiscovery()
+			for i, n := range tt.followers {
+				for j := 0; j < n; j++ {
+					blogs[i].addFollower(9)
+				}
+			}
+			if tt.following {
+				a.MySubscriptions.Subscribe(blogs[0])
+			}
+			got := shares(a, tt.policy, 4000)
+			for i, b := range blogs {
+				if math.Abs(got[b.ID]-tt.want[i]) > 0.03 {
+					t.Errorf("blog %d found in %.3f of the searches, want %.3f", i, got[b.ID], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDiscoverNothing(t *testing.T) {
+	e := testModel(10, 7, 0.3, nil)
+	a := (*e.Landscape.GetAgents())[0].(*EchoChamberAgent)
+	for _, p := range []DiscoveryPolicy{MostSimilar{}, SimilarityWeighted{}, PopularityWeighted{},
+		UniformRandom{}, Serendipity{Share: 1}} {
+		if b := p.Discover(a); b != nil {
+			t.Errorf("%T found blog %d without blogs", p, b.ID)
+		}
+	}
+}
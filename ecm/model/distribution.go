@@ -0,0 +1,425 @@
+package model
+
+import "encoding/json"
+import "fmt"
+import "math"
+import "math/rand"
+import "sort"
+import "strconv"
+import "strings"
+
+// kinds of distributions
+const (
+	DistConstant    = "constant"
+	DistUniform     = "uniform"
+	DistBeta        = "beta"
+	DistTruncNormal = "truncnormal"
+	DistLogNormal   = "lognormal"
+	DistEmpirical   = "empirical"
+	DistMixture     = "mixture"
+)
+
+// Distribution is the spec of a random agent parameter. Only the fields of
+// its Kind are used:
+//
+//	constant     Value
+//	uniform      Min, Max
+//	beta         Alpha, Beta
+//	truncnormal  Mu, Sigma, bounded to Min, Max (unbounded if Min == Max)
+//	lognormal    Mu, Sigma of the underlying normal
+//	empirical    Values, each drawn with the same chance
+//	mixture      Components, chosen by Weights (equal if empty)
+type Distribution struct {
+	Kind string
+
+	Value      float64         `json:",omitempty"`
+	Min        float64         `json:",omitempty"`
+	Max        float64         `json:",omitempty"`
+	Alpha      float64         `json:",omitempty"`
+	Beta       float64         `json:",omitempty"`
+	Mu         float64         `json:",omitempty"`
+	Sigma      float64         `json:",omitempty"`
+	Values     []float64       `json:",omitempty"`
+	Components []*Distribution `json:",omitempty"`
+	Weights    []float64       `json:",omitempty"`
+}
+
+func Constant(v float64) *Distribution {
+	return &Distribution{Kind: DistConstant, Value: v}
+}
+
+func Uniform(min, max float64) *Distribution {
+	return &Distribution{Kind: DistUniform, Min: min, Max: max}
+}
+
+// checks that the parameters of the kind are usable
+func (d *Distribution) Validate() error {
+	if d == nil {
+		return fmt.Errorf("missing distribution")
+	}
+	switch d.Kind {
+	case DistConstant:
+	case DistUniform:
+		if d.Min > d.Max {
+			return fmt.Errorf("uniform: min %g > max %g", d.Min, d.Max)
+		}
+	case DistBeta:
+		if d.Alpha <= 0 || d.Beta <= 0 {
+			return fmt.Errorf("beta: α and β must be positive, got %g, %g", d.Alpha, d.Beta)
+		}
+	case DistTruncNormal:
+		if d.Sigma <= 0 {
+			return fmt.Errorf("truncnormal: σ must be positive, got %g", d.Sigma)
+		}
+		if d.Min > d.Max {
+			return fmt.Errorf("truncnormal: min %g > max %g", d.Min, d.Max)
+		}
+	case DistLogNormal:
+		if d.Sigma < 0 {
+			return fmt.Errorf("lognormal: σ must not be negative, got %g", d.Sigma)
+		}
+	case DistEmpirical:
+		if len(d.Values) == 0 {
+			return fmt.Errorf("empirical: no values")
+		}
+	case DistMixture:
+		if len(d.Components) == 0 {
+			return fmt.Errorf("mixture: no components")
+		}
+		if len(d.Weights) > 0 {
+			if len(d.Weights) != len(d.Components) {
+				return fmt.Errorf("mixture: %d weights for %d components", len(d.Weights), len(d.Components))
+			}
+			total := 0.0
+			for _, w := range d.Weights {
+				if w < 0 {
+					return fmt.Errorf("mixture: negative weight %g", w)
+				}
+				total += w
+			}
+			if total <= 0 {
+				return fmt.Errorf("mixture: weights sum to zero")
+			}
+		}
+		for _, c := range d.Components {
+			if err := c.Validate(); err != nil {
+				return fmt.Errorf("mixture: %v", err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown distribution: %q", d.Kind)
+	}
+	return nil
+}
+
+// draws a value, the distribution must be valid
+func (d *Distribution) Sample(r *rand.Rand) float64 {
+	switch d.Kind {
+	case DistUniform:
+		return d.Min + r.Float64()*(d.Max-d.Min)
+	case DistBeta:
+		return Beta(d.Alpha, d.Beta)(r)
+	case DistTruncNormal:
+		if d.Min == d.Max {
+			return d.Mu + d.Sigma*r.NormFloat64()
+		}
+		// rejection, falls back to clamping if the bounds are far out in
+		// the tails
+		for i := 0; i < 1000; i++ {
+			x := d.Mu + d.Sigma*r.NormFloat64()
+			if x >= d.Min && x <= d.Max {
+				return x
+			}
+		}
+		return math.Max(d.Min, math.Min(d.Max, d.Mu))
+	case DistLogNormal:
+		return math.Exp(d.Mu + d.Sigma*r.NormFloat64())
+	case DistEmpirical:
+		return d.Values[r.Intn(len(d.Values))]
+	case DistMixture:
+		return d.Components[d.component(r)].Sample(r)
+	}
+	return d.Value
+}
+
+// the smallest and the largest value a draw can take, ±Inf if unbounded.
+// The distribution must be valid.
+func (d *Distribution) Support() (min, max float64) {
+	switch d.Kind {
+	case DistUniform:
+		return d.Min, d.Max
+	case DistBeta:
+		return 0, 1
+	case DistTruncNormal:
+		if d.Min == d.Max {
+			return math.Inf(-1), math.Inf(1)
+		}
+		return d.Min, d.Max
+	case DistLogNormal:
+		if d.Sigma == 0 {
+			return math.Exp(d.Mu), math.Exp(d.Mu)
+		}
+		return 0, math.Inf(1)
+	case DistEmpirical:
+		min, max = d.Values[0], d.Values[0]
+		for _, v := range d.Values {
+			min, max = math.Min(min, v), math.Max(max, v)
+		}
+		return min, max
+	case DistMixture:
+		min, max = math.Inf(1), math.Inf(-1)
+		for i, c := range d.Components {
+			if len(d.Weights) > 0 && d.Weights[i] == 0 {
+				continue
+			}
+			lo, hi := c.Support()
+			min, max = math.Min(min, lo), math.Max(max, hi)
+		}
+		return min, max
+	}
+	return d.Value, d.Value
+}
+
+func (d *Distribution) component(r *rand.Rand) int {
+	if len(d.Weights) == 0 {
+		return r.Intn(len(d.Components))
+	}
+	total := 0.0
+	for _, w := range d.Weights {
+		total += w
+	}
+	dice := r.Float64() * total
+	for i, w := range d.Weights {
+		dice -= w
+		if dice < 0 {
+			return i
+		}
+	}
+	return len(d.Components) - 1
+}
+
+// the distribution as a probability function
+func (d *Distribution) PF() PF {
+	return d.Sample
+}
+
+// the short form which ParseDistribution reads
+func (d *Distribution) String() string {
+	var args []float64
+	switch d.Kind {
+	case DistConstant:
+		args = []float64{d.Value}
+	case DistUniform:
+		args = []float64{d.Min, d.Max}
+	case DistBeta:
+		args = []float64{d.Alpha, d.Beta}
+	case DistTruncNormal:
+		args = []float64{d.Mu, d.Sigma, d.Min, d.Max}
+	case DistLogNormal:
+		args = []float64{d.Mu, d.Sigma}
+	case DistEmpirical:
+		args = d.Values
+	case DistMixture:
+		parts := make([]string, len(d.Components))
+		for i, c := range d.Components {
+			parts[i] = c.String()
+			if len(d.Weights) > 0 {
+				parts[i] = strconv.FormatFloat(d.Weights[i], 'g', -1, 64) + ":" + parts[i]
+			}
+		}
+		return d.Kind + "(" + strings.Join(parts, ",") + ")"
+	}
+	parts := make([]string, len(args))
+	for i, a := range args {
+		parts[i] = strconv.FormatFloat(a, 'g', -1, 64)
+	}
+	return d.Kind + "(" + strings.Join(parts, ",") + ")"
+}
+
+// parses the short form of a distribution, e.g.
+//
+//	0.3                  constant
+//	uniform(0,1)
+//	beta(2,5)
+//	truncnormal(0.5,0.1,0,1)   μ, σ, min, max
+//	truncnormal(0.5,0.1)       unbounded
+//	lognormal(0,0.5)
+//	empirical(0.1,0.2,0.7)
+//	mixture(0.3:beta(2,5),0.7:uniform(0,1))
+func ParseDistribution(s string) (*Distribution, error) {
+	s = strings.TrimSpace(s)
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return Constant(v), nil
+	}
+
+	open := strings.Index(s, "(")
+	if open < 0 || !strings.HasSuffix(s, ")") {
+		return nil, fmt.Errorf("invalid distribution: %q", s)
+	}
+	d := &Distribution{Kind: strings.TrimSpace(s[:open])}
+	args := splitArgs(s[open+1 : len(s)-1])
+
+	if d.Kind == DistMixture {
+		for _, a := range args {
+			w := ""
+			if i := strings.Index(a, ":"); i >= 0 && i < strings.Index(a+"(", "(") {
+				w, a = a[:i], a[i+1:]
+			}
+			c, err := ParseDistribution(a)
+			if err != nil {
+				return nil, err
+			}
+			d.Components = append(d.Components, c)
+			if w != "" {
+				f, err := strconv.ParseFloat(strings.TrimSpace(w), 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid mixture weight: %q", w)
+				}
+				d.Weights = append(d.Weights, f)
+			}
+		}
+		return d, d.Validate()
+	}
+
+	var nums []float64
+	for _, a := range args {
+		f, err := strconv.ParseFloat(strings.TrimSpace(a), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument of %s: %q", d.Kind, a)
+		}
+		nums = append(nums, f)
+	}
+
+	want := map[string]int{DistConstant: 1, DistUniform: 2, DistBeta: 2, DistLogNormal: 2}
+	if n, ok := want[d.Kind]; ok && len(nums) != n {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", d.Kind, n, len(nums))
+	}
+	switch d.Kind {
+	case DistConstant:
+		d.Value = nums[0]
+	case DistUniform:
+		d.Min, d.Max = nums[0], nums[1]
+	case DistBeta:
+		d.Alpha, d.Beta = nums[0], nums[1]
+	case DistLogNormal:
+		d.Mu, d.Sigma = nums[0], nums[1]
+	case DistTruncNormal:
+		if len(nums) != 2 && len(nums) != 4 {
+			return nil, fmt.Errorf("truncnormal takes 2 or 4 arguments, got %d", len(nums))
+		}
+		d.Mu, d.Sigma = nums[0], nums[1]
+		if len(nums) == 4 {
+			d.Min, d.Max = nums[2], nums[3]
+		}
+	case DistEmpirical:
+		d.Values = nums
+	}
+	return d, d.Validate()
+}
+
+// splits at the commas which are not nested in parentheses
+func splitArgs(s string) []string {
+	var args []string
+	depth := 0
+	start := 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if strings.TrimSpace(s[start:]) != "" || len(args) > 0 {
+		args = append(args, s[start:])
+	}
+	return args
+}
+
+// names of the agent parameters a distribution can be assigned to. The
+// bounds of the ranges are drawn independently and swapped if needed.
+const (
+	ParamPStartBlogging             = "PStartBlogging"
+	ParamPWriteBlogPost             = "PWriteBlogPost"
+	ParamPRespondBlogPost           = "PRespondBlogPost"
+	ParamPUnderstanding             = "PUnderstanding"
+	ParamPOnline                    = "POnline"
+	ParamPVeloc                     = "PVeloc"
+	ParamSteplength                 = "Steplength"
+	ParamRSubscribedBlogsMin        = "RSubscribedBlogs.Min"
+	ParamRSubscribedBlogsMax        = "RSubscribedBlogs.Max"
+	ParamRSimilarityConfortLevelMin = "RSimilarityConfortLevel.Min"
+	ParamRSimilarityConfortLevelMax = "RSimilarityConfortLevel.Max"
+)
+
+var AgentParams = []string{ParamPStartBlogging, ParamPWriteBlogPost, ParamPRespondBlogPost,
+	ParamPUnderstanding, ParamPOnline, ParamPVeloc, ParamSteplength,
+	ParamRSubscribedBlogsMin, ParamRSubscribedBlogsMax,
+	ParamRSimilarityConfortLevelMin, ParamRSimilarityConfortLevelMax}
+
+// the agent parameters which are probabilities, their distributions must
+// not draw outside of [0, 1]
+var probabilityParams = map[string]bool{ParamPStartBlogging: true, ParamPWriteBlogPost: true,
+	ParamPRespondBlogPost: true, ParamPUnderstanding: true, ParamPOnline: true, ParamPVeloc: true}
+
+// checks the distributions of the model and their parameter names
+func (e *EchoChamberModel) ValidateDistributions() error {
+	known := make(map[string]bool, len(AgentParams))
+	for _, p := range AgentParams {
+		known[p] = true
+	}
+	names := make([]string, 0, len(e.Distributions))
+	for name := range e.Distributions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !known[name] {
+			return fmt.Errorf("unknown agent parameter: %q", name)
+		}
+		d := e.Distributions[name]
+		if err := d.Validate(); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if min, max := d.Support(); probabilityParams[name] && (min < 0 || max > 1) {
+			return fmt.Errorf("%s: %s draws from [%g, %g], a probability needs [0, 1]", name, d, min, max)
+		}
+	}
+	return nil
+}
+
+// draws the parameter from its distribution, or returns def if it has none
+func (e *EchoChamberModel) draw(param string, def float64) float64 {
+	if d, ok := e.Distributions[param]; ok {
+		return d.Sample(e.Rng())
+	}
+	return def
+}
+
+// draws the parameter from its distribution, or from the PF if it has none
+func (e *EchoChamberModel) drawPF(param string, pf PF) float64 {
+	if d, ok := e.Distributions[param]; ok {
+		return d.Sample(e.Rng())
+	}
+	return pf(e.Rng())
+}
+
+// a distribution is either a spec object or its short form as a string
+func (d *Distribution) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		p, err := ParseDistribution(s)
+		if err != nil {
+			return err
+		}
+		*d = *p
+		return nil
+	}
+	type spec Distribution
+	return json.Unmarshal(data, (*spec)(d))
+}
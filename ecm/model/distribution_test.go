@@ -0,0 +1,149 @@
+package model
+
+import "math"
+import "reflect"
+import "testing"
+
+func TestParseDistribution(t *testing.T) {
+	tests := []struct {
+		in   string
+		want *Distribution
+		err  bool
+	}{
+		{in: "0.3", want: Constant(0.3)},
+		{in: " 2 ", want: Constant(2)},
+		{in: "constant(0.5)", want: Constant(0.5)},
+		{in: "uniform(0, 1)", want: Uniform(0, 1)},
+		{in: "beta(2,5)", want: &Distribution{Kind: DistBeta, Alpha: 2, Beta: 5}},
+		{in: "truncnormal(0.5,0.1,0,1)", want: &Distribution{Kind: DistTruncNormal, Mu: 0.5, Sigma: 0.1, Min: 0, Max: 1}},
+		{in: "truncnormal(0.5,0.1)", want: &Distribution{Kind: DistTruncNormal, Mu: 0.5, Sigma: 0.1}},
+		{in: "lognormal(0,0.5)", want: &Distribution{Kind: DistLogNormal, Mu: 0, Sigma: 0.5}},
+		{in: "empirical(0.1,0.2,0.7)", want: &Distribution{Kind: DistEmpirical, Values: []float64{0.1, 0.2, 0.7}}},
+		{in: "mixture(0.3:beta(2,5),0.7:uniform(0,1))", want: &Distribution{Kind: DistMixture,
+			Components: []*Distribution{{Kind: DistBeta, Alpha: 2, Beta: 5}, Uniform(0, 1)},
+			Weights:    []float64{0.3, 0.7}}},
+		{in: "mixture(beta(2,5), 0.2)", want: &Distribution{Kind: DistMixture,
+			Components: []*Distribution{{Kind: DistBeta, Alpha: 2, Beta: 5}, Constant(0.2)}}},
+
+		{in: "", err: true},
+		{in: "beta", err: true},
+		{in: "beta(2,5", err: true},
+		{in: "beta(2)", err: true},
+		{in: "beta(0,5)", err: true},
+		{in: "uniform(1,0)", err: true},
+		{in: "uniform(a,1)", err: true},
+		{in: "truncnormal(0.5,0.1,0)", err: true},
+		{in: "truncnormal(0.5,0,0,1)", err: true},
+		{in: "lognormal(0,-1)", err: true},
+		{in: "empirical()", err: true},
+		{in: "mixture()", err: true},
+		{in: "mixture(0.3:beta(2,5),uniform(0,1))", err: true},
+		{in: "mixture(x:beta(2,5))", err: true},
+		{in: "gamma(1,2)", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseDistribution(tt.in)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("no error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			// the short form reads back the same
+			back, err := ParseDistribution(got.String())
+			if err != nil || !reflect.DeepEqual(back, got) {
+				t.Errorf("%q reads back as %+v (%v)", got.String(), back, err)
+			}
+		})
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"1", []string{"1"}},
+		{"1,2", []string{"1", "2"}},
+		{"1, 2 ,3", []string{"1", " 2 ", "3"}},
+		{"beta(2,5),uniform(0,1)", []string{"beta(2,5)", "uniform(0,1)"}},
+		{"0.3:mixture(beta(2,5),1),2", []string{"0.3:mixture(beta(2,5),1)", "2"}},
+		{"1,", []string{"1", ""}},
+		{",", []string{"", ""}},
+	}
+	for _, tt := range tests {
+		if got := splitArgs(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitArgs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidateDistributions(t *testing.T) {
+	tests := []struct {
+		name string
+		dist map[string]string
+		err  bool
+	}{
+		{"beta probability", map[string]string{ParamPOnline: "beta(2,5)"}, false},
+		{"bounded probability", map[string]string{ParamPVeloc: "truncnormal(0.5,0.1,0,1)"}, false},
+		{"probability mixture", map[string]string{ParamPUnderstanding: "mixture(uniform(0,0.5),0.9)"}, false},
+		{"lognormal steplength", map[string]string{ParamSteplength: "lognormal(0,0.5)"}, false},
+		{"unknown parameter", map[string]string{"PFly": "0.5"}, true},
+		{"lognormal probability", map[string]string{ParamPStartBlogging: "lognormal(-2,0.5)"}, true},
+		{"unbounded probability", map[string]string{ParamPWriteBlogPost: "truncnormal(0.5,0.1)"}, true},
+		{"uniform beyond 1", map[string]string{ParamPRespondBlogPost: "uniform(0.5,1.5)"}, true},
+		{"negative empirical", map[string]string{ParamPOnline: "empirical(-0.1,0.5)"}, true},
+		{"mixture beyond 1", map[string]string{ParamPVeloc: "mixture(beta(2,5),2)"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &EchoChamberModel{Distributions: make(map[string]*Distribution)}
+			for name, s := range tt.dist {
+				d, err := ParseDistribution(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				e.Distributions[name] = d
+			}
+			if err := e.ValidateDistributions(); (err != nil) != tt.err {
+				t.Errorf("error %v, want one: %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSupport(t *testing.T) {
+	inf := math.Inf(1)
+	tests := []struct {
+		in       string
+		min, max float64
+	}{
+		{"0.3", 0.3, 0.3},
+		{"uniform(0.2,0.4)", 0.2, 0.4},
+		{"beta(2,5)", 0, 1},
+		{"truncnormal(0.5,0.1,0,1)", 0, 1},
+		{"truncnormal(0.5,0.1)", -inf, inf},
+		{"lognormal(0,0.5)", 0, inf},
+		{"lognormal(0,0)", 1, 1},
+		{"empirical(0.7,0.1,0.2)", 0.1, 0.7},
+		{"mixture(uniform(0.2,0.4),0.9)", 0.2, 0.9},
+		{"mixture(0:uniform(0,2),1:0.5)", 0.5, 0.5},
+	}
+	for _, tt := range tests {
+		d, err := ParseDistribution(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if min, max := d.Support(); min != tt.min || max != tt.max {
+			t.Errorf("%s: support [%g, %g], want [%g, %g]", tt.in, min, max, tt.min, tt.max)
+		}
+	}
+}
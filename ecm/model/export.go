@@ -0,0 +1,415 @@
+package model
+
+import "bufio"
+import "encoding/xml"
+import "fmt"
+import "io"
+import "path/filepath"
+import "sort"
+import "strings"
+
+// the blogosphere as a network: agents and blogs are nodes, subscriptions,
+// comments and blog ownership are edges
+type Graph struct {
+	Step  int
+	Nodes []GraphNode
+	Edges []GraphEdge
+}
+
+type GraphNode struct {
+	ID    string
+	Label string
+	// int, float64, string or bool values
+	Attrs map[string]interface{}
+}
+
+type GraphEdge struct {
+	Source, Target string
+	// "subscription", "comment" or "owner"
+	Kind   string
+	Weight int
+}
+
+func (e GraphEdge) id() string {
+	return e.Kind + ":" + e.Source + "->" + e.Target
+}
+
+func agentNode(id interface{}) string { return fmt.Sprintf("a%v", id) }
+func blogNode(id int) string          { return fmt.Sprintf("b%d", id) }
+
+// the network of the current step
+func (e *EchoChamberModel) Graph() *Graph {
+	g := &Graph{Step: e.Step}
+
+	for _, b := range *e.Landscape.GetAgents() {
+		a := b.(*EchoChamberAgent)
+		n := GraphNode{ID: agentNode(a.ID()), Label: fmt.Sprintf("agent %d", a.ID()),
+			Attrs: map[string]interface{}{
+				"kind":     "agent",
+				"features": a.Culture(),
+				"p_online": a.POnline,
+				"blogger":  a.MyBlog != nil,
+			}}
+		if x, y, ok := a.Position(); ok {
+			n.Attrs["x"] = x
+			n.Attrs["y"] = y
+		}
+		g.Nodes = append(g.Nodes, n)
+
+		for _, blog := range a.MySubscriptions.Blogs() {
+			g.Edges = append(g.Edges, GraphEdge{Source: n.ID, Target: blogNode(blog.ID),
+				Kind: "subscription", Weight: 1})
+		}
+	}
+
+	blogs := make([]*Blog, 0, len(e.Blogger))
+	for _, blog := range e.Blogger {
+		blogs = append(blogs, blog)
+	}
+	sort.Sort(blogsByID(blogs))
+
+	for _, blog := range blogs {
+		n := GraphNode{ID: blogNode(blog.ID), Label: fmt.Sprintf("blog %d", blog.ID),
+			Attrs: map[string]interface{}{
+				"kind":      "blog",
+				"posts":     len(blog.Posts),
+				"followers": len(blog.Followers),
+			}}
+		if len(blog.Posts) > 0 {
+			n.Attrs["features"] = fmt.Sprintf("%v", blog.Posts[len(blog.Posts)-1].Message)
+		}
+		g.Nodes = append(g.Nodes, n)
+		g.Edges = append(g.Edges, GraphEdge{Source: agentNode(blog.Owner), Target: n.ID,
+			Kind: "owner", Weight: 1})
+
+		// commenter -> blog, weighted by the number of comments
+		comments := make(map[string]int)
+		var commenters []string
+		for _, p := range blog.Posts {
+			for _, c := range p.Thread() {
+				id := agentNode(c.Author)
+				if comments[id] == 0 {
+					commenters = append(commenters, id)
+				}
+				comments[id]++
+			}
+		}
+		for _, id := range commenters {
+			g.Edges = append(g.Edges, GraphEdge{Source: id, Target: n.ID,
+				Kind: "comment", Weight: comments[id]})
+		}
+	}
+	return g
+}
+
+type blogsByID []*Blog
+
+func (a blogsByID) Len() int           { return len(a) }
+func (a blogsByID) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a blogsByID) Less(i, j int) bool { return a[i].ID < a[j].ID }
+
+// attribute names and their types over all nodes of the graphs
+func nodeAttributes(graphs ...*Graph) ([]string, map[string]string) {
+	types := make(map[string]string)
+	for _, g := range graphs {
+		for _, n := range g.Nodes {
+			for k, v := range n.Attrs {
+				types[k] = attrType(v)
+			}
+		}
+	}
+	names := make([]string, 0, len(types))
+	for k := range types {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names, types
+}
+
+// type names as used by GraphML, GEXF uses the same ones
+func attrType(v interface{}) string {
+	switch v.(type) {
+	case int:
+		return "int"
+	case float64:
+		return "double"
+	case bool:
+		return "boolean"
+	}
+	return "string"
+}
+
+func attrValue(v interface{}) string {
+	switch x := v.(type) {
+	case float64:
+		return fmt.Sprintf("%g", x)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	names, types := nodeAttributes(g)
+
+	fmt.Fprintf(bw, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(bw, "<graphml xmlns=\"http://graphml.graphdrawing.org/xmlns\">\n")
+	for _, k := range names {
+		fmt.Fprintf(bw, "  <key id=\"%s\" for=\"node\" attr.name=\"%s\" attr.type=\"%s\"/>\n", k, k, types[k])
+	}
+	fmt.Fprintf(bw, "  <key id=\"label\" for=\"node\" attr.name=\"label\" attr.type=\"string\"/>\n")
+	fmt.Fprintf(bw, "  <key id=\"edge_kind\" for=\"edge\" attr.name=\"kind\" attr.type=\"string\"/>\n")
+	fmt.Fprintf(bw, "  <key id=\"weight\" for=\"edge\" attr.name=\"weight\" attr.type=\"int\"/>\n")
+	fmt.Fprintf(bw, "  <graph id=\"step%d\" edgedefault=\"directed\">\n", g.Step)
+
+	for _, n := range g.Nodes {
+		fmt.Fprintf(bw, "    <node id=\"%s\">\n", n.ID)
+		fmt.Fprintf(bw, "      <data key=\"label\">%s</data>\n", escape(n.Label))
+		for _, k := range names {
+			if v, ok := n.Attrs[k]; ok {
+				fmt.Fprintf(bw, "      <data key=\"%s\">%s</data>\n", k, escape(attrValue(v)))
+			}
+		}
+		fmt.Fprintf(bw, "    </node>\n")
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(bw, "    <edge source=\"%s\" target=\"%s\">\n", e.Source, e.Target)
+		fmt.Fprintf(bw, "      <data key=\"edge_kind\">%s</data>\n", e.Kind)
+		fmt.Fprintf(bw, "      <data key=\"weight\">%d</data>\n", e.Weight)
+		fmt.Fprintf(bw, "    </edge>\n")
+	}
+	fmt.Fprintf(bw, "  </graph>\n</graphml>\n")
+	return bw.Flush()
+}
+
+func (g *Graph) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	names, _ := nodeAttributes(g)
+
+	fmt.Fprintf(bw, "digraph step%d {\n", g.Step)
+	for _, n := range g.Nodes {
+		attrs := []string{fmt.Sprintf("label=%q", n.Label)}
+		if n.Attrs["kind"] == "blog" {
+			attrs = append(attrs, "shape=box")
+		}
+		for _, k := range names {
+			if v, ok := n.Attrs[k]; ok {
+				attrs = append(attrs, fmt.Sprintf("%s=%q", k, attrValue(v)))
+			}
+		}
+		fmt.Fprintf(bw, "  %s [%s];\n", n.ID, strings.Join(attrs, ", "))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(bw, "  %s -> %s [kind=%q, weight=%d];\n", e.Source, e.Target, e.Kind, e.Weight)
+	}
+	fmt.Fprintf(bw, "}\n")
+	return bw.Flush()
+}
+
+// a static GEXF file of the graph
+func (g *Graph) WriteGEXF(w io.Writer) error {
+	t := &Timeline{}
+	t.Add(g)
+	return t.write(w, false)
+}
+
+// Timeline collects graphs of several steps for a dynamic GEXF file, nodes,
+// edges and attribute values carry the spells (steps) they exist in
+type Timeline struct {
+	Slices []*Graph
+}
+
+func (t *Timeline) Add(g *Graph) {
+	t.Slices = append(t.Slices, g)
+}
+
+func (t *Timeline) WriteGEXF(w io.Writer) error {
+	return t.write(w, true)
+}
+
+// a value which holds over the steps [start, end], or [start, end) if open
+type spell struct {
+	start, end int
+	open       bool
+	value      string
+}
+
+// the bounds as GEXF attributes, whose spells are closed unless endopen
+func (sp spell) bounds() string {
+	if sp.open {
+		return fmt.Sprintf("start=\"%d\" endopen=\"%d\"", sp.start, sp.end)
+	}
+	return fmt.Sprintf("start=\"%d\" end=\"%d\"", sp.start, sp.end)
+}
+
+// merges consecutive slices with the same value into one spell. A value
+// which changes holds until the slice where it changed, that slice has the
+// new value only.
+func addSpell(spells []spell, step, prev int, value string) []spell {
+	if n := len(spells); n > 0 && spells[n-1].end == prev {
+		if spells[n-1].value == value {
+			spells[n-1].end = step
+			return spells
+		}
+		spells[n-1].end, spells[n-1].open = step, true
+	}
+	return append(spells, spell{start: step, end: step, value: value})
+}
+
+func (t *Timeline) write(w io.Writer, dynamic bool) error {
+	bw := bufio.NewWriter(w)
+	names, types := nodeAttributes(t.Slices...)
+
+	type nodeHistory struct {
+		label    string
+		presence []spell
+		attrs    map[string][]spell
+	}
+	type edgeHistory struct {
+		edge     GraphEdge
+		presence []spell
+		weight   []spell
+	}
+	nodes := make(map[string]*nodeHistory)
+	var nodeOrder []string
+	edges := make(map[string]*edgeHistory)
+	var edgeOrder []string
+
+	prev := -1
+	for _, g := range t.Slices {
+		for _, n := range g.Nodes {
+			h, ok := nodes[n.ID]
+			if !ok {
+				h = &nodeHistory{label: n.Label, attrs: make(map[string][]spell)}
+				nodes[n.ID] = h
+				nodeOrder = append(nodeOrder, n.ID)
+			}
+			h.presence = addSpell(h.presence, g.Step, prev, "")
+			for k, v := range n.Attrs {
+				h.attrs[k] = addSpell(h.attrs[k], g.Step, prev, attrValue(v))
+			}
+		}
+		for _, e := range g.Edges {
+			h, ok := edges[e.id()]
+			if !ok {
+				h = &edgeHistory{edge: e}
+				edges[e.id()] = h
+				edgeOrder = append(edgeOrder, e.id())
+			}
+			h.presence = addSpell(h.presence, g.Step, prev, "")
+			h.weight = addSpell(h.weight, g.Step, prev, fmt.Sprintf("%d", e.Weight))
+		}
+		prev = g.Step
+	}
+
+	mode := "static"
+	if dynamic {
+		mode = "dynamic"
+	}
+	fmt.Fprintf(bw, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(bw, "<gexf xmlns=\"http://www.gexf.net/1.2draft\" version=\"1.2\">\n")
+	if dynamic {
+		fmt.Fprintf(bw, "  <graph mode=\"%s\" defaultedgetype=\"directed\" timeformat=\"double\">\n", mode)
+		fmt.Fprintf(bw, "    <attributes class=\"node\" mode=\"dynamic\">\n")
+	} else {
+		fmt.Fprintf(bw, "  <graph mode=\"%s\" defaultedgetype=\"directed\">\n", mode)
+		fmt.Fprintf(bw, "    <attributes class=\"node\">\n")
+	}
+	for _, k := range names {
+		typ := types[k]
+		if typ == "int" {
+			typ = "integer"
+		}
+		fmt.Fprintf(bw, "      <attribute id=\"%s\" title=\"%s\" type=\"%s\"/>\n", k, k, typ)
+	}
+	fmt.Fprintf(bw, "    </attributes>\n")
+	if dynamic {
+		fmt.Fprintf(bw, "    <attributes class=\"edge\" mode=\"dynamic\">\n")
+	} else {
+		fmt.Fprintf(bw, "    <attributes class=\"edge\">\n")
+	}
+	fmt.Fprintf(bw, "      <attribute id=\"kind\" title=\"kind\" type=\"string\"/>\n")
+	if dynamic {
+		fmt.Fprintf(bw, "      <attribute id=\"weight\" title=\"weight\" type=\"integer\"/>\n")
+	}
+	fmt.Fprintf(bw, "    </attributes>\n")
+
+	spells := func(indent string, s []spell) {
+		if !dynamic {
+			return
+		}
+		fmt.Fprintf(bw, "%s<spells>\n", indent)
+		for _, sp := range s {
+			fmt.Fprintf(bw, "%s  <spell %s/>\n", indent, sp.bounds())
+		}
+		fmt.Fprintf(bw, "%s</spells>\n", indent)
+	}
+	attvalue := func(indent, key string, sp spell) {
+		if dynamic {
+			fmt.Fprintf(bw, "%s<attvalue for=\"%s\" value=\"%s\" %s/>\n",
+				indent, key, escape(sp.value), sp.bounds())
+		} else {
+			fmt.Fprintf(bw, "%s<attvalue for=\"%s\" value=\"%s\"/>\n", indent, key, escape(sp.value))
+		}
+	}
+
+	fmt.Fprintf(bw, "    <nodes>\n")
+	for _, id := range nodeOrder {
+		h := nodes[id]
+		fmt.Fprintf(bw, "      <node id=\"%s\" label=\"%s\">\n", id, escape(h.label))
+		fmt.Fprintf(bw, "        <attvalues>\n")
+		for _, k := range names {
+			for _, sp := range h.attrs[k] {
+				attvalue("          ", k, sp)
+			}
+		}
+		fmt.Fprintf(bw, "        </attvalues>\n")
+		spells("        ", h.presence)
+		fmt.Fprintf(bw, "      </node>\n")
+	}
+	fmt.Fprintf(bw, "    </nodes>\n")
+
+	fmt.Fprintf(bw, "    <edges>\n")
+	for i, id := range edgeOrder {
+		h := edges[id]
+		e := h.edge
+		if dynamic {
+			fmt.Fprintf(bw, "      <edge id=\"%d\" source=\"%s\" target=\"%s\">\n", i, e.Source, e.Target)
+		} else {
+			fmt.Fprintf(bw, "      <edge id=\"%d\" source=\"%s\" target=\"%s\" weight=\"%d\">\n", i, e.Source, e.Target, e.Weight)
+		}
+		fmt.Fprintf(bw, "        <attvalues>\n")
+		attvalue("          ", "kind", spell{start: h.presence[0].start,
+			end: h.presence[len(h.presence)-1].end, value: e.Kind})
+		if dynamic {
+			for _, sp := range h.weight {
+				attvalue("          ", "weight", sp)
+			}
+		}
+		fmt.Fprintf(bw, "        </attvalues>\n")
+		spells("        ", h.presence)
+		fmt.Fprintf(bw, "      </edge>\n")
+	}
+	fmt.Fprintf(bw, "    </edges>\n")
+	fmt.Fprintf(bw, "  </graph>\n</gexf>\n")
+	return bw.Flush()
+}
+
+// writes the graph in the format given by the file extension of path:
+// .graphml, .gexf or .dot
+func (g *Graph) Write(w io.Writer, path string) error {
+	switch filepath.Ext(path) {
+	case ".graphml":
+		return g.WriteGraphML(w)
+	case ".gexf":
+		return g.WriteGEXF(w)
+	case ".dot", ".gv":
+		return g.WriteDOT(w)
+	}
+	return fmt.Errorf("unknown graph format: %s", path)
+}
@@ -0,0 +1,136 @@
+package model
+
+import "bytes"
+import "encoding/xml"
+import "strconv"
+import "strings"
+import "testing"
+
+// the parts of a GEXF file the tests look at
+type gexfFile struct {
+	Nodes []struct {
+		ID        string `xml:"id,attr"`
+		AttValues []struct {
+			For     string `xml:"for,attr"`
+			Value   string `xml:"value,attr"`
+			Start   string `xml:"start,attr"`
+			End     string `xml:"end,attr"`
+			EndOpen string `xml:"endopen,attr"`
+		} `xml:"attvalues>attvalue"`
+		Spells []struct {
+			Start string `xml:"start,attr"`
+			End   string `xml:"end,attr"`
+		} `xml:"spells>spell"`
+	} `xml:"graph>nodes>node"`
+	Edges []struct {
+		Source string `xml:"source,attr"`
+		Target string `xml:"target,attr"`
+	} `xml:"graph>edges>edge"`
+}
+
+func readGEXF(t *testing.T, b []byte) gexfFile {
+	var f gexfFile
+	if err := xml.Unmarshal(b, &f); err != nil {
+		t.Fatalf("%v\n%s", err, b)
+	}
+	return f
+}
+
+func atoi(t *testing.T, s string) int {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+func TestTimelineSpells(t *testing.T) {
+	node := func(step int, x int) *Graph {
+		return &Graph{Step: step, Nodes: []GraphNode{{ID: "a0", Label: "agent 0",
+			Attrs: map[string]interface{}{"x": x}}}}
+	}
+	tl := &Timeline{}
+	for i, x := range []int{1, 1, 2, 2, 1} {
+		tl.Add(node(i*10, x))
+	}
+	var buf bytes.Buffer
+	if err := tl.WriteGEXF(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f := readGEXF(t, buf.Bytes())
+	if len(f.Nodes) != 1 {
+		t.Fatalf("%d nodes, want 1", len(f.Nodes))
+	}
+
+	// the value of x at every step, exactly one value holds at a step
+	want := map[int]string{0: "1", 10: "1", 20: "2", 30: "2", 40: "1"}
+	for step, value := range want {
+		var values []string
+		for _, v := range f.Nodes[0].AttValues {
+			start := atoi(t, v.Start)
+			in := v.End != "" && start <= step && step <= atoi(t, v.End) ||
+				v.EndOpen != "" && start <= step && step < atoi(t, v.EndOpen)
+			if in {
+				values = append(values, v.Value)
+			}
+		}
+		if len(values) != 1 || values[0] != value {
+			t.Errorf("step %d: x is %v, want %s", step, values, value)
+		}
+	}
+	if s := f.Nodes[0].Spells; len(s) != 1 || s[0].Start != "0" || s[0].End != "40" {
+		t.Errorf("spells %+v, want one from 0 to 40", s)
+	}
+}
+
+func TestGraphFormats(t *testing.T) {
+	e := testModel(10, 7, 0.9, nil)
+	for i := 0; i < 10; i++ {
+		step(e)
+	}
+	g := e.Graph()
+	if len(e.Blogger) == 0 {
+		t.Fatal("no blogs to export")
+	}
+	if n := len(g.Nodes); n != 10+len(e.Blogger) {
+		t.Fatalf("%d nodes, want %d agents and blogs", n, 10+len(e.Blogger))
+	}
+
+	var gexf bytes.Buffer
+	if err := g.Write(&gexf, "net.gexf"); err != nil {
+		t.Fatal(err)
+	}
+	f := readGEXF(t, gexf.Bytes())
+	if len(f.Nodes) != len(g.Nodes) || len(f.Edges) != len(g.Edges) {
+		t.Errorf("GEXF has %d nodes and %d edges, want %d and %d",
+			len(f.Nodes), len(f.Edges), len(g.Nodes), len(g.Edges))
+	}
+
+	var graphml bytes.Buffer
+	if err := g.Write(&graphml, "net.graphml"); err != nil {
+		t.Fatal(err)
+	}
+	var ml struct {
+		Nodes []struct{} `xml:"graph>node"`
+		Edges []struct{} `xml:"graph>edge"`
+	}
+	if err := xml.Unmarshal(graphml.Bytes(), &ml); err != nil {
+		t.Fatal(err)
+	}
+	if len(ml.Nodes) != len(g.Nodes) || len(ml.Edges) != len(g.Edges) {
+		t.Errorf("GraphML has %d nodes and %d edges, want %d and %d",
+			len(ml.Nodes), len(ml.Edges), len(g.Nodes), len(g.Edges))
+	}
+
+	var dot bytes.Buffer
+	if err := g.Write(&dot, "net.dot"); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(dot.String(), " -> "); n != len(g.Edges) {
+		t.Errorf("DOT has %d edges, want %d", n, len(g.Edges))
+	}
+
+	if err := g.Write(&dot, "net.png"); err == nil {
+		t.Errorf("wrote an unknown format")
+	}
+}
@@ -0,0 +1,95 @@
+package model
+
+import "goabm"
+
+// an agent subscribed to (or unsubscribed from) a blog
+type SubscriptionEvent struct {
+	Step      int
+	Agent     goabm.AgentID
+	Blog      int
+	Subscribe bool
+}
+
+func (e *EchoChamberModel) LogSubscription(agent goabm.AgentID, b *Blog, subscribed bool) {
+	e.SubscriptionLog = append(e.SubscriptionLog,
+		SubscriptionEvent{Step: e.Step, Agent: agent, Blog: b.ID, Subscribe: subscribed})
+}
+
+// the current audience of a blog
+func (e *EchoChamberModel) Audience(b *Blog) []*EchoChamberAgent {
+	audience := make([]*EchoChamberAgent, 0, len(b.Followers))
+	for _, id := range b.Followers {
+		audience = append(audience, e.Landscape.GetAgentById(id).(*EchoChamberAgent))
+	}
+	return audience
+}
+
+// number of subscriptions and unsubscriptions of a blog in the steps [from, to)
+func (e *EchoChamberModel) Churn(blog, from, to int) (subscribed, unsubscribed int) {
+	for _, ev := range e.SubscriptionLog {
+		if ev.Blog != blog || ev.Step < from || ev.Step >= to {
+			continue
+		}
+		if ev.Subscribe {
+			subscribed++
+		} else {
+			unsubscribed++
+		}
+	}
+	return
+}
+
+// who is reading a blog
+type AudienceComposition struct {
+	Followers int
+	// distinct cultures among the followers
+	Cultures int
+	// followers which have a blog of their own
+	Bloggers int
+	// average similarity of the followers to the last post
+	MeanSimilarity float64
+}
+
+func (e *EchoChamberModel) AudienceComposition(b *Blog) AudienceComposition {
+	ac := AudienceComposition{Followers: len(b.Followers)}
+	if ac.Followers == 0 {
+		return ac
+	}
+
+	cultures := make(map[string]bool)
+	sim := 0.0
+	for _, f := range e.Audience(b) {
+		cultures[f.Culture()] = true
+		if f.MyBlog != nil {
+			ac.Bloggers++
+		}
+		if len(b.Posts) > 0 {
+			sim += e.Similarity(b.Posts[len(b.Posts)-1].Message, f.Features)
+		}
+	}
+	ac.Cultures = len(cultures)
+	ac.MeanSimilarity = sim / float64(ac.Followers)
+	return ac
+}
+
+// (un)subscriptions of the current step and the average audience size
+func (e *EchoChamberModel) SubscriptionStatistics() {
+	e.Subscriptions = 0
+	e.Unsubscriptions = 0
+	for i := len(e.SubscriptionLog) - 1; i >= 0 && e.SubscriptionLog[i].Step == e.Step; i-- {
+		if e.SubscriptionLog[i].Subscribe {
+			e.Subscriptions++
+		} else {
+			e.Unsubscriptions++
+		}
+	}
+
+	e.AvgAudience = 0
+	if len(e.Blogger) > 0 {
+		followers := 0
+		for _, blog := range e.Blogger {
+			followers += len(blog.Followers)
+		}
+		e.AvgAudience = float64(followers) / float64(len(e.Blogger))
+	}
+}
@@ -0,0 +1,121 @@
+package model
+
+import "goabm"
+import "math/rand"
+import "testing"
+
+// every blog's followers are exactly the agents which follow it, once each
+func checkFollowers(t *testing.T, e *EchoChamberModel, blogs []*Blog) {
+	t.Helper()
+	for _, blog := range blogs {
+		seen := make(map[goabm.AgentID]bool)
+		for _, id := range blog.Followers {
+			if seen[id] {
+				t.Fatalf("agent %d follows blog %d twice", id, blog.ID)
+			}
+			seen[id] = true
+		}
+		for _, b := range *e.Landscape.GetAgents() {
+			a := b.(*EchoChamberAgent)
+			if a.MySubscriptions.Follows(blog) != seen[a.ID()] {
+				t.Fatalf("agent %d follows blog %d: %v, among its followers: %v",
+					a.ID(), blog.ID, a.MySubscriptions.Follows(blog), seen[a.ID()])
+			}
+		}
+	}
+}
+
+func TestFollowersSymmetric(t *testing.T) {
+	e := testModel(10, 7, 0.3, nil)
+	blogs := testBlogs(e, Feature{0, 0, 0, 0, 0}, Feature{0, 0, 0, 0, 1}, Feature{0, 0, 0, 1, 1},
+		Feature{0, 0, 1, 1, 1})
+	agents := *e.Landscape.GetAgents()
+	r := rand.New(rand.NewSource(1))
+
+	subscribed, unsubscribed := 0, 0
+	for i := 0; i < 500; i++ {
+		e.Step = i / 10
+		a := agents[r.Intn(len(agents))].(*EchoChamberAgent)
+		blog := blogs[r.Intn(len(blogs))]
+		follows := a.MySubscriptions.Follows(blog)
+		switch r.Intn(4) {
+		case 0:
+			a.MySubscriptions.Subscribe(blog)
+			if !follows {
+				subscribed++
+			}
+		case 1:
+			a.MySubscriptions.SubscribeRecommended(blog)
+			if !follows {
+				subscribed++
+			}
+		case 2:
+			a.MySubscriptions.Unsubscribe(blog)
+			if follows {
+				unsubscribed++
+			}
+		case 3:
+			a.MySubscriptions.Remove(func(b *Blog) bool { return b == blog })
+			if follows {
+				unsubscribed++
+			}
+		}
+		checkFollowers(t, e, blogs)
+	}
+
+	// only changes are logged
+	s, u := 0, 0
+	for _, blog := range blogs {
+		bs, bu := e.Churn(blog.ID, 0, e.Step+1)
+		s, u = s+bs, u+bu
+	}
+	if s != subscribed || u != unsubscribed {
+		t.Errorf("churn %d subscriptions and %d unsubscriptions, want %d and %d", s, u, subscribed, unsubscribed)
+	}
+	if s, u := e.Churn(blogs[0].ID, 0, 0); s != 0 || u != 0 {
+		t.Errorf("churn %d and %d in an empty range", s, u)
+	}
+
+	e.SubscriptionStatistics()
+	followers := 0
+	for _, blog := range blogs {
+		followers += len(blog.Followers)
+	}
+	if want := float64(followers) / float64(len(blogs)); e.AvgAudience != want {
+		t.Errorf("average audience %g, want %g", e.AvgAudience, want)
+	}
+	s, u = 0, 0
+	for _, ev := range e.SubscriptionLog {
+		if ev.Step == e.Step && ev.Subscribe {
+			s++
+		} else if ev.Step == e.Step {
+			u++
+		}
+	}
+	if e.Subscriptions != s || e.Unsubscriptions != u {
+		t.Errorf("%d subscriptions and %d unsubscriptions in the last step, want %d and %d",
+			e.Subscriptions, e.Unsubscriptions, s, u)
+	}
+}
+
+func TestAudienceComposition(t *testing.T) {
+	e := testModel(10, 7, 0.3, nil)
+	blogs := testBlogs(e, Feature{0, 0, 0, 0, 0}, Feature{1, 1, 1, 1, 1})
+	agents := *e.Landscape.GetAgents()
+	// agent 2 has a blog of its own, agents 5 and 6 share a culture
+	for id, f := range map[int]Feature{2: {1, 1, 1, 1, 1}, 5: {0, 0, 0, 0, 1}, 6: {0, 0, 0, 0, 1}} {
+		a := agents[id].(*EchoChamberAgent)
+		a.Features = f
+		a.MySubscriptions.Subscribe(blogs[0])
+	}
+
+	ac := e.AudienceComposition(blogs[0])
+	// similarities 0, 0.8 and 0.8 to the post
+	want := AudienceComposition{Followers: 3, Cultures: 2, Bloggers: 1, MeanSimilarity: 1.6 / 3}
+	if ac != want {
+		t.Errorf("audience %+v, want %+v", ac, want)
+	}
+	if ac := e.AudienceComposition(blogs[1]); ac != (AudienceComposition{}) {
+		t.Errorf("audience of a blog without followers %+v", ac)
+	}
+}
@@ -0,0 +1,205 @@
+package model
+
+import "sort"
+
+// names of the echo chamber metrics
+const (
+	// share of comments which approve the post they belong to
+	MetricApproval = "approval"
+	// average similarity between a blog and its followers
+	MetricHomophily = "homophily"
+	// E-I index of the comments, -1 all internal, 1 all external
+	MetricEI = "ei"
+	// average similarity of the comments to their post
+	MetricResponseSimilarity = "response-similarity"
+)
+
+var MetricNames = []string{MetricApproval, MetricHomophily, MetricEI, MetricResponseSimilarity}
+
+// thresholds of the echo chamber metrics
+type MetricsConfig struct {
+	// metric which defines TotalEchoChambers and EchoChamberRatio
+	Primary string
+
+	// a comment approves a post if their similarity is above
+	ApprovalSimilarity float64
+	// a blog is an echo chamber if its approval ratio is above
+	EchoChamberApproval float64
+
+	// a blog is an echo chamber if the average follower similarity is above
+	EchoChamberHomophily float64
+
+	// comments more similar than this are internal for the E-I index
+	InternalSimilarity float64
+	// a blog is an echo chamber if its E-I index is below
+	EchoChamberEI float64
+
+	// a blog is an echo chamber if the average response similarity is above
+	EchoChamberResponseSimilarity float64
+}
+
+func DefaultMetricsConfig() *MetricsConfig {
+	return &MetricsConfig{
+		Primary:                       MetricApproval,
+		ApprovalSimilarity:            0.5,
+		EchoChamberApproval:           0.64,
+		EchoChamberHomophily:          0.5,
+		InternalSimilarity:            0.5,
+		EchoChamberEI:                 -0.28, // 64% internal
+		EchoChamberResponseSimilarity: 0.5,
+	}
+}
+
+// metric values of a single blog, Valid tells which metrics could be
+// computed (e.g. a blog without comments has no approval ratio)
+type BlogMetrics struct {
+	Blog      int
+	Responses int
+	Followers int
+
+	Values      map[string]float64
+	Valid       map[string]bool
+	EchoChamber map[string]bool
+}
+
+type MetricsReport struct {
+	Blogs []BlogMetrics
+
+	// metric -> number of blogs classified as echo chamber
+	EchoChambers map[string]int
+	// metric -> echo chambers / total blogs
+	Ratios map[string]float64
+	// metric -> mean value over all blogs with a valid value
+	Means map[string]float64
+}
+
+// values of the metric for every blog where it is defined
+func (r *MetricsReport) Distribution(metric string) []float64 {
+	var d []float64
+	for _, b := range r.Blogs {
+		if b.Valid[metric] {
+			d = append(d, b.Values[metric])
+		}
+	}
+	return d
+}
+
+// echo chamber status of every blog according to the metric
+func (r *MetricsReport) Classification(metric string) map[int]bool {
+	echo := make(map[int]bool, len(r.Blogs))
+	for _, b := range r.Blogs {
+		echo[b.Blog] = b.EchoChamber[metric]
+	}
+	return echo
+}
+
+// measures all echo chamber metrics for every blog
+func (e *EchoChamberModel) MeasureEchoChambers(cfg *MetricsConfig) *MetricsReport {
+	followers := e.followersOf()
+
+	ids := make([]int, 0, len(e.Blogger))
+	blogs := make(map[int]*Blog, len(e.Blogger))
+	for _, blog := range e.Blogger {
+		ids = append(ids, blog.ID)
+		blogs[blog.ID] = blog
+	}
+	sort.Ints(ids)
+
+	report := &MetricsReport{
+		EchoChambers: make(map[string]int),
+		Ratios:       make(map[string]float64),
+		Means:        make(map[string]float64),
+	}
+
+	for _, id := range ids {
+		bm := e.measureBlog(cfg, blogs[id], followers[id])
+		report.Blogs = append(report.Blogs, bm)
+	}
+
+	for _, m := range MetricNames {
+		sum := 0.0
+		n := 0
+		for _, bm := range report.Blogs {
+			if bm.EchoChamber[m] {
+				report.EchoChambers[m]++
+			}
+			if bm.Valid[m] {
+				sum += bm.Values[m]
+				n++
+			}
+		}
+		if len(report.Blogs) > 0 {
+			report.Ratios[m] = float64(report.EchoChambers[m]) / float64(len(report.Blogs))
+		}
+		if n > 0 {
+			report.Means[m] = sum / float64(n)
+		}
+	}
+	return report
+}
+
+func (e *EchoChamberModel) measureBlog(cfg *MetricsConfig, blog *Blog, followers []*EchoChamberAgent) BlogMetrics {
+	bm := BlogMetrics{Blog: blog.ID, Followers: len(followers),
+		Values:      make(map[string]float64),
+		Valid:       make(map[string]bool),
+		EchoChamber: make(map[string]bool)}
+
+	approve := 0
+	internal := 0
+	simSum := 0.0
+	for _, p := range blog.Posts {
+		for _, c := range p.Thread() {
+			sim := e.Similarity(p.Message, c.Message)
+			if sim > cfg.ApprovalSimilarity {
+				approve++
+			}
+			if sim > cfg.InternalSimilarity {
+				internal++
+			}
+			simSum += sim
+			bm.Responses++
+		}
+	}
+
+	if bm.Responses > 0 {
+		total := float64(bm.Responses)
+		external := bm.Responses - internal
+
+		bm.set(MetricApproval, float64(approve)/total, func(v float64) bool {
+			return v > cfg.EchoChamberApproval
+		})
+		bm.set(MetricEI, float64(external-internal)/total, func(v float64) bool {
+			return v < cfg.EchoChamberEI
+		})
+		bm.set(MetricResponseSimilarity, simSum/total, func(v float64) bool {
+			return v > cfg.EchoChamberResponseSimilarity
+		})
+	}
+
+	if len(followers) > 0 && len(blog.Posts) > 0 {
+		last := blog.Posts[len(blog.Posts)-1].Message
+		h := 0.0
+		for _, f := range followers {
+			h += e.Similarity(last, f.Features)
+		}
+		bm.set(MetricHomophily, h/float64(len(followers)), func(v float64) bool {
+			return v > cfg.EchoChamberHomophily
+		})
+	}
+	return bm
+}
+
+func (bm *BlogMetrics) set(metric string, v float64, echo func(float64) bool) {
+	bm.Values[metric] = v
+	bm.Valid[metric] = true
+	bm.EchoChamber[metric] = echo(v)
+}
+
+// the current followers of every blog by blog id
+func (e *EchoChamberModel) followersOf() map[int][]*EchoChamberAgent {
+	followers := make(map[int][]*EchoChamberAgent, len(e.Blogger))
+	for _, blog := range e.Blogger {
+		followers[blog.ID] = e.Audience(blog)
+	}
+	return followers
+}
@@ -0,0 +1,104 @@
+package model
+
+import "math"
+import "testing"
+
+// blog 0 with three comments and two followers, blog 1 without either
+func testMetrics() *MetricsReport {
+	e := testModel(10, 7, 0.3, nil)
+	blogs := testBlogs(e, Feature{0, 0, 0, 0, 0}, Feature{1, 1, 1, 1, 1})
+	post := blogs[0].Posts[0]
+	// similarities 1, 0.8 and 0
+	post.Respond(3, 0, Feature{0, 0, 0, 0, 0})
+	post.Respond(4, 0, Feature{0, 0, 0, 0, 1})
+	post.Respond(5, 0, Feature{1, 1, 1, 1, 1})
+
+	agents := *e.Landscape.GetAgents()
+	for id, f := range map[int]Feature{6: {0, 0, 0, 0, 0}, 7: {1, 1, 1, 1, 1}} {
+		a := agents[id].(*EchoChamberAgent)
+		a.Features = f
+		a.MySubscriptions.Subscribe(blogs[0])
+	}
+	return e.MeasureEchoChambers(DefaultMetricsConfig())
+}
+
+func TestMeasureEchoChambers(t *testing.T) {
+	r := testMetrics()
+	if len(r.Blogs) != 2 {
+		t.Fatalf("%d blogs measured, want 2", len(r.Blogs))
+	}
+	b := r.Blogs[0]
+	if b.Responses != 3 || b.Followers != 2 {
+		t.Errorf("%d responses and %d followers, want 3 and 2", b.Responses, b.Followers)
+	}
+
+	tests := []struct {
+		metric string
+		value  float64
+		echo   bool
+	}{
+		// 2 of 3 comments approve, a ratio and not an integer division
+		{MetricApproval, 2. / 3, true},
+		// 1 external - 2 internal of 3
+		{MetricEI, -1. / 3, true},
+		{MetricResponseSimilarity, 0.6, true},
+		// followers of the similarities 1 and 0, not above 0.5
+		{MetricHomophily, 0.5, false},
+	}
+	for _, tt := range tests {
+		if !b.Valid[tt.metric] || math.Abs(b.Values[tt.metric]-tt.value) > 1e-12 {
+			t.Errorf("%s: %g (valid %v), want %g", tt.metric, b.Values[tt.metric], b.Valid[tt.metric], tt.value)
+		}
+		if b.EchoChamber[tt.metric] != tt.echo {
+			t.Errorf("%s: echo chamber %v, want %v", tt.metric, b.EchoChamber[tt.metric], tt.echo)
+		}
+
+		// the other blog has no value, but counts for the ratio
+		if r.Blogs[1].Valid[tt.metric] || r.Blogs[1].EchoChamber[tt.metric] {
+			t.Errorf("%s: valid on a blog without comments and followers", tt.metric)
+		}
+		ratio := 0.0
+		if tt.echo {
+			ratio = 0.5
+		}
+		if r.Ratios[tt.metric] != ratio {
+			t.Errorf("%s: ratio %g, want %g", tt.metric, r.Ratios[tt.metric], ratio)
+		}
+		if math.Abs(r.Means[tt.metric]-tt.value) > 1e-12 {
+			t.Errorf("%s: mean %g, want the valid blog's %g", tt.metric, r.Means[tt.metric], tt.value)
+		}
+		if d := r.Distribution(tt.metric); len(d) != 1 {
+			t.Errorf("%s: distribution %v, want one value", tt.metric, d)
+		}
+	}
+
+	c := r.Classification(MetricApproval)
+	if !c[r.Blogs[0].Blog] || c[r.Blogs[1].Blog] {
+		t.Errorf("classification %v", c)
+	}
+}
+
+func TestApprovalThreshold(t *testing.T) {
+	// 2 of 3 comments approve: an echo chamber up to the threshold 2/3
+	tests := []struct {
+		threshold float64
+		echo      bool
+	}{
+		{0.5, true},
+		{0.66, true},
+		{2. / 3, false},
+		{0.7, false},
+	}
+	for _, tt := range tests {
+		e := testModel(10, 7, 0.3, nil)
+		post := testBlogs(e, Feature{0, 0, 0, 0, 0})[0].Posts[0]
+		post.Respond(3, 0, Feature{0, 0, 0, 0, 0})
+		post.Respond(4, 0, Feature{0, 0, 0, 0, 1})
+		post.Respond(5, 0, Feature{1, 1, 1, 1, 1})
+		cfg := DefaultMetricsConfig()
+		cfg.EchoChamberApproval = tt.threshold
+		if echo := e.MeasureEchoChambers(cfg).Blogs[0].EchoChamber[MetricApproval]; echo != tt.echo {
+			t.Errorf("threshold %g: echo chamber %v, want %v", tt.threshold, echo, tt.echo)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package model
 import "goabm"
 
 import "fmt"
+import "math"
 import "math/rand"
+import "sort"
 
 
 
@@ -20,58 +22,165 @@ type Range struct {
 }
 
 
-type PF func() float64
+// a probability function, draws from the random source of the model
+type PF func(*rand.Rand) float64
 
 type Feature []int
 
+// whether both features have the same traits
+func (f Feature) Equal(other Feature) bool {
+	if len(f) != len(other) {
+		return false
+	}
+	for i := range f {
+		if f[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// a blog post or a comment, replies to it form a thread
 type Comment struct {
-	Message   Feature
-	Responses []Feature
+	Message Feature
+	Author  goabm.AgentID
+	Step    int
+
+	// the comment we replied to, nil for blog posts
+	Parent    *Comment `json:"-"`
+	Responses []*Comment
 }
 
-func (c *Comment) Respond(f Feature) {
-	c.Responses = append(c.Responses, f)
+// adds a reply by author to the comment
+func (c *Comment) Respond(author goabm.AgentID, step int, f Feature) *Comment {
+	msg := make(Feature, len(f))
+	copy(msg, f)
+	r := &Comment{Message: msg, Author: author, Step: step, Parent: c}
+	c.Responses = append(c.Responses, r)
+	return r
 }
 
 type FloatRange [2]float64
 type IntRange [2]int
 
 type Blog struct {
-	Posts     []Comment
+	Posts     []*Comment
 	Followers []goabm.AgentID
 	ID        int
+	Owner     goabm.AgentID
+
+	index *BlogIndex
+}
+
+func (b *Blog) addFollower(id goabm.AgentID) {
+	b.Followers = append(b.Followers, id)
 }
 
-func (b *Blog) Publish(f Feature) {
-	b.Posts = append(b.Posts, Comment{Message: f})
+func (b *Blog) removeFollower(id goabm.AgentID) {
+	for i, f := range b.Followers {
+		if f == id {
+			b.Followers = append(b.Followers[:i], b.Followers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (b *Blog) Publish(step int, f Feature) {
+	// the post is a snapshot, the author's features will change later on
+	msg := make(Feature, len(f))
+	copy(msg, f)
+	b.Posts = append(b.Posts, &Comment{Message: msg, Author: b.Owner, Step: step})
+
+	if b.index != nil {
+		b.index.Update(b)
+	}
 }
 
 type BlogSubscription struct {
+	Subscriber    goabm.AgentID
 	FollowedBlogs map[int]*Blog
 	ReadPosts     map[int]map[int]bool //blogid -> postid
+	Recommended   map[int]bool         //blogid -> subscribed on recommendation
+
+	// called on every (un)subscription
+	onChange func(b *Blog, subscribed bool)
 }
 
 func (bs *BlogSubscription) Subscribe(b *Blog) {
+	if bs.Follows(b) {
+		return
+	}
 	bs.FollowedBlogs[len(bs.FollowedBlogs)] = b
 	bs.ReadPosts[b.ID] = make(map[int]bool, len(b.Posts))
-}
+	delete(bs.Recommended, b.ID)
 
-func (bs *BlogSubscription) Remove(cl FloatRange, subscriber Feature) {
+	b.addFollower(bs.Subscriber)
+	if bs.onChange != nil {
+		bs.onChange(b, true)
+	}
+}
 
+func (bs *BlogSubscription) Unsubscribe(b *Blog) {
 	nb := make(map[int]*Blog)
-	for _, blog := range bs.FollowedBlogs {
-		// get last post
-		p := blog.Posts[len(blog.Posts)-1]
+	found := false
+	for _, blog := range bs.Blogs() {
+		if blog.ID == b.ID {
+			found = true
+			continue
+		}
+		nb[len(nb)] = blog
+	}
+	if !found {
+		return
+	}
+	bs.FollowedBlogs = nb
+	delete(bs.Recommended, b.ID)
 
-		sim := Similarity(subscriber, p.Message)
-		if sim > cl[0] {
-			// keep it
+	b.removeFollower(bs.Subscriber)
+	if bs.onChange != nil {
+		bs.onChange(b, false)
+	}
+}
+
+// subscribe to a blog the recommender suggested
+func (bs *BlogSubscription) SubscribeRecommended(b *Blog) {
+	bs.Subscribe(b)
+	bs.Recommended[b.ID] = true
+}
+
+// the followed blogs in subscription order, ranging over the map directly
+// would make runs irreproducible
+func (bs *BlogSubscription) Blogs() []*Blog {
+	keys := make([]int, 0, len(bs.FollowedBlogs))
+	for k := range bs.FollowedBlogs {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+
+	blogs := make([]*Blog, len(keys))
+	for i, k := range keys {
+		blogs[i] = bs.FollowedBlogs[k]
+	}
+	return blogs
+}
 
-			//nb = append(nb, blog)
-			nb[len(nb)] = blog
+// returns true if the blog is among the followed ones
+func (bs *BlogSubscription) Follows(b *Blog) bool {
+	for _, blog := range bs.FollowedBlogs {
+		if blog.ID == b.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// unsubscribe from all blogs we want to drop
+func (bs *BlogSubscription) Remove(drop func(*Blog) bool) {
+	for _, blog := range bs.Blogs() {
+		if drop(blog) {
+			bs.Unsubscribe(blog)
 		}
 	}
-	bs.FollowedBlogs = nb
 }
 
 func (bs *BlogSubscription) UnreadBlogPost() *Comment {
@@ -81,7 +190,7 @@ func (bs *BlogSubscription) UnreadBlogPost() *Comment {
 	//	bs.FollowedBlogs, bs.ReadPosts)
 	var PostToRead *Comment
 	// foreach blog
-	for _, blog := range bs.FollowedBlogs {
+	for _, blog := range bs.Blogs() {
 		// have we read it all?
 		if val, ok := bs.ReadPosts[blog.ID]; ok && len(blog.Posts) == len(val) {
 			//skip
@@ -99,7 +208,7 @@ func (bs *BlogSubscription) UnreadBlogPost() *Comment {
 			// mark as read
 			//fmt.Printf("read P %d %v", j, post)
 			bs.ReadPosts[blog.ID][j] = true
-			PostToRead = &blog.Posts[j]
+			PostToRead = blog.Posts[j]
 
 		}
 
@@ -168,8 +277,8 @@ func (a *EchoChamberAgent) Culture() string {
 }
 
 func (a *EchoChamberAgent) MutateFeatures() {
-	i := rand.Intn(len(a.Features))
-	j := rand.Intn(a.Model.NTraits)
+	i := a.Model.Rng().Intn(len(a.Features))
+	j := a.Model.Rng().Intn(a.Model.NTraits)
 
 	a.Features[i] = j
 }
@@ -184,7 +293,7 @@ func (a *EchoChamberAgent) ChangeFeatures(other Feature) {
 				a.Features[i] = other[i]
 			} else {
 			        // we didn't, but we still got influeced
-			     	j := rand.Intn(a.Model.NTraits)
+			     	j := a.Model.Rng().Intn(a.Model.NTraits)
 			     	a.Features[i] = j
 			}
 
@@ -201,13 +310,11 @@ func (a *EchoChamberAgent) ChangeFeatures(other Feature) {
 // returns true if  a change happend
 func (a *EchoChamberAgent) FeatureInteraction(other Feature) bool {
 
-	sim := a.Similarity(other)
-
-	//fmt.Printf("sim: %f", sim)
-	if sim >= 0.99 {
-		// agents are already equal
+	if a.Features.Equal(other) {
+		// agents are already equal, nothing to copy
 		return false
 	}
+	sim := a.Similarity(other)
 
 	//interact with sim% chance
 	if a.Model.RollDice(sim) {
@@ -243,7 +350,15 @@ func (a *EchoChamberAgent) PhysicalInteraction(other *EchoChamberAgent) {
 }
 
 func (a *EchoChamberAgent) FindABlog() {
-	blog := a.Model.GoogleBlog(a.Features)
+	// the platform might recommend us something
+	if a.Model.Recommender != nil && a.Model.RollDice(a.Model.PRecommend) {
+		if blog := a.Model.Recommender.Recommend(a); blog != nil {
+			a.MySubscriptions.SubscribeRecommended(blog)
+			return
+		}
+	}
+
+	blog := a.Model.DiscoveryPolicy().Discover(a)
 	if blog == nil {
 		//panic("nil blog")
 		//fmt.Println("no blog found...")
@@ -258,7 +373,7 @@ func (a *EchoChamberAgent) WriteBlog() {
 		panic("can't blog without a blog")
 	}
 
-	a.MyBlog.Publish(a.Features)
+	a.MyBlog.Publish(a.Model.Step, a.Features)
 }
 
 func (a *EchoChamberAgent) ReadBlogs() {
@@ -282,8 +397,11 @@ func (a *EchoChamberAgent) ReadBlogs() {
 	}
 
 	// check if we like our blogs
-	if a.Model.RollDice(0.4) {
-		a.MySubscriptions.Remove(a.RSimilarityConfortLevel, a.Features)
+	policy := a.Model.UnsubscribePolicy()
+	if a.Model.RollDice(policy.CheckRate()) {
+		a.MySubscriptions.Remove(func(b *Blog) bool {
+			return policy.Drop(a, b)
+		})
 	}
 
 	if len(a.MySubscriptions.FollowedBlogs) == 0 {
@@ -307,14 +425,21 @@ func (a *EchoChamberAgent) ReadBlogs() {
 	}
 	
 	// now we read some responses, if there are any
-	if len(post.Responses) > 0 {
+	// we will reply to the post, or with threaded_replies to the last
+	// thing we read
+	last := post
+	thread := post.Thread()
+	if len(thread) > 0 {
 	
-		numResponses := rand.Intn(len(post.Responses))
+		numResponses := a.Model.Rng().Intn(len(thread))
 		for i := 0; i < numResponses; i++ {
 			// and interact with them
-			comment := post.Responses[i]
+			comment := thread[i]
+			if a.Model.IsRuleActive("threaded_replies") {
+				last = comment
+			}
 			//read it
-			change := a.FeatureInteraction(comment)
+			change := a.FeatureInteraction(comment.Message)
 
 			if change {
 				a.OnlineInteraction++
@@ -327,7 +452,7 @@ func (a *EchoChamberAgent) ReadBlogs() {
 
 	if a.Model.RollDice(a.PRespondBlogPost) {
 		// write comment
-		post.Respond(a.Features)
+		last.Respond(a.ID(), a.Model.Step, a.Features)
 
 	}
 }
@@ -367,19 +492,20 @@ func (a *EchoChamberAgent) VirtualInteraction() {
 // required for the simulation interface, called everytime when the agent is activated
 func (a *EchoChamberAgent) Act() {
 
-	dicem := rand.Float64()
-	// (i) agent decides to move according to the probability veloc
-	if dicem <= a.PVeloc {
-		a.MoveRandomly(a.Steplength)
+	dicem := a.Model.Rng().Float64()
+	// (i) agent decides to move according to the probability veloc, unless
+	// the movement rule keeps everyone in place
+	if a.Model.IsRuleActive("movement") && dicem <= a.PVeloc {
+		a.Move(a.Steplength)
 		//fmt.Println("move...")
 	}
 
 	if a.Model.RollDice(a.POnline) {
 		a.VirtualInteraction()
 	} else {
-		other := a.GetRandomNeighbor()
+		other := a.RandomNeighbor()
 		if other != nil {
-			a.PhysicalInteraction(other.(*EchoChamberAgent))
+			a.PhysicalInteraction(other)
 		}
 	}
 
@@ -387,15 +513,7 @@ func (a *EchoChamberAgent) Act() {
 
 // helper function to determine the similarity between to agents
 func (a *EchoChamberAgent) Similarity(other Feature) float64 {
-	c := float64(0.0)
-	// count equal traits, final score = shared traits/total traits
-	for i := range a.Features {
-		if a.Features[i] == other[i] {
-			c = c + 1
-		}
-	}
-	//fmt.Printf("sim: %f/%d\n",c,len(a.features))
-	return c / float64(len(a.Features))
+	return a.Model.Similarity(a.Features, other)
 }
 
 type EchoChamberModel struct {
@@ -410,6 +528,25 @@ type EchoChamberModel struct {
 	TotalEchoChambers  int
 	EchoChamberRatio   float64
 
+	// cultural regions on the physical landscape
+	Regions       int
+	LargestRegion float64
+	// the located agents, kept up to date as they move
+	located *neighborhood `goabm:"hide"`
+
+	// subscription stats of the current step
+	Subscriptions   int
+	Unsubscriptions int
+	AvgAudience     float64
+
+	// conversation stats
+	MaxThreadDepth int
+	AvgThreadDepth float64
+
+	// recommender stats
+	RecommendedSubscriptions    int
+	RecommendedEchoChamberRatio float64
+	SearchedEchoChamberRatio    float64
 
 	//parameters
 	NTraits   int `goabm:"hide"` // don't show these in the stats'
@@ -421,61 +558,138 @@ type EchoChamberModel struct {
         PFOnline PF
         PFU PF
 
+	// definition of cultural closeness, defaults to ExactMatch
+	Metric SimilarityMetric `goabm:"hide"`
+
+	// how agents find new blogs, defaults to MostSimilar
+	Discovery DiscoveryPolicy `goabm:"hide"`
+
+	// when agents drop blogs, defaults to DefaultUnsubscribePolicy
+	Unsubscribe UnsubscribePolicy `goabm:"hide"`
+
+	// platform recommendations, used instead of searching with PRecommend
+	Recommender Recommender `goabm:"hide"`
+	PRecommend  float64     `goabm:"hide"`
+
 	// blogging parameters
 	PStartBlogging          float64    `goabm:"hide"`
 
 	RSubscribedBlogs        IntRange   `goabm:"hide"`
 	RSimilarityConfortLevel FloatRange `goabm:"hide"`
 
-
+	// per agent distributions of the parameters by name (see AgentParams),
+	// parameters without one take the value above or their PF
+	Distributions map[string]*Distribution `goabm:"hide"`
 
 	//movement parameters
 	Steplength float64 `goabm:"hide"`
 	PVeloc     float64 `goabm:"hide"`
 
+	// radius of a cultural region, 0 takes the Sight of the landscape
+	Sight float64 `goabm:"hide"`
+
+	// echo chamber metrics, defaults to DefaultMetricsConfig
+	MetricsConfig *MetricsConfig `goabm:"hide"`
+	Metrics       *MetricsReport `goabm:"hide"`
+
+	// current simulation step
+	Step int `goabm:"hide"`
+
+	// every random draw of the model goes through Rand, which is seeded
+	// with Seed, so a run is reproducible. The model places and moves the
+	// agents and picks their neighbors itself, goabm's versions draw from
+	// the global source.
+	Seed   int64      `goabm:"hide"`
+	Source *Source    `goabm:"hide"`
+	Rand   *rand.Rand `goabm:"hide"`
+	// the source of the placement, see place
+	placement *rand.Rand
+
 	//datastructures
+	SubscriptionLog []SubscriptionEvent `goabm:"hide"`
 	Blogger   map[goabm.AgentID]*Blog `goabm:"hide"`
+	Search    *BlogIndex              `goabm:"hide"`
 	Landscape goabm.Landscaper
 
 	goabm.Model
 }
 
+// returns the random source of the model, seeded with Seed on first use
+func (e *EchoChamberModel) Rng() *rand.Rand {
+	if e.Rand == nil {
+		e.Source = NewSource(e.Seed)
+		e.Rand = rand.New(e.Source)
+	}
+	return e.Rand
+}
+
+// returns true with probability p, replaces goabm's version which uses
+// the global source
+func (e *EchoChamberModel) RollDice(p float64) bool {
+	return e.Rng().Float64() < p
+}
+
+// returns the configured similarity metric
+func (e *EchoChamberModel) SimilarityMetric() SimilarityMetric {
+	if e.Metric == nil {
+		return ExactMatch{}
+	}
+	return e.Metric
+}
+
+// returns the configured discovery policy
+func (e *EchoChamberModel) DiscoveryPolicy() DiscoveryPolicy {
+	if e.Discovery == nil {
+		return MostSimilar{}
+	}
+	return e.Discovery
+}
+
+// returns the configured unsubscribe policy
+func (e *EchoChamberModel) UnsubscribePolicy() UnsubscribePolicy {
+	if e.Unsubscribe == nil {
+		return DefaultUnsubscribePolicy()
+	}
+	return e.Unsubscribe
+}
+
+// similarity of two features according to the configured metric
+func (e *EchoChamberModel) Similarity(first, other Feature) float64 {
+	return e.SimilarityMetric().Similarity(first, other)
+}
+
 func (e *EchoChamberModel) CreateBlog(a *EchoChamberAgent) *Blog {
 	//e.Blogger = append(e.Blogger, )
-	e.Blogger[a.ID()] = &Blog{ID: len(e.Blogger)}
+	e.Blogger[a.ID()] = &Blog{ID: len(e.Blogger), Owner: a.ID(), index: e.Search}
 	//fmt.Printf("%d created blog %d\n", a.ID(), len(e.Blogger))
 
 	return e.Blogger[a.ID()]
 }
 
-func (e *EchoChamberModel) GoogleBlog(f Feature) *Blog {
-	// we google a blog for our cultural identity (features)
-	// rank all blogs for similarity (last post), return the best
-
-	ranking := make(map[goabm.AgentID]float64, len(e.Blogger))
-
-	for i, blog := range e.Blogger {
-
-		lastPost := blog.Posts[len(blog.Posts)-1]
-		sim := Similarity(f, lastPost.Message)
-		ranking[i] = sim
+// we google a blog for our cultural identity (features), the blogs are
+// ranked for similarity of their last post, skip filters out blogs we
+// don't want to see (e.g. the ones we already follow)
+func (e *EchoChamberModel) GoogleBlog(f Feature, skip func(*Blog) bool) *Blog {
+	res := e.SearchBlogs(f, 1, skip)
+	if len(res) == 0 {
+		return nil
 	}
+	return res[0].Blog
+}
 
-	// find best match
-	best := goabm.AgentID(0)
-	for i, rank := range ranking {
-		if rank > ranking[best] {
-			best = i
-		}
-	}
-	bestBlog := e.Blogger[best]
-	return bestBlog
+// returns the k best matching blogs, best first
+func (e *EchoChamberModel) SearchBlogs(f Feature, k int, skip func(*Blog) bool) []SearchResult {
+	return e.Search.Search(f, k, e.SimilarityMetric(), skip)
 }
 
 func (e *EchoChamberModel) Init(l interface{}) {
 	e.Landscape = l.(goabm.Landscaper)
+	for _, b := range *e.Landscape.GetAgents() {
+		e.place(b.(*EchoChamberAgent))
+	}
 
 	e.Blogger = make(map[goabm.AgentID]*Blog)
+	e.Search = NewBlogIndex()
 
 	//e.Ruleset.Init()
 }
@@ -483,34 +697,56 @@ func (e *EchoChamberModel) Init(l interface{}) {
 func (a *EchoChamberModel) CreateAgent(agenter interface{}) goabm.Agenter {
 
 	agent := &EchoChamberAgent{FLWMAgent: agenter.(*goabm.FLWMAgent)}
+	a.drawAgent(agent)
+	// a no-op until the model knows the landscape, Init places the agents
+	// created before
+	a.place(agent)
+
+	agent.MySubscriptions.ReadPosts = make(map[int]map[int]bool)
+	agent.MySubscriptions.FollowedBlogs = make(map[int]*Blog)
+	agent.MySubscriptions.Recommended = make(map[int]bool)
+	agent.MySubscriptions.Subscriber = agent.ID()
+	agent.MySubscriptions.onChange = func(b *Blog, subscribed bool) {
+		a.LogSubscription(agent.ID(), b, subscribed)
+	}
+	agent.Model = a
+	//fmt.Printf("agent: %v\n",agent)
+	return agent
+}
 
+// draws the features and the parameters of a new agent
+func (a *EchoChamberModel) drawAgent(agent *EchoChamberAgent) {
 	f := make(Feature, a.NFeatures)
 	for i := range f {
-		f[i] = rand.Intn(a.NTraits)
+		f[i] = a.Rng().Intn(a.NTraits)
 	}
 	agent.Features = f
 
-	agent.PStartBlogging = a.PStartBlogging
-	agent.PVeloc = a.PVeloc
+	agent.PStartBlogging = a.draw(ParamPStartBlogging, a.PStartBlogging)
+	agent.PVeloc = a.draw(ParamPVeloc, a.PVeloc)
+	agent.Steplength = a.draw(ParamSteplength, a.Steplength)
 
-	agent.RSubscribedBlogs = a.RSubscribedBlogs
+	min := int(math.Round(a.draw(ParamRSubscribedBlogsMin, float64(a.RSubscribedBlogs[0]))))
+	max := int(math.Round(a.draw(ParamRSubscribedBlogsMax, float64(a.RSubscribedBlogs[1]))))
+	if min > max {
+		min, max = max, min
+	}
+	agent.RSubscribedBlogs = IntRange{min, max}
 
-	agent.RSimilarityConfortLevel = a.RSimilarityConfortLevel
+	lo := a.draw(ParamRSimilarityConfortLevelMin, a.RSimilarityConfortLevel[0])
+	hi := a.draw(ParamRSimilarityConfortLevelMax, a.RSimilarityConfortLevel[1])
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	agent.RSimilarityConfortLevel = FloatRange{lo, hi}
 	
 	// pdfs
-	agent.POnline = a.PFOnline()
-	
-	agent.PRespondBlogPost = a.PFConsumptive()
-	agent.PWriteBlogPost = a.PFExpressive()
+	agent.POnline = a.drawPF(ParamPOnline, a.PFOnline)
 	
-	agent.PUnderstanding = a.PFU()
+	agent.PRespondBlogPost = a.drawPF(ParamPRespondBlogPost, a.PFConsumptive)
+	agent.PWriteBlogPost = a.drawPF(ParamPWriteBlogPost, a.PFExpressive)
 	
-	
-	agent.MySubscriptions.ReadPosts = make(map[int]map[int]bool)
-	agent.MySubscriptions.FollowedBlogs = make(map[int]*Blog)
-	agent.Model = a
-	//fmt.Printf("agent: %v\n",agent)
-	return agent
+	agent.PUnderstanding = a.drawPF(ParamPUnderstanding, a.PFU)
 }
 
 func (a *EchoChamberModel) BlogStatistics() {
@@ -518,39 +754,22 @@ func (a *EchoChamberModel) BlogStatistics() {
 	a.TotalBlogs = len(a.Blogger)
 	posts := 0
 	comments := 0
-	ec := 0
 	for _, blog := range a.Blogger {
 		posts += len(blog.Posts)
 
-		approve := 0
-		totalresp := 0
 		for _, p := range blog.Posts {
-			comments += len(p.Responses)
-			// calculate the similarity of each comment to the blog
-
-			for _, c := range p.Responses {
-				sim := Similarity(p.Message, c)
-				if sim > 0.50 {
-					approve++
-				}
-				totalresp++
-			}
-
+			comments += len(p.Thread())
 		}
+	}
 
-		if totalresp > 0 {
-			//avg := avgt / float64(len(p.Responses))
-			//fmt.Printf("avg: %f %f %d\n", avg, avgt, len(p.Responses))
-			approval := float64(approve / totalresp)
-			if approval > 0.64 {
-				// we found an echo chamber
-				//fmt.Printf("approval: %f", approval)
-				ec++
-			}
-		}
+	// calculate the similarity of each comment to the blog, followers etc.
+	cfg := a.MetricsConfig
+	if cfg == nil {
+		cfg = DefaultMetricsConfig()
 	}
+	a.Metrics = a.MeasureEchoChambers(cfg)
 
-	a.TotalEchoChambers = ec
+	a.TotalEchoChambers = a.Metrics.EchoChambers[cfg.Primary]
 
 	if a.TotalBlogs > 0 {
 		a.EchoChamberRatio = float64(a.TotalEchoChambers) / float64(a.TotalBlogs)
@@ -566,15 +785,17 @@ func (a *EchoChamberModel) BlogStatistics() {
 	}
 
 	// analyze follower constitution
-	for _, blog := range a.Blogger {
-		posts += len(blog.Posts)
-	}
+	a.SubscriptionStatistics()
+
+	a.RecommenderStatistics(a.Metrics.Classification(cfg.Primary))
+	a.ThreadStatistics()
 }
 
 func (a *EchoChamberModel) LandscapeAction() {
 	a.BlogStatistics()
 
 	a.Cultures = a.CountCultures()
+	a.RegionStatistics()
 
 	for _, b := range *a.Landscape.GetAgents() {
 		eca := b.(*EchoChamberAgent)
@@ -583,6 +804,7 @@ func (a *EchoChamberModel) LandscapeAction() {
 		a.OnlineInteraction += eca.OnlineInteraction
 	}
 
+	a.Step++
 }
 
 func (a *EchoChamberModel) CountCultures() int {
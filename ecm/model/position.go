@@ -0,0 +1,166 @@
+package model
+
+import "goabm"
+import "math"
+import "math/rand"
+import "sort"
+
+// position of the agent on the landscape, goabm keeps it in the FLWMAgent.
+// ok is false if the agent carries no position.
+func (a *EchoChamberAgent) Position() (x, y float64, ok bool) {
+	if a.FLWMAgent == nil {
+		return 0, 0, false
+	}
+	return a.FLWMAgent.X, a.FLWMAgent.Y, true
+}
+
+// the agents with a position at the end of a step, in a grid of cells as
+// wide as the sight to look up the neighbors
+type neighborhood struct {
+	agents []*EchoChamberAgent
+	x, y   []float64
+	sight  float64
+	size   float64
+	cells  map[[2]int][]int
+	index  map[*EchoChamberAgent]int
+}
+
+// locates the agents, those without a position are left out
+func (e *EchoChamberModel) locate() *neighborhood {
+	n := &neighborhood{sight: e.sight(), cells: make(map[[2]int][]int),
+		index: make(map[*EchoChamberAgent]int)}
+	// a sight of 0 only connects agents on the same spot
+	n.size = n.sight
+	if n.size <= 0 {
+		n.size = 1
+	}
+	for _, b := range *e.Landscape.GetAgents() {
+		a := b.(*EchoChamberAgent)
+		x, y, ok := a.Position()
+		if !ok {
+			continue
+		}
+		i := len(n.agents)
+		n.agents = append(n.agents, a)
+		n.x = append(n.x, x)
+		n.y = append(n.y, y)
+		n.index[a] = i
+		c := n.cell(x, y)
+		n.cells[c] = append(n.cells[c], i)
+	}
+	return n
+}
+
+// moves agent i to x, y
+func (n *neighborhood) move(i int, x, y float64) {
+	from, to := n.cell(n.x[i], n.y[i]), n.cell(x, y)
+	n.x[i], n.y[i] = x, y
+	if from == to {
+		return
+	}
+	cell := n.cells[from]
+	for k, j := range cell {
+		if j == i {
+			n.cells[from] = append(cell[:k], cell[k+1:]...)
+			break
+		}
+	}
+	n.cells[to] = append(n.cells[to], i)
+}
+
+func (n *neighborhood) cell(x, y float64) [2]int {
+	return [2]int{int(math.Floor(x / n.size)), int(math.Floor(y / n.size))}
+}
+
+// calls f for every agent j > i within sight of agent i
+func (n *neighborhood) neighbors(i int, f func(j int)) {
+	n.within(i, func(j int) {
+		if j > i {
+			f(j)
+		}
+	})
+}
+
+// calls f for every other agent within sight of agent i
+func (n *neighborhood) within(i int, f func(j int)) {
+	c := n.cell(n.x[i], n.y[i])
+	for dx := -1; dx <= 1; dx++ {
+		for dy := -1; dy <= 1; dy++ {
+			for _, j := range n.cells[[2]int{c[0] + dx, c[1] + dy}] {
+				if j != i && math.Hypot(n.x[i]-n.x[j], n.y[i]-n.y[j]) <= n.sight {
+					f(j)
+				}
+			}
+		}
+	}
+}
+
+// the width of the landscape, false if it has no positions
+func (e *EchoChamberModel) size() (float64, bool) {
+	if l, ok := e.Landscape.(*goabm.FixedLandscapeWithMovement); ok {
+		return float64(l.Size), true
+	}
+	return 0, false
+}
+
+// places the agent at random on the landscape instead of goabm, whose draws
+// are not seeded. Placement has a source of its own derived from Seed, so
+// the parameters CreateAgent draws (and Restore replays) do not depend on
+// when goabm hands the agents over.
+func (e *EchoChamberModel) place(a *EchoChamberAgent) {
+	size, ok := e.size()
+	if !ok || a.FLWMAgent == nil {
+		return
+	}
+	if e.placement == nil {
+		e.placement = rand.New(NewSource(e.Seed ^ placementSalt))
+	}
+	a.FLWMAgent.X = e.placement.Float64() * size
+	a.FLWMAgent.Y = e.placement.Float64() * size
+}
+
+// separates the placement source from the model's
+const placementSalt = 0x5deece66d
+
+// moves the agent up to steplength in a random direction, it stops at the
+// border of the landscape
+func (a *EchoChamberAgent) Move(steplength float64) {
+	x, y, ok := a.Position()
+	if !ok {
+		return
+	}
+	rng := a.Model.Rng()
+	angle := 2 * math.Pi * rng.Float64()
+	d := steplength * rng.Float64()
+	x += d * math.Cos(angle)
+	y += d * math.Sin(angle)
+	if size, ok := a.Model.size(); ok {
+		x = math.Max(0, math.Min(size, x))
+		y = math.Max(0, math.Min(size, y))
+	}
+	a.FLWMAgent.X, a.FLWMAgent.Y = x, y
+
+	if n := a.Model.located; n != nil {
+		n.move(n.index[a], x, y)
+	}
+}
+
+// a random agent within sight, nil if there is none
+func (a *EchoChamberAgent) RandomNeighbor() *EchoChamberAgent {
+	n := a.Model.neighbors()
+	i, ok := n.index[a]
+	if !ok {
+		return nil
+	}
+	var near []int
+	n.within(i, func(j int) {
+		near = append(near, j)
+	})
+	if len(near) == 0 {
+		return nil
+	}
+	// the order of the cells depends on the moves since the agents were
+	// located, the order of the agents does not
+	sort.Ints(near)
+	return n.agents[near[a.Model.Rng().Intn(len(near))]]
+}
@@ -0,0 +1,170 @@
+package model
+
+import "goabm"
+import "math"
+import "testing"
+
+// a model of n agents on a landscape with movement, placed by the model
+func testPlaced(n, size int, sight float64, seed int64) *EchoChamberModel {
+	e := testModel(0, seed, 0.3, nil)
+	l := &goabm.FixedLandscapeWithMovement{Size: size, NAgents: n, Sight: sight}
+	for i := 0; i < n; i++ {
+		l.Agents = append(l.Agents, e.CreateAgent(&goabm.FLWMAgent{Seqnr: goabm.AgentID(i)}))
+	}
+	e.Sight = 0
+	e.Init(l)
+	return e
+}
+
+func positions(e *EchoChamberModel) [][2]float64 {
+	var p [][2]float64
+	for _, b := range *e.Landscape.GetAgents() {
+		x, y, _ := b.(*EchoChamberAgent).Position()
+		p = append(p, [2]float64{x, y})
+	}
+	return p
+}
+
+func TestPlace(t *testing.T) {
+	a, b := testPlaced(50, 10, 1, 3), testPlaced(50, 10, 1, 3)
+	pa, pb := positions(a), positions(b)
+	for i := range pa {
+		if pa[i] != pb[i] {
+			t.Fatalf("agent %d at %v and %v with the same seed", i, pa[i], pb[i])
+		}
+		if pa[i][0] < 0 || pa[i][0] >= 10 || pa[i][1] < 0 || pa[i][1] >= 10 {
+			t.Errorf("agent %d off the landscape at %v", i, pa[i])
+		}
+	}
+	if c := positions(testPlaced(50, 10, 1, 4)); c[0] == pa[0] && c[1] == pa[1] {
+		t.Errorf("another seed, the same places: %v", c[:2])
+	}
+	// the parameters do not depend on the placement
+	unplaced := *testModel(50, 3, 0.3, nil).Landscape.GetAgents()
+	for i, b := range *a.Landscape.GetAgents() {
+		want := unplaced[i].(*EchoChamberAgent)
+		if got := b.(*EchoChamberAgent); !got.Features.Equal(want.Features) || got.POnline != want.POnline {
+			t.Fatalf("agent %d drew other parameters", i)
+		}
+	}
+}
+
+func TestMove(t *testing.T) {
+	tests := []struct {
+		name       string
+		steplength float64
+	}{
+		{"short steps", 0.5},
+		{"long steps", 4},
+		// the border stops the agents
+		{"across the landscape", 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := testPlaced(60, 10, 1.5, 1)
+			agents := *e.Landscape.GetAgents()
+			for step := 0; step < 20; step++ {
+				for _, b := range agents {
+					a := b.(*EchoChamberAgent)
+					x, y, _ := a.Position()
+					a.Move(tt.steplength)
+					nx, ny, _ := a.Position()
+					if d := math.Hypot(nx-x, ny-y); d > tt.steplength+1e-9 {
+						t.Fatalf("moved %g, more than %g", d, tt.steplength)
+					}
+					if nx < 0 || nx > 10 || ny < 0 || ny > 10 {
+						t.Fatalf("off the landscape at %g, %g", nx, ny)
+					}
+					// the neighbors are found from where the agents are now
+					if o := a.RandomNeighbor(); o != nil {
+						ox, oy, _ := o.Position()
+						if o == a || math.Hypot(nx-ox, ny-oy) > 1.5 {
+							t.Fatalf("neighbor at %g, %g of an agent at %g, %g", ox, oy, nx, ny)
+						}
+					}
+				}
+			}
+			fresh := e.locate()
+			for i, a := range fresh.agents {
+				var want, got []int
+				fresh.within(i, func(j int) { want = append(want, int(fresh.agents[j].ID())) })
+				n := e.neighbors()
+				n.within(n.index[a], func(j int) { got = append(got, int(n.agents[j].ID())) })
+				if !sameIDs(want, got) {
+					t.Fatalf("agent %d: neighbors %v, located again %v", a.ID(), got, want)
+				}
+			}
+		})
+	}
+}
+
+func sameIDs(a, b []int) bool {
+	seen := make(map[int]int)
+	for _, id := range a {
+		seen[id]++
+	}
+	for _, id := range b {
+		seen[id]--
+	}
+	for _, c := range seen {
+		if c != 0 {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
+
+func TestRandomNeighbor(t *testing.T) {
+	// agents in a row one apart, sight 1.5 reaches the next ones
+	e := testModel(5, 1, 0.3, nil)
+	agents := *e.Landscape.GetAgents()
+	tests := []struct {
+		agent int
+		want  []int
+	}{
+		{0, []int{1}},
+		{2, []int{1, 3}},
+		{4, []int{3}},
+	}
+	for _, tt := range tests {
+		a := agents[tt.agent].(*EchoChamberAgent)
+		seen := make(map[int]bool)
+		for i := 0; i < 50; i++ {
+			seen[int(a.RandomNeighbor().ID())] = true
+		}
+		var got []int
+		for id := range seen {
+			got = append(got, id)
+		}
+		if !sameIDs(got, tt.want) {
+			t.Errorf("agent %d met %v, want %v", tt.agent, got, tt.want)
+		}
+	}
+
+	alone := testModel(1, 1, 0.3, nil)
+	if o := (*alone.Landscape.GetAgents())[0].(*EchoChamberAgent).RandomNeighbor(); o != nil {
+		t.Errorf("a single agent met %d", o.ID())
+	}
+}
+
+func TestMovementRule(t *testing.T) {
+	for _, movement := range []bool{true, false} {
+		e := testPlaced(30, 10, 1, 3)
+		e.Ruleset.SetRule("movement", movement)
+		before := positions(e)
+		for _, b := range *e.Landscape.GetAgents() {
+			a := b.(*EchoChamberAgent)
+			a.PVeloc, a.Steplength = 1, 2
+			a.Act()
+		}
+		moved := 0
+		for i, p := range positions(e) {
+			if p != before[i] {
+				moved++
+			}
+		}
+		if movement && moved == 0 || !movement && moved > 0 {
+			t.Errorf("movement %v: %d of 30 agents moved", movement, moved)
+		}
+	}
+}
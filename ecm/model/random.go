@@ -0,0 +1,66 @@
+package model
+
+import "math"
+import "math/rand"
+
+// Source is a splitmix64 random source. Unlike the sources of math/rand its
+// state is a single exported word, so it can be saved in a checkpoint.
+type Source struct {
+	State uint64
+}
+
+func NewSource(seed int64) *Source {
+	return &Source{State: uint64(seed)}
+}
+
+func (s *Source) Seed(seed int64) {
+	s.State = uint64(seed)
+}
+
+func (s *Source) Uint64() uint64 {
+	s.State += 0x9e3779b97f4a7c15
+	z := s.State
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (s *Source) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+// Beta returns a PF which samples from the beta distribution B(α, β)
+func Beta(α, β float64) PF {
+	return func(r *rand.Rand) float64 {
+		x := Gamma(r, α)
+		y := Gamma(r, β)
+		return x / (x + y)
+	}
+}
+
+// Gamma samples from the gamma distribution with the given shape and scale 1,
+// after Marsaglia & Tsang (2000)
+func Gamma(r *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		// boost the shape and correct with a uniform power
+		return Gamma(r, shape+1) * math.Pow(r.Float64(), 1/shape)
+	}
+
+	d := shape - 1.0/3.0
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := r.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := r.Float64()
+		if u < 1-0.0331*x*x*x*x {
+			return d * v
+		}
+		if math.Log(u) < 0.5*x*x+d*(1-v+math.Log(v)) {
+			return d * v
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package model
+
+// Recommender suggests a blog to an agent, the platform driven counterpart
+// to searching (DiscoveryPolicy)
+type Recommender interface {
+	Recommend(a *EchoChamberAgent) *Blog
+}
+
+// collaborative filtering on the subscriptions of all agents: "agents who
+// follow what you follow also follow X". Every other agent votes for the
+// blogs we don't follow yet with the number of blogs we have in common.
+type CollaborativeFilter struct{}
+
+func (cf CollaborativeFilter) Recommend(a *EchoChamberAgent) *Blog {
+	mine := a.MySubscriptions.FollowedBlogs
+	if len(mine) == 0 {
+		// nothing to base a recommendation on
+		return nil
+	}
+
+	score := make(map[int]int)
+	blogs := make(map[int]*Blog)
+	for _, b := range *a.Model.Landscape.GetAgents() {
+		other := b.(*EchoChamberAgent)
+		if other == a {
+			continue
+		}
+
+		overlap := 0
+		for _, blog := range other.MySubscriptions.FollowedBlogs {
+			if a.MySubscriptions.Follows(blog) {
+				overlap++
+			}
+		}
+		if overlap == 0 {
+			continue
+		}
+
+		for _, blog := range other.MySubscriptions.FollowedBlogs {
+			if a.MySubscriptions.Follows(blog) {
+				continue
+			}
+			score[blog.ID] += overlap
+			blogs[blog.ID] = blog
+		}
+	}
+
+	// highest score wins, ties go to the lower blog id
+	var best *Blog
+	for id, s := range score {
+		if best == nil || s > score[best.ID] || (s == score[best.ID] && id < best.ID) {
+			best = blogs[id]
+		}
+	}
+	return best
+}
+
+// measures how echo chambers are reached: compares the subscriptions which
+// came from the recommender with the ones found by searching. echo holds
+// the echo chamber status per blog id.
+func (e *EchoChamberModel) RecommenderStatistics(echo map[int]bool) {
+	recommended, recommendedEC := 0, 0
+	searched, searchedEC := 0, 0
+
+	for _, b := range *e.Landscape.GetAgents() {
+		agent := b.(*EchoChamberAgent)
+		for _, blog := range agent.MySubscriptions.FollowedBlogs {
+			if agent.MySubscriptions.Recommended[blog.ID] {
+				recommended++
+				if echo[blog.ID] {
+					recommendedEC++
+				}
+			} else {
+				searched++
+				if echo[blog.ID] {
+					searchedEC++
+				}
+			}
+		}
+	}
+
+	e.RecommendedSubscriptions = recommended
+	e.RecommendedEchoChamberRatio = 0
+	if recommended > 0 {
+		e.RecommendedEchoChamberRatio = float64(recommendedEC) / float64(recommended)
+	}
+	e.SearchedEchoChamberRatio = 0
+	if searched > 0 {
+		e.SearchedEchoChamberRatio = float64(searchedEC) / float64(searched)
+	}
+}
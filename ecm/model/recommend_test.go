@@ -0,0 +1,74 @@
+package model
+
+import "testing"
+
+func TestCollaborativeFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		// the blogs every agent follows, agent 0 gets the recommendation
+		follows map[int][]int
+		// the recommended blog, -1 for none
+		want int
+	}{
+		{"most overlap", map[int][]int{0: {0, 1}, 6: {0, 1, 2}, 7: {0, 3}, 8: {4}}, 2},
+		// agent 6 votes 2 for blog 2, agents 7 and 8 1 each for blog 3
+		{"votes add up", map[int][]int{0: {0, 1}, 6: {0, 1, 2}, 7: {0, 3}, 8: {1, 3}}, 2},
+		{"more voters", map[int][]int{0: {0, 1}, 6: {0, 2}, 7: {0, 3}, 8: {1, 3}}, 3},
+		{"tie", map[int][]int{0: {0, 1}, 6: {0, 1, 3}, 7: {0, 1, 2}}, 2},
+		{"no overlap", map[int][]int{0: {0}, 6: {1, 2}}, -1},
+		{"following nothing", map[int][]int{6: {0, 1}}, -1},
+		{"following everything", map[int][]int{0: {0, 1, 2, 3, 4}, 6: {0, 1, 2}}, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := testModel(10, 7, 0.3, nil)
+			blogs := testBlogs(e, Feature{0, 0, 0, 0, 0}, Feature{0, 0, 0, 0, 1}, Feature{0, 0, 0, 1, 1},
+				Feature{0, 0, 1, 1, 1}, Feature{0, 1, 1, 1, 1})
+			agents := *e.Landscape.GetAgents()
+			for id, follows := range tt.follows {
+				for _, b := range follows {
+					agents[id].(*EchoChamberAgent).MySubscriptions.Subscribe(blogs[b])
+				}
+			}
+
+			got := CollaborativeFilter{}.Recommend(agents[0].(*EchoChamberAgent))
+			switch {
+			case tt.want < 0 && got != nil:
+				t.Errorf("recommended blog %d", got.ID)
+			case tt.want >= 0 && got != blogs[tt.want]:
+				t.Errorf("recommended %v, want blog %d", got, blogs[tt.want].ID)
+			}
+		})
+	}
+}
+
+func TestRecommenderStatistics(t *testing.T) {
+	e := testModel(10, 7, 0.3, nil)
+	blogs := testBlogs(e, Feature{0, 0, 0, 0, 0}, Feature{0, 0, 0, 0, 1}, Feature{0, 0, 0, 1, 1})
+	agents := *e.Landscape.GetAgents()
+	a, b := agents[0].(*EchoChamberAgent), agents[6].(*EchoChamberAgent)
+	a.MySubscriptions.SubscribeRecommended(blogs[0])
+	a.MySubscriptions.Subscribe(blogs[1])
+	b.MySubscriptions.SubscribeRecommended(blogs[2])
+	b.MySubscriptions.Subscribe(blogs[0])
+
+	e.RecommenderStatistics(map[int]bool{blogs[0].ID: true})
+	// recommended: 0 (echo chamber) and 2, searched: 1 and 0 (echo chamber)
+	if e.RecommendedSubscriptions != 2 {
+		t.Errorf("%d recommended subscriptions, want 2", e.RecommendedSubscriptions)
+	}
+	if e.RecommendedEchoChamberRatio != 0.5 || e.SearchedEchoChamberRatio != 0.5 {
+		t.Errorf("echo chamber ratios %g recommended, %g searched, want 0.5 each",
+			e.RecommendedEchoChamberRatio, e.SearchedEchoChamberRatio)
+	}
+
+	// a subscription found by searching again is no longer a recommended one
+	b.MySubscriptions.Unsubscribe(blogs[2])
+	b.MySubscriptions.Subscribe(blogs[2])
+	e.RecommenderStatistics(map[int]bool{blogs[0].ID: true})
+	// searched: 1, 0 (echo chamber) and 2
+	if e.RecommendedSubscriptions != 1 || e.RecommendedEchoChamberRatio != 1 || e.SearchedEchoChamberRatio != 1./3 {
+		t.Errorf("%d recommended subscriptions, ratios %g and %g, want 1, 1 and 1/3",
+			e.RecommendedSubscriptions, e.RecommendedEchoChamberRatio, e.SearchedEchoChamberRatio)
+	}
+}
@@ -0,0 +1,130 @@
+package model
+
+import "encoding/csv"
+import "encoding/json"
+import "fmt"
+import "io"
+import "reflect"
+import "strconv"
+import "sync"
+
+// a stat of the model at one step
+type Stat struct {
+	Name  string
+	Value float64
+}
+
+// all stats of the model which goabm shows, i.e. the numeric fields without
+// the goabm:"hide" tag, in declaration order
+func (e *EchoChamberModel) Stats() []Stat {
+	var stats []Stat
+	v := reflect.ValueOf(e).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous || f.PkgPath != "" || f.Tag.Get("goabm") == "hide" {
+			continue
+		}
+		fv := v.Field(i)
+		switch fv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			stats = append(stats, Stat{f.Name, float64(fv.Int())})
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			stats = append(stats, Stat{f.Name, float64(fv.Uint())})
+		case reflect.Float32, reflect.Float64:
+			stats = append(stats, Stat{f.Name, fv.Float()})
+		}
+	}
+	return stats
+}
+
+// Recorder writes the stats of the model after every step, it is called
+// from concurrent runs
+type Recorder interface {
+	Record(run string, m *EchoChamberModel) error
+	Flush() error
+}
+
+// one row per step: run, seed, step, stats...
+type CSVRecorder struct {
+	mu     sync.Mutex
+	w      *csv.Writer
+	header bool
+}
+
+func NewCSVRecorder(w io.Writer) *CSVRecorder {
+	return &CSVRecorder{w: csv.NewWriter(w)}
+}
+
+func (r *CSVRecorder) Record(run string, m *EchoChamberModel) error {
+	stats := m.Stats()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.header {
+		h := []string{"run", "seed", "step"}
+		for _, s := range stats {
+			h = append(h, s.Name)
+		}
+		if err := r.w.Write(h); err != nil {
+			return err
+		}
+		r.header = true
+	}
+
+	row := []string{run, strconv.FormatInt(m.Seed, 10), strconv.Itoa(m.Step)}
+	for _, s := range stats {
+		row = append(row, strconv.FormatFloat(s.Value, 'g', -1, 64))
+	}
+	return r.w.Write(row)
+}
+
+func (r *CSVRecorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.w.Flush()
+	return r.w.Error()
+}
+
+// one json object per step
+type JSONLRecorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewJSONLRecorder(w io.Writer) *JSONLRecorder {
+	return &JSONLRecorder{enc: json.NewEncoder(w)}
+}
+
+type jsonlRow struct {
+	Run   string
+	Seed  int64
+	Step  int
+	Stats map[string]float64
+}
+
+func (r *JSONLRecorder) Record(run string, m *EchoChamberModel) error {
+	row := jsonlRow{Run: run, Seed: m.Seed, Step: m.Step, Stats: make(map[string]float64)}
+	for _, s := range m.Stats() {
+		row.Stats[s.Name] = s.Value
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enc.Encode(row)
+}
+
+func (r *JSONLRecorder) Flush() error {
+	return nil
+}
+
+// returns a recorder for the format "csv" or "jsonl"
+func NewRecorder(format string, w io.Writer) (Recorder, error) {
+	switch format {
+	case "csv":
+		return NewCSVRecorder(w), nil
+	case "jsonl":
+		return NewJSONLRecorder(w), nil
+	}
+	return nil, fmt.Errorf("unknown recorder format: %s", format)
+}
@@ -0,0 +1,114 @@
+package model
+
+import "bytes"
+import "encoding/csv"
+import "encoding/json"
+import "strconv"
+import "sync"
+import "testing"
+
+func TestStats(t *testing.T) {
+	e := testModel(10, 7, 0.3, nil)
+	e.Cultures, e.EchoChamberRatio, e.NTraits = 4, 0.25, 3
+	stats := make(map[string]float64)
+	var names []string
+	for _, s := range e.Stats() {
+		stats[s.Name] = s.Value
+		names = append(names, s.Name)
+	}
+	if stats["Cultures"] != 4 || stats["EchoChamberRatio"] != 0.25 {
+		t.Errorf("stats %v", stats)
+	}
+	for _, hidden := range []string{"NTraits", "Step", "Seed", "PVeloc", "Sight"} {
+		if _, ok := stats[hidden]; ok {
+			t.Errorf("hidden %s among the stats", hidden)
+		}
+	}
+	// in declaration order
+	if names[0] != "Cultures" || names[1] != "OnlineInteraction" {
+		t.Errorf("stats start with %v", names[:2])
+	}
+}
+
+// records steps 1 to 3 of two runs from concurrent goroutines
+func record(t *testing.T, r Recorder) {
+	var wg sync.WaitGroup
+	for _, run := range []string{"run-1", "run-2"} {
+		wg.Add(1)
+		go func(run string) {
+			defer wg.Done()
+			e := testModel(10, 7, 0.3, nil)
+			for step := 1; step <= 3; step++ {
+				e.Step, e.Cultures = step, 10*step
+				if err := r.Record(run, e); err != nil {
+					t.Error(err)
+				}
+			}
+		}(run)
+	}
+	wg.Wait()
+	if err := r.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCSVRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	r, err := NewRecorder("csv", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record(t, r)
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 7 {
+		t.Fatalf("%d rows, want a header and 6", len(rows))
+	}
+	header := rows[0]
+	if header[0] != "run" || header[1] != "seed" || header[2] != "step" || header[3] != "Cultures" {
+		t.Errorf("header %v", header)
+	}
+	for _, row := range rows[1:] {
+		if len(row) != len(header) {
+			t.Fatalf("row %v has %d columns, the header %d", row, len(row), len(header))
+		}
+		step, _ := strconv.Atoi(row[2])
+		if row[1] != "7" || row[3] != strconv.Itoa(10*step) {
+			t.Errorf("row %v", row)
+		}
+	}
+}
+
+func TestJSONLRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	r, err := NewRecorder("jsonl", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record(t, r)
+
+	dec := json.NewDecoder(&buf)
+	steps := make(map[string]int)
+	for dec.More() {
+		var row jsonlRow
+		if err := dec.Decode(&row); err != nil {
+			t.Fatal(err)
+		}
+		if row.Seed != 7 || row.Stats["Cultures"] != float64(10*row.Step) {
+			t.Errorf("row %+v", row)
+		}
+		steps[row.Run]++
+	}
+	if steps["run-1"] != 3 || steps["run-2"] != 3 {
+		t.Errorf("steps per run %v, want 3 each", steps)
+	}
+}
+
+func TestNewRecorderFormat(t *testing.T) {
+	if _, err := NewRecorder("xml", &bytes.Buffer{}); err == nil {
+		t.Errorf("a recorder of an unknown format")
+	}
+}
@@ -0,0 +1,148 @@
+package model
+
+import "goabm"
+import "fmt"
+import "sort"
+
+// cultural regions: groups of agents with identical features which are
+// connected through neighbors within Sight on the physical landscape.
+// Agents without a position belong to no region. Returns the size of every
+// region, largest first.
+func (e *EchoChamberModel) CulturalRegions() []int {
+	n := e.neighbors()
+	cultures := make([]string, len(n.agents))
+	for i, a := range n.agents {
+		cultures[i] = a.Culture()
+	}
+
+	// union find over the agents
+	parent := make([]int, len(n.agents))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range n.agents {
+		n.neighbors(i, func(j int) {
+			if cultures[i] == cultures[j] {
+				parent[find(i)] = find(j)
+			}
+		})
+	}
+
+	size := make(map[int]int)
+	for i := range n.agents {
+		size[find(i)]++
+	}
+	regions := make([]int, 0, len(size))
+	for _, s := range size {
+		regions = append(regions, s)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(regions)))
+	return regions
+}
+
+// the located agents, computed again once an agent moved
+func (e *EchoChamberModel) neighbors() *neighborhood {
+	if e.located == nil {
+		e.located = e.locate()
+	}
+	return e.located
+}
+
+// number of regions per region size
+func RegionSizeDistribution(regions []int) map[int]int {
+	d := make(map[int]int)
+	for _, s := range regions {
+		d[s]++
+	}
+	return d
+}
+
+// the Axelrod order parameters: number of regions and the size of the
+// largest region relative to the number of agents with a position
+func (e *EchoChamberModel) RegionStatistics() {
+	regions := e.CulturalRegions()
+	e.Regions = len(regions)
+	e.LargestRegion = 0
+	if n := len(e.neighbors().agents); n > 0 {
+		e.LargestRegion = float64(regions[0]) / float64(n)
+	}
+}
+
+// the sight of the agents, the landscape's if the model has none
+func (e *EchoChamberModel) sight() float64 {
+	if l, ok := e.Landscape.(*goabm.FixedLandscapeWithMovement); ok && e.Sight == 0 {
+		return l.Sight
+	}
+	return e.Sight
+}
+
+// whether the model is in an absorbing state, no interaction can change a
+// feature (see FeatureInteraction): every two neighbors within Sight on the
+// physical landscape and every reader and what it can read online have the
+// same features or a similarity of 0. Transmission errors change features
+// anyway, with that rule the model is never absorbing. A landscape without
+// located agents is not absorbing.
+func (e *EchoChamberModel) Absorbing() bool {
+	if e.IsRuleActive("transmission_error") {
+		return false
+	}
+	n := e.neighbors()
+	if len(n.agents) == 0 {
+		return false
+	}
+	absorbing := true
+	for i, a := range n.agents {
+		n.neighbors(i, func(j int) {
+			other := n.agents[j].Features
+			if absorbing && !a.Features.Equal(other) && a.Similarity(other) != 0 {
+				absorbing = false
+			}
+		})
+		if !absorbing {
+			return false
+		}
+	}
+	return e.absorbingOnline()
+}
+
+// whether reading blogs can change no feature. A reader (an agent who goes
+// online) may follow any blog later, so it is checked against every post and
+// comment written so far and against every other reader, whose features are
+// the messages still to come.
+func (e *EchoChamberModel) absorbingOnline() bool {
+	// distinct features suffice, near an absorbing state there are few
+	readers := make(map[string]Feature)
+	for _, b := range *e.Landscape.GetAgents() {
+		if a := b.(*EchoChamberAgent); a.POnline > 0 {
+			readers[a.Culture()] = a.Features
+		}
+	}
+	messages := make(map[string]Feature)
+	for k, f := range readers {
+		messages[k] = f
+	}
+	for _, blog := range e.Blogger {
+		for _, p := range blog.Posts {
+			messages[fmt.Sprintf("%v", p.Message)] = p.Message
+			for _, c := range p.Thread() {
+				messages[fmt.Sprintf("%v", c.Message)] = c.Message
+			}
+		}
+	}
+	for _, r := range readers {
+		for _, m := range messages {
+			if !r.Equal(m) && e.Similarity(r, m) != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
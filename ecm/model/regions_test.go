@@ -0,0 +1,133 @@
+package model
+
+import "testing"
+
+func TestAbsorbing(t *testing.T) {
+	same, other := Feature{0, 0, 0, 0, 0}, Feature{1, 1, 1, 1, 1}
+	// similar to both, some features of either
+	between := Feature{0, 0, 1, 1, 1}
+
+	tests := []struct {
+		name string
+		// the features of the agent, every second agent if alternating
+		features     func(i int) Feature
+		pOnline      float64
+		message      Feature
+		transmission bool
+		want         bool
+	}{
+		{"one culture", func(i int) Feature { return same }, 0.8, nil, false, true},
+		{"two cultures without overlap", func(i int) Feature {
+			if i%2 == 0 {
+				return same
+			}
+			return other
+		}, 0.8, nil, false, true},
+		{"similar neighbors", func(i int) Feature {
+			if i == 4 {
+				return between
+			}
+			return same
+		}, 0, nil, false, false},
+		// agent 9 has no neighbor within sight, but reads
+		{"similar reader", func(i int) Feature {
+			if i == 9 {
+				return between
+			}
+			return same
+		}, 0.8, nil, false, false},
+		{"similar reader offline", func(i int) Feature {
+			if i == 9 {
+				return between
+			}
+			return same
+		}, 0, nil, false, true},
+		{"similar post", func(i int) Feature { return same }, 0.8, between, false, false},
+		{"post without overlap", func(i int) Feature { return same }, 0.8, other, false, true},
+		{"transmission error", func(i int) Feature { return same }, 0.8, nil, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := testModel(10, 7, 0.3, nil)
+			agents := *e.Landscape.GetAgents()
+			// agent 9 stands apart
+			agents[9].(*EchoChamberAgent).X = 20
+			for i, b := range agents {
+				a := b.(*EchoChamberAgent)
+				a.Features = append(Feature(nil), tt.features(i)...)
+				a.POnline = tt.pOnline
+			}
+			if tt.message != nil {
+				blog := e.CreateBlog(agents[0].(*EchoChamberAgent))
+				blog.Posts = append(blog.Posts, &Comment{Message: same,
+					Responses: []*Comment{{Message: tt.message, Author: 1}}})
+			}
+			e.Ruleset.SetRule("transmission_error", tt.transmission)
+
+			if got := e.Absorbing(); got != tt.want {
+				t.Errorf("absorbing %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCulturalRegions(t *testing.T) {
+	a, b, c := Feature{0, 0, 0, 0, 0}, Feature{1, 1, 1, 1, 1}, Feature{2, 2, 2, 2, 2}
+	tests := []struct {
+		name string
+		// the cultures of a 3x3 grid, row by row
+		grid    []Feature
+		regions []int
+	}{
+		{"one culture", []Feature{a, a, a, a, a, a, a, a, a}, []int{9}},
+		{"three regions", []Feature{
+			a, a, b,
+			a, b, b,
+			c, c, b}, []int{4, 3, 2}},
+		// diagonals are out of sight
+		{"checkerboard", []Feature{
+			a, b, a,
+			b, a, b,
+			a, b, a}, []int{1, 1, 1, 1, 1, 1, 1, 1, 1}},
+		// the same culture in two places are two regions
+		{"apart", []Feature{
+			a, a, b,
+			a, b, b,
+			c, c, a}, []int{3, 3, 2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := testModel(9, 7, 0.3, nil)
+			e.Sight = 1
+			for i, b := range *e.Landscape.GetAgents() {
+				agent := b.(*EchoChamberAgent)
+				agent.X, agent.Y = float64(i%3), float64(i/3)
+				agent.Features = append(Feature(nil), tt.grid[i]...)
+			}
+			e.located = nil
+
+			regions := e.CulturalRegions()
+			if len(regions) != len(tt.regions) {
+				t.Fatalf("regions %v, want %v", regions, tt.regions)
+			}
+			for i := range regions {
+				if regions[i] != tt.regions[i] {
+					t.Fatalf("regions %v, want %v", regions, tt.regions)
+				}
+			}
+
+			e.RegionStatistics()
+			if e.Regions != len(tt.regions) || e.LargestRegion != float64(tt.regions[0])/9 {
+				t.Errorf("%d regions, the largest %g, want %d and %g",
+					e.Regions, e.LargestRegion, len(tt.regions), float64(tt.regions[0])/9)
+			}
+			sizes := 0
+			for size, n := range RegionSizeDistribution(regions) {
+				sizes += size * n
+			}
+			if sizes != 9 {
+				t.Errorf("the size distribution covers %d agents, want 9", sizes)
+			}
+		})
+	}
+}
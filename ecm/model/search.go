@@ -0,0 +1,198 @@
+package model
+
+import "container/heap"
+import "sort"
+
+// BlogIndex keeps the trait vector of the latest post of every blog, so a
+// search does not have to walk all blogs and their posts. Blogs register
+// themselves on Publish.
+type BlogIndex struct {
+	blogs  []*Blog
+	latest []Feature
+	pos    map[int]int // blog id -> position in blogs/latest
+	// positions ordered by blog id, for the ties of blogs without overlap
+	byID []int
+
+	// inverted index: feature -> trait -> positions of the blogs whose
+	// latest post carries this trait
+	postings []map[int]map[int]bool
+}
+
+type SearchResult struct {
+	Blog  *Blog
+	Score float64
+}
+
+func NewBlogIndex() *BlogIndex {
+	return &BlogIndex{pos: make(map[int]int)}
+}
+
+// number of searchable blogs
+func (ix *BlogIndex) Len() int {
+	return len(ix.blogs)
+}
+
+// Update reindexes the blog with its latest post
+func (ix *BlogIndex) Update(b *Blog) {
+	if len(b.Posts) == 0 {
+		return
+	}
+	f := b.Posts[len(b.Posts)-1].Message
+
+	p, ok := ix.pos[b.ID]
+	if !ok {
+		p = len(ix.blogs)
+		ix.pos[b.ID] = p
+		ix.blogs = append(ix.blogs, b)
+		ix.latest = append(ix.latest, nil)
+		i := sort.Search(len(ix.byID), func(i int) bool { return ix.blogs[ix.byID[i]].ID > b.ID })
+		ix.byID = append(ix.byID, 0)
+		copy(ix.byID[i+1:], ix.byID[i:])
+		ix.byID[i] = p
+	}
+
+	if ix.postings == nil {
+		ix.postings = make([]map[int]map[int]bool, len(f))
+		for i := range ix.postings {
+			ix.postings[i] = make(map[int]map[int]bool)
+		}
+	}
+
+	// remove the old vector from the inverted index
+	if old := ix.latest[p]; old != nil {
+		for i, t := range old {
+			delete(ix.postings[i][t], p)
+		}
+	}
+
+	for i, t := range f {
+		if ix.postings[i][t] == nil {
+			ix.postings[i][t] = make(map[int]bool)
+		}
+		ix.postings[i][t][p] = true
+	}
+	ix.latest[p] = f
+}
+
+// Search returns the k blogs whose latest post is most similar to f. Ties are
+// broken by the lower blog id, blogs for which skip returns true are left out.
+// Overlap based metrics only visit the blogs sharing a trait with f, other
+// metrics compare f with every blog.
+func (ix *BlogIndex) Search(f Feature, k int, m SimilarityMetric, skip func(*Blog) bool) []SearchResult {
+	if k <= 0 || len(ix.blogs) == 0 {
+		return nil
+	}
+
+	h := &resultHeap{}
+	offer := func(r SearchResult) {
+		if h.Len() < k {
+			heap.Push(h, r)
+		} else if better(r, (*h)[0]) {
+			(*h)[0] = r
+			heap.Fix(h, 0)
+		}
+	}
+
+	if scores, ok := ix.overlap(f, m); ok {
+		for p, score := range scores {
+			if b := ix.blogs[p]; skip == nil || !skip(b) {
+				offer(SearchResult{Blog: b, Score: score})
+			}
+		}
+		// the blogs without a shared trait score 0, the lowest ids first
+		for _, p := range ix.byID {
+			if _, ok := scores[p]; ok {
+				continue
+			}
+			r := SearchResult{Blog: ix.blogs[p]}
+			if h.Len() == k && !better(r, (*h)[0]) {
+				break
+			}
+			if skip == nil || !skip(r.Blog) {
+				offer(r)
+			}
+		}
+	} else {
+		for p, b := range ix.blogs {
+			if skip == nil || !skip(b) {
+				offer(SearchResult{Blog: b, Score: m.Similarity(f, ix.latest[p])})
+			}
+		}
+	}
+
+	res := make([]SearchResult, h.Len())
+	for i := len(res) - 1; i >= 0; i-- {
+		res[i] = heap.Pop(h).(SearchResult)
+	}
+	return res
+}
+
+// similarity of f to the latest post of the blogs sharing a trait with it,
+// by position. Only overlap based metrics can be computed from the inverted
+// index, ok is false for the others.
+func (ix *BlogIndex) overlap(f Feature, m SimilarityMetric) (scores map[int]float64, ok bool) {
+	var weight func(i int) float64
+	switch w := m.(type) {
+	case ExactMatch, Jaccard:
+		weight = func(i int) float64 { return 1 }
+	case WeightedOverlap:
+		weight = func(i int) float64 {
+			if i < len(w.Salience) {
+				return w.Salience[i]
+			}
+			return 1
+		}
+	default:
+		return nil, false
+	}
+
+	scores = make(map[int]float64)
+	total := 0.0
+	for i, t := range f {
+		wi := weight(i)
+		total += wi
+		if i >= len(ix.postings) {
+			continue
+		}
+		for p := range ix.postings[i][t] {
+			scores[p] += wi
+		}
+	}
+
+	for p, c := range scores {
+		switch m.(type) {
+		case Jaccard:
+			if u := 2*total - c; u > 0 {
+				scores[p] = c / u
+			}
+		default:
+			if total > 0 {
+				scores[p] = c / total
+			}
+		}
+	}
+	return scores, true
+}
+
+// ranking order of search results
+func better(a, b SearchResult) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.Blog.ID < b.Blog.ID
+}
+
+// min-heap with the worst result on top
+type resultHeap []SearchResult
+
+func (h resultHeap) Len() int            { return len(h) }
+func (h resultHeap) Less(i, j int) bool  { return better(h[j], h[i]) }
+func (h resultHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *resultHeap) Push(x interface{}) { *h = append(*h, x.(SearchResult)) }
+func (h *resultHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
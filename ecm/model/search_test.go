@@ -0,0 +1,113 @@
+package model
+
+import "math/rand"
+import "sort"
+import "testing"
+
+// an index of n blogs with random latest posts
+func testIndex(r *rand.Rand, n, features, traits int) (*BlogIndex, []*Blog) {
+	ix := NewBlogIndex()
+	blogs := make([]*Blog, n)
+	// blogs publish in a different order than their ids
+	for _, id := range r.Perm(n) {
+		blogs[id] = &Blog{ID: id, index: ix}
+		blogs[id].Publish(0, randomFeature(r, features, traits))
+	}
+	return ix, blogs
+}
+
+func randomFeature(r *rand.Rand, features, traits int) Feature {
+	f := make(Feature, features)
+	for i := range f {
+		f[i] = r.Intn(traits)
+	}
+	return f
+}
+
+// the linear scan the index replaces: rank every blog on its latest post
+func scan(blogs []*Blog, f Feature, k int, m SimilarityMetric, skip func(*Blog) bool) []SearchResult {
+	var res []SearchResult
+	for _, b := range blogs {
+		if skip != nil && skip(b) {
+			continue
+		}
+		res = append(res, SearchResult{Blog: b, Score: m.Similarity(f, b.Posts[len(b.Posts)-1].Message)})
+	}
+	sort.Slice(res, func(i, j int) bool { return better(res[i], res[j]) })
+	if len(res) > k {
+		res = res[:k]
+	}
+	return res
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		name             string
+		metric           SimilarityMetric
+		n, traits, k     int
+		skipOddBlogs     bool
+		republishedBlogs int
+	}{
+		{"exact", ExactMatch{}, 200, 30, 5, false, 0},
+		{"exact few traits", ExactMatch{}, 200, 2, 10, false, 0},
+		{"exact no overlap", ExactMatch{}, 50, 1000, 3, false, 0},
+		{"exact skip", ExactMatch{}, 200, 30, 5, true, 0},
+		{"exact republished", ExactMatch{}, 200, 5, 5, false, 50},
+		{"jaccard", Jaccard{}, 200, 5, 5, false, 0},
+		{"weighted", WeightedOverlap{Salience: []float64{0, 0, 3, 1, 0.5}}, 200, 3, 8, false, 0},
+		{"ordinal", OrdinalDistance{NTraits: 30}, 200, 30, 5, true, 0},
+		{"more than blogs", ExactMatch{}, 10, 30, 20, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(1))
+			ix, blogs := testIndex(r, tt.n, 10, tt.traits)
+			for i := 0; i < tt.republishedBlogs; i++ {
+				blogs[r.Intn(len(blogs))].Publish(1, randomFeature(r, 10, tt.traits))
+			}
+			var skip func(*Blog) bool
+			if tt.skipOddBlogs {
+				skip = func(b *Blog) bool { return b.ID%2 == 1 }
+			}
+			for q := 0; q < 20; q++ {
+				f := randomFeature(r, 10, tt.traits)
+				got := ix.Search(f, tt.k, tt.metric, skip)
+				want := scan(blogs, f, tt.k, tt.metric, skip)
+				if len(got) != len(want) {
+					t.Fatalf("query %d: %d results, want %d", q, len(got), len(want))
+				}
+				for i := range want {
+					if got[i].Blog != want[i].Blog || !near(got[i].Score, want[i].Score) {
+						t.Fatalf("query %d, result %d: blog %d (%g), want blog %d (%g)", q, i,
+							got[i].Blog.ID, got[i].Score, want[i].Blog.ID, want[i].Score)
+					}
+				}
+			}
+		})
+	}
+}
+
+func near(a, b float64) bool {
+	d := a - b
+	return d < 1e-12 && d > -1e-12
+}
+
+func BenchmarkSearch(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	ix, _ := testIndex(r, 500, 30, 30)
+	f := randomFeature(r, 30, 30)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ix.Search(f, 1, ExactMatch{}, nil)
+	}
+}
+
+func BenchmarkScan(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	_, blogs := testIndex(r, 500, 30, 30)
+	f := randomFeature(r, 30, 30)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		scan(blogs, f, 1, ExactMatch{}, nil)
+	}
+}
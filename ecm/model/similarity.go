@@ -0,0 +1,76 @@
+package model
+
+import "math"
+
+// SimilarityMetric defines what cultural closeness means in the model. The
+// result is expected in [0,1], where 1 means the two cultures are identical.
+type SimilarityMetric interface {
+	Similarity(first, other Feature) float64
+}
+
+// share of traits which are exactly the same, the classic axelrod overlap
+type ExactMatch struct{}
+
+func (m ExactMatch) Similarity(first, other Feature) float64 {
+	return Similarity(first, other)
+}
+
+// overlap where every feature counts according to its salience, features
+// without a salience value are weighted with 1. If no feature has any
+// weight, every feature counts alike.
+type WeightedOverlap struct {
+	Salience []float64
+}
+
+func (m WeightedOverlap) Similarity(first, other Feature) float64 {
+	c := 0.0
+	total := 0.0
+	for i := range first {
+		w := 1.0
+		if i < len(m.Salience) {
+			w = m.Salience[i]
+		}
+		total += w
+		if first[i] == other[i] {
+			c += w
+		}
+	}
+	if total == 0 {
+		return Similarity(first, other)
+	}
+	return c / total
+}
+
+// treats the traits as numbered (ordinal) values, trait 3 is closer to 4 than to 20
+type OrdinalDistance struct {
+	NTraits int
+}
+
+func (m OrdinalDistance) Similarity(first, other Feature) float64 {
+	if m.NTraits < 2 {
+		return Similarity(first, other)
+	}
+	d := 0.0
+	for i := range first {
+		d += math.Abs(float64(first[i] - other[i]))
+	}
+	maxd := float64(len(first) * (m.NTraits - 1))
+	return 1.0 - d/maxd
+}
+
+// jaccard index of the two sets of (feature, trait) pairs
+type Jaccard struct{}
+
+func (m Jaccard) Similarity(first, other Feature) float64 {
+	shared := 0
+	for i := range first {
+		if first[i] == other[i] {
+			shared++
+		}
+	}
+	union := 2*len(first) - shared
+	if union == 0 {
+		return 1
+	}
+	return float64(shared) / float64(union)
+}
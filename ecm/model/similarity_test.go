@@ -0,0 +1,60 @@
+package model
+
+import "testing"
+
+func TestSimilarityMetrics(t *testing.T) {
+	tests := []struct {
+		name         string
+		metric       SimilarityMetric
+		first, other Feature
+		want         float64
+	}{
+		{"exact identical", ExactMatch{}, Feature{1, 2, 3}, Feature{1, 2, 3}, 1},
+		{"exact one of three", ExactMatch{}, Feature{1, 2, 3}, Feature{1, 0, 0}, 1.0 / 3},
+		{"exact disjoint", ExactMatch{}, Feature{1, 2, 3}, Feature{0, 0, 0}, 0},
+
+		{"weighted salient feature", WeightedOverlap{Salience: []float64{2, 1, 1}}, Feature{1, 2, 3}, Feature{1, 0, 0}, 0.5},
+		{"weighted missing salience", WeightedOverlap{Salience: []float64{3}}, Feature{1, 2}, Feature{1, 0}, 0.75},
+		{"weighted no salience", WeightedOverlap{}, Feature{1, 2, 3}, Feature{1, 2, 0}, 2.0 / 3},
+		{"weighted zero salience", WeightedOverlap{Salience: []float64{0, 0}}, Feature{1, 2}, Feature{1, 2}, 1},
+		{"weighted zero salience overlap", WeightedOverlap{Salience: []float64{0, 0}}, Feature{1, 2}, Feature{1, 0}, 0.5},
+
+		{"ordinal identical", OrdinalDistance{NTraits: 5}, Feature{0, 4}, Feature{0, 4}, 1},
+		{"ordinal neighbor trait", OrdinalDistance{NTraits: 5}, Feature{0, 0}, Feature{1, 0}, 0.875},
+		{"ordinal farthest", OrdinalDistance{NTraits: 5}, Feature{0, 0}, Feature{4, 4}, 0},
+		{"ordinal single trait", OrdinalDistance{NTraits: 1}, Feature{0, 0}, Feature{0, 0}, 1},
+
+		{"jaccard identical", Jaccard{}, Feature{1, 2, 3}, Feature{1, 2, 3}, 1},
+		{"jaccard one of three", Jaccard{}, Feature{1, 2, 3}, Feature{1, 0, 0}, 0.2},
+		{"jaccard disjoint", Jaccard{}, Feature{1, 2}, Feature{0, 0}, 0},
+		{"jaccard empty", Jaccard{}, Feature{}, Feature{}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.metric.Similarity(tt.first, tt.other)
+			if !near(got, tt.want) {
+				t.Errorf("Similarity(%v, %v) = %g, want %g", tt.first, tt.other, got, tt.want)
+			}
+			if back := tt.metric.Similarity(tt.other, tt.first); !near(back, got) {
+				t.Errorf("not symmetric: %g and %g", got, back)
+			}
+		})
+	}
+}
+
+func TestFeatureEqual(t *testing.T) {
+	tests := []struct {
+		f, other Feature
+		want     bool
+	}{
+		{Feature{1, 2}, Feature{1, 2}, true},
+		{Feature{1, 2}, Feature{2, 1}, false},
+		{Feature{1, 2}, Feature{1, 2, 3}, false},
+		{Feature{}, Feature{}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.f.Equal(tt.other); got != tt.want {
+			t.Errorf("%v.Equal(%v) = %v, want %v", tt.f, tt.other, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package model
+
+import "goabm"
+
+// all replies to the comment, depth first in the order they were written
+func (c *Comment) Thread() []*Comment {
+	var thread []*Comment
+	for _, r := range c.Responses {
+		thread = append(thread, r)
+		thread = append(thread, r.Thread()...)
+	}
+	return thread
+}
+
+// length of the longest reply chain below the comment, 0 without replies
+func (c *Comment) Depth() int {
+	d := 0
+	for _, r := range c.Responses {
+		if rd := r.Depth() + 1; rd > d {
+			d = rd
+		}
+	}
+	return d
+}
+
+// who replies to whom: author of a comment -> author of the comment it
+// replies to -> number of replies
+func (e *EchoChamberModel) ReplyNetwork() map[goabm.AgentID]map[goabm.AgentID]int {
+	net := make(map[goabm.AgentID]map[goabm.AgentID]int)
+	for _, blog := range e.Blogger {
+		for _, p := range blog.Posts {
+			for _, c := range p.Thread() {
+				if net[c.Author] == nil {
+					net[c.Author] = make(map[goabm.AgentID]int)
+				}
+				net[c.Author][c.Parent.Author]++
+			}
+		}
+	}
+	return net
+}
+
+// conversation depth over all commented blog posts
+func (e *EchoChamberModel) ThreadStatistics() {
+	max := 0
+	total := 0
+	threads := 0
+	for _, blog := range e.Blogger {
+		for _, p := range blog.Posts {
+			if len(p.Responses) == 0 {
+				continue
+			}
+			d := p.Depth()
+			if d > max {
+				max = d
+			}
+			total += d
+			threads++
+		}
+	}
+
+	e.MaxThreadDepth = max
+	e.AvgThreadDepth = 0
+	if threads > 0 {
+		e.AvgThreadDepth = float64(total) / float64(threads)
+	}
+}
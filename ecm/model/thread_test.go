@@ -0,0 +1,101 @@
+package model
+
+import "goabm"
+import "testing"
+
+func TestThread(t *testing.T) {
+	e := testModel(10, 7, 0.3, nil)
+	blogs := testBlogs(e, Feature{0, 0, 0, 0, 0})
+	post := blogs[0].Posts[0]
+	f := Feature{1, 1, 1, 1, 1}
+	c1 := post.Respond(2, 1, f)
+	c2 := c1.Respond(3, 1, f)
+	c3 := post.Respond(4, 2, f)
+	c4 := c2.Respond(2, 2, f)
+
+	// a reply keeps the message it was written with
+	f[0] = 2
+	if c1.Message[0] != 1 {
+		t.Errorf("the reply changed with its author: %v", c1.Message)
+	}
+
+	thread := post.Thread()
+	want := []*Comment{c1, c2, c4, c3}
+	if len(thread) != len(want) {
+		t.Fatalf("%d comments in the thread, want %d", len(thread), len(want))
+	}
+	for i := range want {
+		if thread[i] != want[i] {
+			t.Errorf("comment %d of the thread is by %d at step %d", i, thread[i].Author, thread[i].Step)
+		}
+	}
+	if d := post.Depth(); d != 3 {
+		t.Errorf("depth %d, want 3", d)
+	}
+	if d := c3.Depth(); d != 0 {
+		t.Errorf("depth of a comment without replies %d, want 0", d)
+	}
+
+	net := e.ReplyNetwork()
+	replies := map[[2]goabm.AgentID]int{{2, 1}: 1, {3, 2}: 1, {4, 1}: 1, {2, 3}: 1}
+	n := 0
+	for from, to := range net {
+		for id, count := range to {
+			n++
+			if replies[[2]goabm.AgentID{from, id}] != count {
+				t.Errorf("%d replies of %d to %d", count, from, id)
+			}
+		}
+	}
+	if n != len(replies) {
+		t.Errorf("%d pairs in the reply network, want %d", n, len(replies))
+	}
+
+	// a post with a single reply and one without
+	blogs[0].Publish(3, Feature{0, 0, 0, 0, 0})
+	blogs[0].Posts[1].Respond(5, 3, f)
+	blogs[0].Publish(4, Feature{0, 0, 0, 0, 0})
+	e.ThreadStatistics()
+	if e.MaxThreadDepth != 3 || e.AvgThreadDepth != 2 {
+		t.Errorf("max depth %d, average %g, want 3 and 2", e.MaxThreadDepth, e.AvgThreadDepth)
+	}
+}
+
+func TestThreadedReplies(t *testing.T) {
+	for _, threaded := range []bool{false, true} {
+		e := testModel(10, 7, 0.3, nil)
+		e.Ruleset.SetRule("threaded_replies", threaded)
+		same := Feature{0, 0, 0, 0, 0}
+		blog := testBlogs(e, same)[0]
+		a := (*e.Landscape.GetAgents())[0].(*EchoChamberAgent)
+		a.Features = append(Feature(nil), same...)
+		a.PRespondBlogPost = 1
+		a.MySubscriptions.Subscribe(blog)
+
+		toComments := 0
+		for i := 0; i < 50; i++ {
+			blog.Publish(i, same)
+			post := blog.Posts[len(blog.Posts)-1]
+			for j := 0; j < 4; j++ {
+				post.Respond(goabm.AgentID(2+j), i, same)
+			}
+			a.ReadBlogs()
+
+			var reply *Comment
+			for _, c := range post.Thread() {
+				if c.Author == a.ID() {
+					reply = c
+				}
+			}
+			if reply == nil {
+				t.Fatalf("threaded %v: no reply to post %d", threaded, i)
+			}
+			if reply.Parent != post {
+				toComments++
+			}
+		}
+		if threaded && toComments == 0 || !threaded && toComments > 0 {
+			t.Errorf("threaded %v: %d of 50 replies to comments", threaded, toComments)
+		}
+	}
+}
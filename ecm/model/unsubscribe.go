@@ -0,0 +1,54 @@
+package model
+
+// UnsubscribePolicy decides when an agent drops a blog it follows
+type UnsubscribePolicy interface {
+	// probability to review the subscriptions when reading blogs
+	CheckRate() float64
+	// returns true if the agent should unsubscribe from the blog
+	Drop(a *EchoChamberAgent, b *Blog) bool
+}
+
+// the comfort zone of an agent is given by its RSimilarityConfortLevel. A
+// blog below the lower bound is too different; with TwoSided a blog above
+// the upper bound is too similar and the agent gets bored.
+type ComfortZone struct {
+	Rate float64
+	// similarity is averaged over the last Window posts, 1 only looks at the last post
+	Window   int
+	TwoSided bool
+}
+
+// the original behaviour: check with p=0.4, last post, lower bound only
+func DefaultUnsubscribePolicy() UnsubscribePolicy {
+	return ComfortZone{Rate: 0.4, Window: 1}
+}
+
+func (cz ComfortZone) CheckRate() float64 {
+	return cz.Rate
+}
+
+func (cz ComfortZone) Drop(a *EchoChamberAgent, b *Blog) bool {
+	if len(b.Posts) == 0 {
+		return false
+	}
+
+	n := cz.Window
+	if n < 1 {
+		n = 1
+	}
+	if n > len(b.Posts) {
+		n = len(b.Posts)
+	}
+
+	sim := 0.0
+	for _, p := range b.Posts[len(b.Posts)-n:] {
+		sim += a.Similarity(p.Message)
+	}
+	sim /= float64(n)
+
+	cl := a.RSimilarityConfortLevel
+	if sim <= cl[0] {
+		return true
+	}
+	return cz.TwoSided && sim > cl[1]
+}
@@ -0,0 +1,68 @@
+package model
+
+import "testing"
+
+func TestComfortZone(t *testing.T) {
+	// similarities to the agent's 00000
+	s1, s06, s02 := Feature{0, 0, 0, 0, 0}, Feature{0, 0, 0, 1, 1}, Feature{0, 1, 1, 1, 1}
+
+	tests := []struct {
+		name    string
+		policy  ComfortZone
+		comfort FloatRange
+		posts   []Feature
+		want    bool
+	}{
+		{"no posts", ComfortZone{Window: 1}, FloatRange{0.2, 0.9}, nil, false},
+		{"inside", ComfortZone{Window: 1}, FloatRange{0.2, 0.9}, []Feature{s06}, false},
+		{"at the lower bound", ComfortZone{Window: 1}, FloatRange{0.2, 0.9}, []Feature{s02}, true},
+		{"above the upper bound", ComfortZone{Window: 1}, FloatRange{0.2, 0.9}, []Feature{s1}, false},
+		{"two-sided inside", ComfortZone{Window: 1, TwoSided: true}, FloatRange{0.2, 0.9}, []Feature{s06}, false},
+		{"two-sided below", ComfortZone{Window: 1, TwoSided: true}, FloatRange{0.2, 0.9}, []Feature{s02}, true},
+		{"two-sided above", ComfortZone{Window: 1, TwoSided: true}, FloatRange{0.2, 0.9}, []Feature{s1}, true},
+		{"two-sided at the upper bound", ComfortZone{Window: 1, TwoSided: true}, FloatRange{0.2, 1}, []Feature{s1}, false},
+		// the last post only
+		{"last post", ComfortZone{Window: 1}, FloatRange{0.2, 0.9}, []Feature{s1, s02}, true},
+		// (1 + 0.2) / 2
+		{"window", ComfortZone{Window: 2}, FloatRange{0.2, 0.9}, []Feature{s1, s02}, false},
+		{"window of the last posts", ComfortZone{Window: 2}, FloatRange{0.2, 0.9}, []Feature{s06, s02, s02}, true},
+		{"window longer than the blog", ComfortZone{Window: 5}, FloatRange{0.2, 0.9}, []Feature{s1, s02}, false},
+		{"two-sided window", ComfortZone{Window: 2, TwoSided: true}, FloatRange{0.2, 0.9}, []Feature{s02, s1, s1}, true},
+		{"no window", ComfortZone{}, FloatRange{0.2, 0.9}, []Feature{s1, s02}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := testModel(10, 7, 0.3, nil)
+			a := (*e.Landscape.GetAgents())[0].(*EchoChamberAgent)
+			a.Features = Feature{0, 0, 0, 0, 0}
+			a.RSimilarityConfortLevel = tt.comfort
+			blog := &Blog{}
+			for i, p := range tt.posts {
+				blog.Publish(i, p)
+			}
+			if got := tt.policy.Drop(a, blog); got != tt.want {
+				t.Errorf("drop %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsubscribeOnReading(t *testing.T) {
+	e := testModel(10, 7, 0.3, nil)
+	e.Unsubscribe = ComfortZone{Rate: 1, Window: 1, TwoSided: true}
+	blogs := testBlogs(e, Feature{0, 0, 0, 0, 0}, Feature{0, 0, 0, 1, 1}, Feature{0, 1, 1, 1, 1})
+	a := (*e.Landscape.GetAgents())[0].(*EchoChamberAgent)
+	a.Features = Feature{0, 0, 0, 0, 0}
+	a.RSimilarityConfortLevel = FloatRange{0.2, 0.9}
+	a.RSubscribedBlogs = IntRange{0, 3}
+	for _, b := range blogs {
+		a.MySubscriptions.Subscribe(b)
+	}
+
+	a.ReadBlogs()
+	// too similar and too different are dropped
+	if got := a.MySubscriptions.Blogs(); len(got) != 1 || got[0] != blogs[1] {
+		t.Errorf("follows %d blogs, want only blog %d", len(got), blogs[1].ID)
+	}
+	checkFollowers(t, e, blogs)
+}
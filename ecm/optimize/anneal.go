@@ -0,0 +1,48 @@
+package optimize
+
+import "math"
+
+// SimulatedAnnealing walks to random neighbors, worse ones are accepted with
+// probability exp(-Δ/T) while the temperature T cools down
+type SimulatedAnnealing struct {
+	// start temperature, in units of the score
+	Temp float64
+	// share the temperature drops per step
+	CoolingRate float64
+	// number of steps
+	KMax int
+	// standard deviation of a move on the unit cube, 0.1 if 0
+	Step float64
+	// candidates re-evaluated for the result, 5 if 0
+	Keep int
+}
+
+func (sa SimulatedAnnealing) Minimize(e *Evaluator, x0 []float64) Result {
+	step := sa.Step
+	if step == 0 {
+		step = 0.1
+	}
+	keep := sa.Keep
+	if keep == 0 {
+		keep = 5
+	}
+
+	current := e.Eval(e.start(x0))
+	t := sa.Temp
+	for k := 0; k < sa.KMax && !e.Done() && e.Space.Dim() > 0; k++ {
+		x := make([]float64, len(current.X))
+		for i := range x {
+			x[i] = current.X[i] + e.Rand.NormFloat64()*step
+		}
+		next := e.Eval(x)
+
+		// the current state competes with its mean over more runs
+		e.Reevaluate(current)
+		delta := next.Score() - current.Score()
+		if delta <= 0 || (t > 0 && e.Rand.Float64() < math.Exp(-delta/t)) {
+			current = next
+		}
+		t *= 1 - sa.CoolingRate
+	}
+	return e.result(append(e.best(keep), current))
+}
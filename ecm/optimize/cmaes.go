@@ -0,0 +1,257 @@
+package optimize
+
+import "math"
+import "sort"
+
+// CMAES is the covariance matrix adaptation evolution strategy: every
+// generation samples Lambda points from a normal distribution whose mean,
+// step size and covariance follow the best points. Samples outside the unit
+// cube are moved onto its boundary.
+type CMAES struct {
+	// initial step size on the unit cube, 0.3 if 0
+	Sigma float64
+	// points per generation, 4 + 3 ln(dim) if 0
+	Lambda int
+	// number of generations
+	MaxGen int
+	// stops once the step size is smaller, 0 to never stop
+	Tol float64
+}
+
+func (c CMAES) Minimize(e *Evaluator, x0 []float64) Result {
+	n := e.Space.Dim()
+	mean := e.start(x0)
+	if n == 0 {
+		return e.result([]*Point{e.Eval(mean)})
+	}
+
+	sigma := c.Sigma
+	if sigma == 0 {
+		sigma = 0.3
+	}
+	lambda := c.Lambda
+	if lambda == 0 {
+		lambda = 4 + int(3*math.Log(float64(n)))
+	}
+	mu := lambda / 2
+	if mu < 1 {
+		mu = 1
+	}
+
+	// recombination weights and the strategy constants of Hansen's tutorial
+	weights := make([]float64, mu)
+	sum := 0.0
+	for i := range weights {
+		weights[i] = math.Log(float64(mu)+0.5) - math.Log(float64(i+1))
+		sum += weights[i]
+	}
+	sq := 0.0
+	for i := range weights {
+		weights[i] /= sum
+		sq += weights[i] * weights[i]
+	}
+	mueff := 1 / sq
+	fn := float64(n)
+	cc := (4 + mueff/fn) / (fn + 4 + 2*mueff/fn)
+	cs := (mueff + 2) / (fn + mueff + 5)
+	c1 := 2 / ((fn+1.3)*(fn+1.3) + mueff)
+	cmu := math.Min(1-c1, 2*(mueff-2+1/mueff)/((fn+2)*(fn+2)+mueff))
+	damps := 1 + 2*math.Max(0, math.Sqrt((mueff-1)/(fn+1))-1) + cs
+	chiN := math.Sqrt(fn) * (1 - 1/(4*fn) + 1/(21*fn*fn))
+
+	C := identity(n)
+	B := identity(n)
+	D := make([]float64, n)
+	for i := range D {
+		D[i] = 1
+	}
+	pc := make([]float64, n)
+	ps := make([]float64, n)
+
+	var incumbent *Point
+	var candidates []*Point
+	for gen := 0; gen < c.MaxGen && !e.Done(); gen++ {
+		type sample struct {
+			p *Point
+			y []float64
+		}
+		samples := make([]sample, 0, lambda)
+		for k := 0; k < lambda && !e.Done(); k++ {
+			z := make([]float64, n)
+			for i := range z {
+				z[i] = D[i] * e.Rand.NormFloat64()
+			}
+			x := make([]float64, n)
+			for i := range x {
+				y := 0.0
+				for j := range z {
+					y += B[i][j] * z[j]
+				}
+				x[i] = mean[i] + sigma*y
+			}
+			p := e.Eval(x)
+			// the step actually taken after clamping
+			y := make([]float64, n)
+			for i := range y {
+				y[i] = (p.X[i] - mean[i]) / sigma
+			}
+			samples = append(samples, sample{p, y})
+		}
+		if len(samples) < mu {
+			break
+		}
+		sort.SliceStable(samples, func(i, j int) bool {
+			return samples[i].p.Score() < samples[j].p.Score()
+		})
+
+		// the incumbent competes with its mean over more runs
+		if incumbent != nil {
+			e.Reevaluate(incumbent)
+		}
+		if incumbent == nil || samples[0].p.Score() < incumbent.Score() {
+			incumbent = samples[0].p
+			candidates = append(candidates, incumbent)
+		}
+
+		// move the mean
+		yw := make([]float64, n)
+		for k := 0; k < mu; k++ {
+			for i := range yw {
+				yw[i] += weights[k] * samples[k].y[i]
+			}
+		}
+		for i := range mean {
+			mean[i] += sigma * yw[i]
+		}
+
+		// evolution paths
+		invSqrt := make([]float64, n)
+		for i := range invSqrt {
+			// C^-1/2 yw = B D^-1 B' yw
+			for j := range yw {
+				v := 0.0
+				for k := range yw {
+					v += B[k][j] * yw[k]
+				}
+				invSqrt[i] += B[i][j] * v / D[j]
+			}
+		}
+		norm := 0.0
+		for i := range ps {
+			ps[i] = (1-cs)*ps[i] + math.Sqrt(cs*(2-cs)*mueff)*invSqrt[i]
+			norm += ps[i] * ps[i]
+		}
+		norm = math.Sqrt(norm)
+		hsig := 0.0
+		if norm/math.Sqrt(1-math.Pow(1-cs, 2*float64(gen+1)))/chiN < 1.4+2/(fn+1) {
+			hsig = 1
+		}
+		for i := range pc {
+			pc[i] = (1-cc)*pc[i] + hsig*math.Sqrt(cc*(2-cc)*mueff)*yw[i]
+		}
+
+		// covariance and step size
+		for i := 0; i < n; i++ {
+			for j := 0; j <= i; j++ {
+				rankMu := 0.0
+				for k := 0; k < mu; k++ {
+					rankMu += weights[k] * samples[k].y[i] * samples[k].y[j]
+				}
+				v := (1-c1-cmu)*C[i][j] +
+					c1*(pc[i]*pc[j]+(1-hsig)*cc*(2-cc)*C[i][j]) +
+					cmu*rankMu
+				C[i][j], C[j][i] = v, v
+			}
+		}
+		sigma *= math.Exp(cs / damps * (norm/chiN - 1))
+
+		vals, vecs := eigen(C)
+		for i := range D {
+			D[i] = math.Sqrt(math.Max(vals[i], 1e-20))
+		}
+		B = vecs
+
+		if c.Tol > 0 && sigma*maxOf(D) < c.Tol {
+			break
+		}
+	}
+	if incumbent == nil {
+		return e.result([]*Point{e.Eval(mean)})
+	}
+	return e.result(append(candidates, e.Eval(mean)))
+}
+
+func identity(n int) [][]float64 {
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n)
+		m[i][i] = 1
+	}
+	return m
+}
+
+func maxOf(v []float64) float64 {
+	m := v[0]
+	for _, x := range v {
+		m = math.Max(m, x)
+	}
+	return m
+}
+
+// eigenvalues and eigenvectors (the columns) of a symmetric matrix by
+// cyclic Jacobi rotations
+func eigen(a [][]float64) ([]float64, [][]float64) {
+	n := len(a)
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = append([]float64(nil), a[i]...)
+	}
+	v := identity(n)
+
+	for sweep := 0; sweep < 50; sweep++ {
+		off := 0.0
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				off += m[i][j] * m[i][j]
+			}
+		}
+		if off < 1e-22 {
+			break
+		}
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if m[p][q] == 0 {
+					continue
+				}
+				theta := (m[q][q] - m[p][p]) / (2 * m[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					mkp, mkq := m[k][p], m[k][q]
+					m[k][p] = c*mkp - s*mkq
+					m[k][q] = s*mkp + c*mkq
+				}
+				for k := 0; k < n; k++ {
+					mpk, mqk := m[p][k], m[q][k]
+					m[p][k] = c*mpk - s*mqk
+					m[q][k] = s*mpk + c*mqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p] = c*vkp - s*vkq
+					v[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+
+	vals := make([]float64, n)
+	for i := range vals {
+		vals[i] = m[i][i]
+	}
+	return vals, v
+}
@@ -0,0 +1,272 @@
+/*
+Package optimize searches the parameters of an experiment which minimize a
+experiment.TargetFunction.
+
+The optimizers work on the unit cube: a Space maps every variable of a
+parameter set (the α and β of the probabilities) to [0, 1] between its Min
+and Max. The target is noisy, an Evaluator runs it, averages repeated runs of
+a point and keeps the history of all runs. A RerunTargetFunction gives every
+run of a point other seeds. An experiment.MultiTargetFunction scores the mean of its
+objectives, the Result keeps the Pareto front of all evaluated points.
+
+	e := optimize.NewEvaluator(mt, p, seed)
+	r := optimize.SimulatedAnnealing{Temp: 1, CoolingRate: 0.01, KMax: 400}.Minimize(e, nil)
+*/
+package optimize
+
+import "fmt"
+import "io"
+import "math"
+import "math/rand"
+import "sort"
+import "time"
+
+import "flache/ecm/experiment"
+
+// Point is an evaluated point of the space, its score is the mean of its runs
+type Point struct {
+	X          []float64
+	Parameters experiment.Parameters
+	Scores     []float64
+	// the objectives of every run of a MultiTargetFunction
+	Objectives [][]float64
+}
+
+func (p *Point) Score() float64 {
+	if len(p.Scores) == 0 {
+		return math.Inf(1)
+	}
+	sum := 0.0
+	for _, s := range p.Scores {
+		sum += s
+	}
+	return sum / float64(len(p.Scores))
+}
+
+// the mean of every objective over the runs, nil without objectives
+func (p *Point) ObjectiveMeans() []float64 {
+	if len(p.Objectives) == 0 {
+		return nil
+	}
+	means := make([]float64, len(p.Objectives[0]))
+	for _, run := range p.Objectives {
+		for i, v := range run {
+			means[i] += v / float64(len(p.Objectives))
+		}
+	}
+	return means
+}
+
+// Evaluation is a single run of the target
+type Evaluation struct {
+	// position in the history
+	N     int
+	X     []float64
+	Score float64
+	// the objectives of a MultiTargetFunction
+	Objectives []float64
+	// number of runs of the point so far, > 1 for a re-evaluation
+	Run      int
+	Duration time.Duration
+}
+
+// Evaluator runs the target on points of the space. The target is noisy: a
+// new point is run Repeats times and a point which stays the incumbent of an
+// optimizer is run again until it has MaxRepeats runs, a lucky first run does
+// not stick.
+type Evaluator struct {
+	TF    experiment.TargetFunction
+	Space *experiment.Space
+	// runs of a new point
+	Repeats int
+	// runs of a re-evaluated point
+	MaxRepeats int
+	// maximal number of runs, 0 for no limit
+	Budget int
+	Rand   *rand.Rand
+	// largest Pareto front of the result, 0 keeps all
+	FrontSize int
+
+	History []Evaluation
+	points  []*Point
+}
+
+func NewEvaluator(tf experiment.TargetFunction, p experiment.Parameters, seed int64) *Evaluator {
+	return &Evaluator{TF: tf, Space: experiment.NewSpace(p), Repeats: 1, MaxRepeats: 5,
+		Rand: rand.New(rand.NewSource(seed))}
+}
+
+// whether the budget is used up
+func (e *Evaluator) Done() bool {
+	return e.Budget > 0 && len(e.History) >= e.Budget
+}
+
+// evaluates a new point, the point is clamped into the cube. Once the
+// budget is used up the point is not run and scores +Inf.
+func (e *Evaluator) Eval(x []float64) *Point {
+	x = experiment.Clamp(x)
+	p := &Point{X: x, Parameters: e.Space.Parameters(x)}
+	e.points = append(e.points, p)
+	n := e.Repeats
+	if n < 1 {
+		n = 1
+	}
+	for i := 0; i < n && !e.Done(); i++ {
+		e.run(p)
+	}
+	return p
+}
+
+// runs the point once more unless it has MaxRepeats runs
+func (e *Evaluator) Reevaluate(p *Point) {
+	if len(p.Scores) < e.MaxRepeats && !e.Done() {
+		e.run(p)
+	}
+}
+
+func (e *Evaluator) run(p *Point) {
+	start := time.Now()
+	tf := e.TF
+	if r, ok := tf.(experiment.RerunTargetFunction); ok {
+		// every run of the point has seeds of its own
+		tf = r.Rerun(len(p.Scores))
+	}
+	var score float64
+	var objectives []float64
+	if mtf, ok := tf.(experiment.MultiTargetFunction); ok {
+		objectives = mtf.RunAll(p.Parameters)
+		for _, v := range objectives {
+			score += v / float64(len(objectives))
+		}
+		p.Objectives = append(p.Objectives, objectives)
+	} else {
+		score = tf.Run(p.Parameters)
+	}
+	p.Scores = append(p.Scores, score)
+	e.History = append(e.History, Evaluation{N: len(e.History), X: p.X, Score: score,
+		Objectives: objectives, Run: len(p.Scores), Duration: time.Since(start)})
+}
+
+// a uniform random point of the cube
+func (e *Evaluator) random() []float64 {
+	x := make([]float64, e.Space.Dim())
+	for i := range x {
+		x[i] = e.Rand.Float64()
+	}
+	return x
+}
+
+// the start point of an optimizer, the initial parameters if x0 is nil
+func (e *Evaluator) start(x0 []float64) []float64 {
+	if x0 == nil {
+		return e.Space.Point(e.Space.Initial)
+	}
+	return experiment.Clamp(x0)
+}
+
+// Result of an optimization
+type Result struct {
+	Best *Point
+	// the best points of the run, best first
+	Candidates []*Point
+	// runs of the target
+	Evaluations int
+	// the non-dominated points of all evaluations of a MultiTargetFunction
+	Front experiment.Front
+}
+
+func (r Result) Parameters() experiment.Parameters {
+	return r.Best.Parameters
+}
+
+func (r Result) Score() float64 {
+	return r.Best.Score()
+}
+
+// re-evaluates the candidates, which are the best points an optimizer found,
+// and returns them ordered by their score
+func (e *Evaluator) result(points []*Point) Result {
+	var candidates []*Point
+	seen := make(map[*Point]bool)
+	for _, p := range points {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		candidates = append(candidates, p)
+		for len(p.Scores) < e.MaxRepeats && !e.Done() {
+			e.run(p)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score() < candidates[j].Score()
+	})
+	r := Result{Best: candidates[0], Candidates: candidates, Evaluations: len(e.History),
+		Front: experiment.Front{Size: e.FrontSize}}
+	for _, p := range e.points {
+		if objectives := p.ObjectiveMeans(); objectives != nil {
+			r.Front.Add(experiment.SimRunRes{Parameters: p.Parameters, Score: p.Score(),
+				Objectives: objectives})
+		}
+	}
+	return r
+}
+
+// the names of the objectives of a MultiTargetFunction, nil for another
+// target
+func (e *Evaluator) ObjectiveNames() []string {
+	if mtf, ok := e.TF.(experiment.MultiTargetFunction); ok {
+		return mtf.ObjectiveNames()
+	}
+	return nil
+}
+
+// the n evaluated points with the lowest score
+func (e *Evaluator) best(n int) []*Point {
+	points := append([]*Point(nil), e.points...)
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Score() < points[j].Score()
+	})
+	if len(points) > n {
+		points = points[:n]
+	}
+	return points
+}
+
+// writes the history as csv: one row per run with the values of the variables
+func (e *Evaluator) WriteHistory(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "n, run, score, seconds"); err != nil {
+		return err
+	}
+	objectives := e.ObjectiveNames()
+	for _, name := range objectives {
+		fmt.Fprintf(w, ", %s", name)
+	}
+	for _, name := range e.Space.Names() {
+		fmt.Fprintf(w, ", %s", name)
+	}
+	fmt.Fprintf(w, "\n")
+	for _, h := range e.History {
+		fmt.Fprintf(w, "%d, %d, %f, %f", h.N, h.Run, h.Score, h.Duration.Seconds())
+		for i := range objectives {
+			v := math.NaN()
+			if i < len(h.Objectives) {
+				v = h.Objectives[i]
+			}
+			fmt.Fprintf(w, ", %f", v)
+		}
+		for _, v := range e.Space.Values(h.X) {
+			fmt.Fprintf(w, ", %f", v)
+		}
+		if _, err := fmt.Fprintf(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Optimizer minimizes the target of an evaluator, starting at x0 or at the
+// initial parameters if x0 is nil
+type Optimizer interface {
+	Minimize(e *Evaluator, x0 []float64) Result
+}
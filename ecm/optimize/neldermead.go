@@ -0,0 +1,104 @@
+package optimize
+
+import "sort"
+
+// NelderMead moves a simplex of dim+1 points by reflection, expansion,
+// contraction and shrinking. The best vertex is re-evaluated every
+// iteration, so a vertex which only had a lucky run is replaced eventually.
+type NelderMead struct {
+	// edge length of the initial simplex on the unit cube, 0.2 if 0
+	Step float64
+	// number of iterations
+	MaxIter int
+	// stops once the scores of the simplex differ by less, 0 to never stop
+	Tol float64
+}
+
+func (nm NelderMead) Minimize(e *Evaluator, x0 []float64) Result {
+	step := nm.Step
+	if step == 0 {
+		step = 0.2
+	}
+	start := e.start(x0)
+	n := e.Space.Dim()
+
+	simplex := []*Point{e.Eval(start)}
+	for i := 0; i < n; i++ {
+		x := append([]float64(nil), start...)
+		// step away from the closer bound
+		if x[i]+step <= 1 {
+			x[i] += step
+		} else {
+			x[i] -= step
+		}
+		simplex = append(simplex, e.Eval(x))
+	}
+	order := func() {
+		sort.SliceStable(simplex, func(i, j int) bool {
+			return simplex[i].Score() < simplex[j].Score()
+		})
+	}
+
+	for it := 0; it < nm.MaxIter && !e.Done() && n > 0; it++ {
+		e.Reevaluate(simplex[0])
+		order()
+		best, worst := simplex[0], simplex[n]
+		if nm.Tol > 0 && worst.Score()-best.Score() < nm.Tol {
+			break
+		}
+
+		centroid := make([]float64, n)
+		for _, p := range simplex[:n] {
+			for i, v := range p.X {
+				centroid[i] += v / float64(n)
+			}
+		}
+		// the point centroid + c * (centroid - worst)
+		along := func(c float64) []float64 {
+			x := make([]float64, n)
+			for i := range x {
+				x[i] = centroid[i] + c*(centroid[i]-worst.X[i])
+			}
+			return x
+		}
+
+		r := e.Eval(along(1))
+		switch {
+		case r.Score() < best.Score():
+			if x := e.Eval(along(2)); x.Score() < r.Score() {
+				simplex[n] = x
+			} else {
+				simplex[n] = r
+			}
+		case r.Score() < simplex[n-1].Score():
+			simplex[n] = r
+		default:
+			// contract outside if the reflected point beats the worst
+			// vertex and keep the contraction if it is no worse than r,
+			// else contract inside and keep it if it beats the worst
+			var x *Point
+			var accept bool
+			if r.Score() < worst.Score() {
+				x = e.Eval(along(0.5))
+				accept = x.Score() <= r.Score()
+			} else {
+				x = e.Eval(along(-0.5))
+				accept = x.Score() < worst.Score()
+			}
+			if accept {
+				simplex[n] = x
+				break
+			}
+			// shrink towards the best vertex
+			for j := 1; j <= n && !e.Done(); j++ {
+				x := make([]float64, n)
+				for i := range x {
+					x[i] = best.X[i] + 0.5*(simplex[j].X[i]-best.X[i])
+				}
+				simplex[j] = e.Eval(x)
+			}
+		}
+	}
+	order()
+	return e.result(simplex)
+}
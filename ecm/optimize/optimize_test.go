@@ -0,0 +1,112 @@
+package optimize
+
+import "math"
+import "testing"
+
+import "flache/ecm/experiment"
+import . "flache/ecm/model"
+
+// a deterministic target, the squared distance to min on the unit cube
+type quadratic struct {
+	space *experiment.Space
+	min   []float64
+}
+
+func (q quadratic) Run(p experiment.Parameters) float64 {
+	sum := 0.0
+	for i, v := range q.space.Point(p) {
+		sum += (v - q.min[i]) * (v - q.min[i])
+	}
+	return sum
+}
+
+func testParameters() experiment.Parameters {
+	return experiment.Parameters{Probabilities: []experiment.BPFP{{
+		Alpha: DiscreteVarWithLimit{Var: 5, Min: 0, Max: 10},
+		Beta:  DiscreteVarWithLimit{Var: 5, Min: 0, Max: 10}}}}
+}
+
+func TestConvergence(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Optimizer
+		tol  float64
+	}{
+		{"sa", SimulatedAnnealing{Temp: 0.01, CoolingRate: 0.02, KMax: 500, Step: 0.05}, 0.05},
+		{"nelder-mead", NelderMead{MaxIter: 200, Tol: 1e-12}, 1e-3},
+		{"cma-es", CMAES{MaxGen: 100, Tol: 1e-6}, 1e-3},
+		{"restarts", RandomRestarts{Local: NelderMead{MaxIter: 60}, Restarts: 3}, 1e-3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testParameters()
+			q := quadratic{space: experiment.NewSpace(p), min: []float64{0.3, 0.8}}
+			e := NewEvaluator(q, p, 1)
+			e.MaxRepeats = 1
+			r := tt.opt.Minimize(e, nil)
+
+			if d := math.Hypot(r.Best.X[0]-0.3, r.Best.X[1]-0.8); d > tt.tol {
+				t.Errorf("best point %v is %g away from the minimum", r.Best.X, d)
+			}
+			if r.Evaluations != len(e.History) {
+				t.Errorf("%d evaluations, %d in the history", r.Evaluations, len(e.History))
+			}
+			for i := 1; i < len(r.Candidates); i++ {
+				if r.Candidates[i].Score() < r.Candidates[i-1].Score() {
+					t.Fatalf("candidates out of order")
+				}
+			}
+		})
+	}
+}
+
+func TestBudget(t *testing.T) {
+	for _, opt := range []Optimizer{SimulatedAnnealing{KMax: 100}, NelderMead{MaxIter: 100},
+		CMAES{MaxGen: 100}, RandomRestarts{Local: NelderMead{MaxIter: 10}, Restarts: 10}} {
+		p := testParameters()
+		e := NewEvaluator(quadratic{space: experiment.NewSpace(p), min: []float64{0.5, 0.5}}, p, 1)
+		e.Budget = 25
+		opt.Minimize(e, nil)
+		if len(e.History) > e.Budget {
+			t.Errorf("%T: %d runs, the budget is %d", opt, len(e.History), e.Budget)
+		}
+	}
+}
+
+// records the evaluation of every run
+type reruns struct {
+	quadratic
+	runs *[]int
+	n    int
+}
+
+func (r reruns) Run(p experiment.Parameters) float64 {
+	*r.runs = append(*r.runs, r.n)
+	return r.quadratic.Run(p)
+}
+
+func (r reruns) Rerun(n int) experiment.TargetFunction {
+	r.n = n
+	return r
+}
+
+func TestReevaluate(t *testing.T) {
+	p := testParameters()
+	var runs []int
+	e := NewEvaluator(reruns{quadratic: quadratic{space: experiment.NewSpace(p), min: []float64{0.5, 0.5}},
+		runs: &runs}, p, 1)
+	e.Repeats, e.MaxRepeats = 2, 4
+	x := e.Eval([]float64{0.2, 0.2})
+	for i := 0; i < 3; i++ {
+		e.Reevaluate(x)
+	}
+	want := []int{0, 1, 2, 3}
+	if len(runs) != len(want) {
+		t.Fatalf("runs %v, want %v", runs, want)
+	}
+	for i := range want {
+		if runs[i] != want[i] {
+			t.Errorf("runs %v, want %v", runs, want)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package optimize
+
+// RandomRestarts runs a local optimizer from the start point and then from
+// random points of the unit cube, the best results of all runs compete for
+// the final result
+type RandomRestarts struct {
+	Local Optimizer
+	// runs after the first one
+	Restarts int
+}
+
+func (rr RandomRestarts) Minimize(e *Evaluator, x0 []float64) Result {
+	var candidates []*Point
+	for i := 0; i <= rr.Restarts && !e.Done(); i++ {
+		start := x0
+		if i > 0 {
+			start = e.random()
+		}
+		r := rr.Local.Minimize(e, start)
+		candidates = append(candidates, r.Best)
+	}
+	if len(candidates) == 0 {
+		return e.result([]*Point{e.Eval(e.start(x0))})
+	}
+	return e.result(candidates)
+}
@@ -0,0 +1,137 @@
+/*
+Package sampler draws the parameter sets of a sweep. The variables of a
+parameter set with Min < Max span the unit cube of an experiment.Space, a
+method places points in the cube: a grid, uniform Monte Carlo, a Latin
+hypercube or a Sobol sequence. The Design of a sweep records the method and
+its points, so a sweep can be analyzed or repeated later.
+*/
+package sampler
+
+import "encoding/json"
+import "fmt"
+import "io"
+import "math"
+import "math/rand"
+
+import "flache/ecm/experiment"
+
+// Method places n points in the unit cube of dimension dim
+type Method interface {
+	Points(n, dim int, rng *rand.Rand) ([][]float64, error)
+}
+
+// the known methods by name
+var Methods = map[string]Method{
+	"grid":  Grid{},
+	"mc":    MonteCarlo{},
+	"lhs":   LatinHypercube{},
+	"sobol": Sobol{},
+}
+
+// Grid has the same number of levels in every dimension, the largest number
+// which keeps the grid within n points. The levels span [0, 1].
+type Grid struct{}
+
+func (Grid) Points(n, dim int, rng *rand.Rand) ([][]float64, error) {
+	if dim == 0 {
+		return [][]float64{{}}, nil
+	}
+	levels := int(math.Floor(math.Pow(float64(n), 1/float64(dim)) + 1e-9))
+	if levels < 1 {
+		return nil, fmt.Errorf("a grid of %d dimensions needs at least 1 point", dim)
+	}
+	level := func(i int) float64 {
+		if levels == 1 {
+			return 0.5
+		}
+		return float64(i) / float64(levels-1)
+	}
+
+	total := 1
+	for d := 0; d < dim; d++ {
+		total *= levels
+	}
+	points := make([][]float64, total)
+	for i := range points {
+		x := make([]float64, dim)
+		k := i
+		for d := dim - 1; d >= 0; d-- {
+			x[d] = level(k % levels)
+			k /= levels
+		}
+		points[i] = x
+	}
+	return points, nil
+}
+
+// MonteCarlo draws independent uniform points
+type MonteCarlo struct{}
+
+func (MonteCarlo) Points(n, dim int, rng *rand.Rand) ([][]float64, error) {
+	points := make([][]float64, n)
+	for i := range points {
+		points[i] = make([]float64, dim)
+		for d := range points[i] {
+			points[i][d] = rng.Float64()
+		}
+	}
+	return points, nil
+}
+
+// LatinHypercube places exactly one point in every of the n slices of each
+// dimension
+type LatinHypercube struct{}
+
+func (LatinHypercube) Points(n, dim int, rng *rand.Rand) ([][]float64, error) {
+	points := make([][]float64, n)
+	for i := range points {
+		points[i] = make([]float64, dim)
+	}
+	for d := 0; d < dim; d++ {
+		for i, slice := range rng.Perm(n) {
+			points[i][d] = (float64(slice) + rng.Float64()) / float64(n)
+		}
+	}
+	return points, nil
+}
+
+// Design is a sample of parameter sets and how it was drawn
+type Design struct {
+	Method string
+	Seed   int64
+	// the sampled variables
+	Names []string
+	// the points on the unit cube and the values of the variables
+	Points [][]float64
+	Values [][]float64
+	// the parameter sets of the points, not part of the record
+	Parameters []experiment.Parameters `json:"-"`
+}
+
+// draws n points of the space with the method of that name
+func Sample(method string, space *experiment.Space, n int, seed int64) (*Design, error) {
+	m, ok := Methods[method]
+	if !ok {
+		return nil, fmt.Errorf("unknown sampling method %q", method)
+	}
+	points, err := m.Points(n, space.Dim(), rand.New(rand.NewSource(seed)))
+	if err != nil {
+		return nil, err
+	}
+	d := &Design{Method: method, Seed: seed, Names: space.Names(), Points: points}
+	for _, x := range points {
+		d.Values = append(d.Values, space.Values(x))
+		d.Parameters = append(d.Parameters, space.Parameters(x))
+	}
+	return d, nil
+}
+
+// writes the design as json
+func (d *Design) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
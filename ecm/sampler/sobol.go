@@ -0,0 +1,86 @@
+package sampler
+
+import "fmt"
+import "math/rand"
+
+// Sobol is the quasi-random Sobol sequence with the direction numbers of
+// Joe and Kuo. The origin, the first point of the sequence, is skipped.
+type Sobol struct{}
+
+// degree s, coefficients a and initial direction numbers m of the primitive
+// polynomials of the dimensions after the first (new-joe-kuo-6.21201)
+var joeKuo = []struct {
+	s, a uint32
+	m    []uint32
+}{
+	{1, 0, []uint32{1}},
+	{2, 1, []uint32{1, 3}},
+	{3, 1, []uint32{1, 3, 1}},
+	{3, 2, []uint32{1, 1, 1}},
+	{4, 1, []uint32{1, 1, 3, 3}},
+	{4, 4, []uint32{1, 3, 5, 13}},
+	{5, 2, []uint32{1, 1, 5, 5, 17}},
+	{5, 4, []uint32{1, 1, 5, 5, 5}},
+	{5, 7, []uint32{1, 1, 7, 11, 19}},
+	{5, 11, []uint32{1, 1, 5, 1, 1}},
+	{5, 13, []uint32{1, 1, 1, 3, 11}},
+	{5, 14, []uint32{1, 3, 5, 5, 31}},
+	{6, 1, []uint32{1, 3, 3, 9, 7, 49}},
+	{6, 13, []uint32{1, 1, 1, 15, 21, 21}},
+	{6, 16, []uint32{1, 3, 1, 13, 27, 49}},
+	{6, 19, []uint32{1, 1, 1, 15, 7, 5}},
+	{6, 22, []uint32{1, 3, 1, 15, 13, 25}},
+	{6, 25, []uint32{1, 1, 5, 5, 19, 61}},
+	{7, 1, []uint32{1, 3, 7, 11, 23, 15, 103}},
+	{7, 4, []uint32{1, 3, 7, 13, 13, 15, 69}},
+}
+
+// the largest dimension of the sequence
+const SobolMaxDim = 21
+
+const sobolBits = 32
+
+// the direction numbers of every dimension
+func directions(dim int) [][sobolBits]uint32 {
+	v := make([][sobolBits]uint32, dim)
+	for i := 0; i < sobolBits && dim > 0; i++ {
+		v[0][i] = 1 << (sobolBits - 1 - uint(i))
+	}
+	for d := 1; d < dim; d++ {
+		p := joeKuo[d-1]
+		s := int(p.s)
+		for i := 0; i < sobolBits; i++ {
+			if i < s {
+				v[d][i] = p.m[i] << (sobolBits - 1 - uint(i))
+				continue
+			}
+			v[d][i] = v[d][i-s] ^ (v[d][i-s] >> uint(s))
+			for k := 1; k < s; k++ {
+				v[d][i] ^= ((p.a >> uint(s-1-k)) & 1) * v[d][i-k]
+			}
+		}
+	}
+	return v
+}
+
+func (Sobol) Points(n, dim int, rng *rand.Rand) ([][]float64, error) {
+	if dim > SobolMaxDim {
+		return nil, fmt.Errorf("the Sobol sequence has at most %d dimensions, got %d", SobolMaxDim, dim)
+	}
+	v := directions(dim)
+	x := make([]uint32, dim)
+	points := make([][]float64, n)
+	for i := range points {
+		// gray code order: flip the direction of the lowest zero bit of i
+		c := 0
+		for k := uint32(i); k&1 == 1; k >>= 1 {
+			c++
+		}
+		points[i] = make([]float64, dim)
+		for d := range x {
+			x[d] ^= v[d][c]
+			points[i][d] = float64(x[d]) / (1 << sobolBits)
+		}
+	}
+	return points, nil
+}
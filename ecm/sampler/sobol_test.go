@@ -0,0 +1,68 @@
+package sampler
+
+import "testing"
+
+func TestSobolJoeKuo(t *testing.T) {
+	// the points after the origin of new-joe-kuo-6.21201, as published
+	want := [][]float64{
+		{0.5, 0.5, 0.5, 0.5, 0.5},
+		{0.75, 0.25, 0.25, 0.25, 0.75},
+		{0.25, 0.75, 0.75, 0.75, 0.25},
+		{0.375, 0.375, 0.625, 0.875, 0.375},
+		{0.875, 0.875, 0.125, 0.375, 0.875},
+		{0.625, 0.125, 0.875, 0.625, 0.625},
+		{0.125, 0.625, 0.375, 0.125, 0.125},
+	}
+	got, err := Sobol{}.Points(len(want), len(want[0]), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range want {
+		for d := range want[i] {
+			if got[i][d] != want[i][d] {
+				t.Errorf("point %d: got %v, want %v", i+1, got[i], want[i])
+				break
+			}
+		}
+	}
+}
+
+func TestSobolStratified(t *testing.T) {
+	// with the origin, the first 2^m points of every dimension fall into
+	// each of the 2^m intervals of width 2^-m once
+	tests := []struct {
+		name string
+		m    uint
+		dim  int
+	}{
+		{"2 points", 1, SobolMaxDim},
+		{"64 points", 6, SobolMaxDim},
+		{"1024 points", 10, SobolMaxDim},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := 1 << tt.m
+			points, err := Sobol{}.Points(n-1, tt.dim, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for d := 0; d < tt.dim; d++ {
+				seen := make([]bool, n)
+				seen[0] = true // the origin
+				for _, x := range points {
+					cell := int(x[d] * float64(n))
+					if seen[cell] {
+						t.Fatalf("dimension %d: two points in interval %d of %d", d, cell, n)
+					}
+					seen[cell] = true
+				}
+			}
+		})
+	}
+}
+
+func TestSobolMaxDim(t *testing.T) {
+	if _, err := (Sobol{}).Points(1, SobolMaxDim+1, nil); err == nil {
+		t.Errorf("no error for %d dimensions", SobolMaxDim+1)
+	}
+}
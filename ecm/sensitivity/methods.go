@@ -0,0 +1,177 @@
+package sensitivity
+
+import "context"
+import "math"
+
+import "flache/ecm/sampler"
+
+// Sobol indices from a Saltelli design of n base samples, n(k+2) points for
+// k factors. S uses the estimator of Saltelli (2010), ST the one of Jansen.
+func (a *Analysis) Saltelli(ctx context.Context, n int) (*Indices, error) {
+	k := len(a.Factors)
+	// two independent samples A and B from a 2k dimensional sequence
+	var method sampler.Method = sampler.Sobol{}
+	if 2*k > sampler.SobolMaxDim {
+		method = sampler.MonteCarlo{}
+	}
+	ab, err := method.Points(n, 2*k, a.Rand)
+	if err != nil {
+		return nil, err
+	}
+
+	// A, B and the k matrices A with column i from B
+	points := make([][]float64, 0, n*(k+2))
+	for _, x := range ab {
+		points = append(points, x[:k])
+	}
+	for _, x := range ab {
+		points = append(points, x[k:])
+	}
+	for i := 0; i < k; i++ {
+		for _, x := range ab {
+			p := append([]float64(nil), x[:k]...)
+			p[i] = x[k+i]
+			points = append(points, p)
+		}
+	}
+
+	y, err := a.evaluate(ctx, points)
+	if err != nil {
+		return nil, err
+	}
+	fA, fB := y[:n], y[n:2*n]
+	fAB := func(i, j int) float64 { return y[(2+i)*n+j] }
+
+	// the base samples without a failed point
+	var rows []int
+	for j := 0; j < n; j++ {
+		ok := !math.IsNaN(fA[j]) && !math.IsNaN(fB[j])
+		for i := 0; i < k; i++ {
+			ok = ok && !math.IsNaN(fAB(i, j))
+		}
+		if ok {
+			rows = append(rows, j)
+		}
+	}
+	v := func(idx []int) float64 {
+		var all []float64
+		for _, r := range idx {
+			all = append(all, fA[rows[r]], fB[rows[r]])
+		}
+		return variance(all)
+	}
+
+	ix := &Indices{Method: "saltelli", Factors: a.Factors, Columns: []string{"S", "ST"},
+		Runs: len(points) * a.replicates()}
+	for i := 0; i < k; i++ {
+		i := i
+		first := a.bootstrap(len(rows), func(idx []int) float64 {
+			sum := 0.0
+			for _, r := range idx {
+				j := rows[r]
+				sum += fB[j] * (fAB(i, j) - fA[j])
+			}
+			return sum / float64(len(idx)) / v(idx)
+		})
+		total := a.bootstrap(len(rows), func(idx []int) float64 {
+			sum := 0.0
+			for _, r := range idx {
+				j := rows[r]
+				d := fA[j] - fAB(i, j)
+				sum += d * d
+			}
+			return sum / 2 / float64(len(idx)) / v(idx)
+		})
+		ix.Estimates = append(ix.Estimates, []Estimate{first, total})
+	}
+	return ix, nil
+}
+
+// elementary effects of r Morris trajectories on a grid of levels (4 if 0),
+// r(k+1) points for k factors. The effects are in units of the factor
+// ranges.
+func (a *Analysis) Morris(ctx context.Context, r, levels int) (*Indices, error) {
+	k := len(a.Factors)
+	if levels < 2 {
+		levels = 4
+	}
+	delta := float64(levels) / (2 * float64(levels-1))
+
+	points := make([][]float64, 0, r*(k+1))
+	// the factor and the signed step of every move
+	type move struct {
+		factor int
+		step   float64
+	}
+	moves := make([][]move, r)
+	for t := 0; t < r; t++ {
+		x := make([]float64, k)
+		for i := range x {
+			x[i] = float64(a.Rand.Intn(levels)) / float64(levels-1)
+		}
+		points = append(points, append([]float64(nil), x...))
+		for _, i := range a.Rand.Perm(k) {
+			step := delta
+			if x[i]+delta > 1+1e-9 {
+				step = -delta
+			}
+			x[i] += step
+			points = append(points, append([]float64(nil), x...))
+			moves[t] = append(moves[t], move{i, step})
+		}
+	}
+
+	y, err := a.evaluate(ctx, points)
+	if err != nil {
+		return nil, err
+	}
+
+	// effects[i][t] of factor i in trajectory t, NaN if a run failed
+	effects := make([][]float64, k)
+	for i := range effects {
+		effects[i] = make([]float64, r)
+	}
+	for t, ms := range moves {
+		base := t * (k + 1)
+		for m, mv := range ms {
+			effects[mv.factor][t] = (y[base+m+1] - y[base+m]) / mv.step
+		}
+	}
+
+	ix := &Indices{Method: "morris", Factors: a.Factors, Columns: []string{"mu*", "mu", "sigma"},
+		Runs: len(points) * a.replicates()}
+	for i := 0; i < k; i++ {
+		var ee []float64
+		for _, e := range effects[i] {
+			if !math.IsNaN(e) {
+				ee = append(ee, e)
+			}
+		}
+		stat := func(f func(idx []int) float64) Estimate {
+			return a.bootstrap(len(ee), f)
+		}
+		muStar := stat(func(idx []int) float64 {
+			sum := 0.0
+			for _, j := range idx {
+				sum += math.Abs(ee[j])
+			}
+			return sum / float64(len(idx))
+		})
+		mu := stat(func(idx []int) float64 {
+			sum := 0.0
+			for _, j := range idx {
+				sum += ee[j]
+			}
+			return sum / float64(len(idx))
+		})
+		sigma := stat(func(idx []int) float64 {
+			v := make([]float64, len(idx))
+			for n, j := range idx {
+				v[n] = ee[j]
+			}
+			return math.Sqrt(variance(v))
+		})
+		ix.Estimates = append(ix.Estimates, []Estimate{muStar, mu, sigma})
+	}
+	return ix, nil
+}
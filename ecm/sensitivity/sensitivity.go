@@ -0,0 +1,209 @@
+/*
+Package sensitivity measures how much the factors of an experiment drive an
+output statistic.
+
+Saltelli designs estimate the first-order index S (the share of the output
+variance explained by a factor alone) and the total-order index ST (the
+share including all its interactions). Morris designs are cheaper and screen
+the factors by their elementary effects: μ* is the mean absolute change of
+the output per unit change of the factor and σ the spread of the changes,
+which is large for interacting or nonlinear factors. The indices come with
+bootstrap confidence intervals.
+*/
+package sensitivity
+
+import "context"
+import "fmt"
+import "io"
+import "math"
+import "math/rand"
+import "sort"
+import "text/tabwriter"
+
+import "flache/ecm/experiment"
+
+// a factor varied uniformly between Min and Max
+type Factor struct {
+	Name     string
+	Min, Max float64
+}
+
+// runs the model with the values of the factors and returns the output
+type Model func(ctx context.Context, values []float64, seed int64) (float64, error)
+
+// Analysis is the setup of a sensitivity study
+type Analysis struct {
+	Factors []Factor
+	Model   Model
+	// runs the points of the design
+	Executor *experiment.Executor
+	// runs per point, the output of a point is their mean
+	Replicates int
+	Rand       *rand.Rand
+	// bootstrap resamples and the level of the confidence intervals
+	Bootstrap int
+	Level     float64
+}
+
+func (a *Analysis) replicates() int {
+	if a.Replicates < 1 {
+		return 1
+	}
+	return a.Replicates
+}
+
+// the values of a point of the unit cube
+func (a *Analysis) values(x []float64) []float64 {
+	v := make([]float64, len(x))
+	for i, f := range a.Factors {
+		v[i] = f.Min + x[i]*(f.Max-f.Min)
+	}
+	return v
+}
+
+// the output of every point, NaN if all its runs failed. The error is ctx's
+// if the analysis was canceled.
+func (a *Analysis) evaluate(ctx context.Context, points [][]float64) ([]float64, error) {
+	replicates := a.replicates()
+	var jobs []experiment.Job
+	for set := range points {
+		for r := 0; r < replicates; r++ {
+			jobs = append(jobs, experiment.Job{Set: set, Replicate: r, Seed: a.Rand.Int63()})
+		}
+	}
+
+	// the model's outputs by job, the executor only passes on SimRes
+	outputs := make([]float64, len(jobs))
+	results := a.Executor.Execute(ctx, jobs, func(ctx context.Context, j experiment.Job) (experiment.SimRes, error) {
+		y, err := a.Model(ctx, a.values(points[j.Set]), j.Seed)
+		outputs[j.ID] = y
+		return experiment.SimRes{}, err
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sums := make([]float64, len(points))
+	n := make([]int, len(points))
+	for _, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		sums[r.Set] += outputs[r.ID]
+		n[r.Set]++
+	}
+	y := make([]float64, len(points))
+	for i := range y {
+		y[i] = math.NaN()
+		if n[i] > 0 {
+			y[i] = sums[i] / float64(n[i])
+		}
+	}
+	return y, nil
+}
+
+// an estimate with its confidence interval
+type Estimate struct {
+	Value  float64
+	Lo, Hi float64
+}
+
+func (e Estimate) String() string {
+	return fmt.Sprintf("%.3f [%.3f, %.3f]", e.Value, e.Lo, e.Hi)
+}
+
+// the estimate on all samples and the percentile interval of the estimates
+// on bootstrap resamples of the n samples
+func (a *Analysis) bootstrap(n int, estimate func(idx []int) float64) Estimate {
+	all := make([]int, n)
+	for i := range all {
+		all[i] = i
+	}
+	e := Estimate{Value: estimate(all), Lo: math.NaN(), Hi: math.NaN()}
+	if a.Bootstrap < 1 || n == 0 {
+		return e
+	}
+
+	var values []float64
+	idx := make([]int, n)
+	for b := 0; b < a.Bootstrap; b++ {
+		for i := range idx {
+			idx[i] = a.Rand.Intn(n)
+		}
+		if v := estimate(idx); !math.IsNaN(v) {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return e
+	}
+	sort.Float64s(values)
+	tail := (1 - a.Level) / 2
+	e.Lo = values[int(math.Floor(tail*float64(len(values)-1)))]
+	e.Hi = values[int(math.Ceil((1-tail)*float64(len(values)-1)))]
+	return e
+}
+
+func variance(v []float64) float64 {
+	mean := 0.0
+	for _, x := range v {
+		mean += x
+	}
+	mean /= float64(len(v))
+	ss := 0.0
+	for _, x := range v {
+		ss += (x - mean) * (x - mean)
+	}
+	return ss / float64(len(v))
+}
+
+// Indices of the factors, the columns depend on the method
+type Indices struct {
+	Method  string
+	Factors []Factor
+	Columns []string
+	// per factor the estimates of the columns
+	Estimates [][]Estimate
+	// model evaluations (points times replicates)
+	Runs int
+}
+
+// writes the indices as a table
+func (ix *Indices) Print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "factor")
+	for _, c := range ix.Columns {
+		fmt.Fprintf(tw, "\t%s", c)
+	}
+	fmt.Fprintf(tw, "\n")
+	for i, f := range ix.Factors {
+		fmt.Fprintf(tw, "%s", f.Name)
+		for _, e := range ix.Estimates[i] {
+			fmt.Fprintf(tw, "\t%v", e)
+		}
+		fmt.Fprintf(tw, "\n")
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%s, %d runs\n", ix.Method, ix.Runs)
+}
+
+// writes the indices as csv: factor, then value, lo and hi of every column
+func (ix *Indices) Write(w io.Writer) error {
+	fmt.Fprintf(w, "factor")
+	for _, c := range ix.Columns {
+		fmt.Fprintf(w, ", %s, %s_lo, %s_hi", c, c, c)
+	}
+	if _, err := fmt.Fprintf(w, "\n"); err != nil {
+		return err
+	}
+	for i, f := range ix.Factors {
+		fmt.Fprintf(w, "%s", f.Name)
+		for _, e := range ix.Estimates[i] {
+			fmt.Fprintf(w, ", %g, %g, %g", e.Value, e.Lo, e.Hi)
+		}
+		if _, err := fmt.Fprintf(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package sensitivity
+
+import "context"
+import "math"
+import "math/rand"
+import "testing"
+
+import "flache/ecm/experiment"
+
+func analysis(factors []Factor, f func(x []float64) float64) *Analysis {
+	return &Analysis{Factors: factors,
+		Model: func(ctx context.Context, x []float64, seed int64) (float64, error) {
+			return f(x), nil
+		},
+		Executor: &experiment.Executor{Workers: 4},
+		Rand:     rand.New(rand.NewSource(1)), Bootstrap: 100, Level: 0.95}
+}
+
+// the Ishigami function with a = 7 and b = 0.1 on [-π, π]³
+func ishigami(x []float64) float64 {
+	return math.Sin(x[0]) + 7*math.Pow(math.Sin(x[1]), 2) + 0.1*math.Pow(x[2], 4)*math.Sin(x[0])
+}
+
+func TestSaltelli(t *testing.T) {
+	pi := math.Pi
+	cube := []Factor{{"x1", -pi, pi}, {"x2", -pi, pi}, {"x3", -pi, pi}}
+	// the analytic indices of the Ishigami function
+	a, b := 7.0, 0.1
+	v1 := 0.5 * math.Pow(1+b*math.Pow(pi, 4)/5, 2)
+	v2 := a * a / 8
+	v13 := b * b * math.Pow(pi, 8) * (1.0/18 - 1.0/50)
+	v := v1 + v2 + v13
+
+	tests := []struct {
+		name    string
+		factors []Factor
+		f       func(x []float64) float64
+		n       int
+		s, st   []float64
+		tol     float64
+	}{
+		{"ishigami", cube, ishigami, 4096,
+			[]float64{v1 / v, v2 / v, 0}, []float64{(v1 + v13) / v, v2 / v, v13 / v}, 0.05},
+		// additive, the factors explain their share of the variance
+		{"additive", []Factor{{"x1", 0, 1}, {"x2", 0, 1}, {"x3", 0, 1}},
+			func(x []float64) float64 { return 2*x[0] + x[1] }, 1024,
+			[]float64{0.8, 0.2, 0}, []float64{0.8, 0.2, 0}, 0.03},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ix, err := analysis(tt.factors, tt.f).Saltelli(context.Background(), tt.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := tt.n * (len(tt.factors) + 2); ix.Runs != want {
+				t.Errorf("%d runs, want %d", ix.Runs, want)
+			}
+			for i, f := range tt.factors {
+				s, st := ix.Estimates[i][0], ix.Estimates[i][1]
+				if math.Abs(s.Value-tt.s[i]) > tt.tol {
+					t.Errorf("%s: S %v, want %.3f", f.Name, s, tt.s[i])
+				}
+				if math.Abs(st.Value-tt.st[i]) > tt.tol {
+					t.Errorf("%s: ST %v, want %.3f", f.Name, st, tt.st[i])
+				}
+				if !(s.Lo <= s.Value && s.Value <= s.Hi) || !(st.Lo <= st.Value && st.Value <= st.Hi) {
+					t.Errorf("%s: estimates outside their intervals: %v, %v", f.Name, s, st)
+				}
+			}
+		})
+	}
+}
+
+func TestMorris(t *testing.T) {
+	unit := []Factor{{"x1", 0, 1}, {"x2", 0, 1}, {"x3", 0, 1}}
+	tests := []struct {
+		name    string
+		factors []Factor
+		f       func(x []float64) float64
+		// μ*, μ and σ of every factor, NaN to skip
+		muStar, mu, sigma []float64
+	}{
+		{"linear", unit, func(x []float64) float64 { return 2*x[0] - 0.5*x[1] },
+			[]float64{2, 0.5, 0}, []float64{2, -0.5, 0}, []float64{0, 0, 0}},
+		// effects are per unit of the factor's range
+		{"scaled range", []Factor{{"x1", 0, 10}, {"x2", 5, 6}},
+			func(x []float64) float64 { return x[0] + x[1] },
+			[]float64{10, 1}, []float64{10, 1}, []float64{0, 0}},
+		// the effect of x1 depends on x2, so it spreads
+		{"interaction", unit, func(x []float64) float64 { return x[0] * x[1] },
+			[]float64{math.NaN(), math.NaN(), 0}, []float64{math.NaN(), math.NaN(), 0},
+			[]float64{math.Inf(1), math.Inf(1), 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := 20
+			ix, err := analysis(tt.factors, tt.f).Morris(context.Background(), r, 4)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := r * (len(tt.factors) + 1); ix.Runs != want {
+				t.Errorf("%d runs, want %d", ix.Runs, want)
+			}
+			for i, f := range tt.factors {
+				for c, want := range [][]float64{tt.muStar, tt.mu, tt.sigma} {
+					got := ix.Estimates[i][c].Value
+					switch {
+					case math.IsNaN(want[i]):
+					case math.IsInf(want[i], 1):
+						if got <= 1e-9 {
+							t.Errorf("%s: %s %g, want a spread", f.Name, ix.Columns[c], got)
+						}
+					case math.Abs(got-want[i]) > 1e-9:
+						t.Errorf("%s: %s %g, want %g", f.Name, ix.Columns[c], got, want[i])
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import "encoding/json"
+import "flag"
+import "io"
+
+import "flache/ra-ec"
+import "flache/v1"
+
+func init() {
+	register("ra-ec", &raecVariant{})
+	register("v1", &v1Variant{})
+}
+
+// writes the result of a run as json
+func writeResult(w io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	_, err = w.Write(data)
+	return err
+}
+
+// the relative agreement model with continuous opinions
+type raecVariant struct {
+	mu, uncertainty, pOnline       float64
+	agents, steps, blogs, comments int
+}
+
+func (v *raecVariant) Description() string {
+	return "relative agreement model with continuous opinions"
+}
+
+func (v *raecVariant) Flags(cmd string, fs *flag.FlagSet) {
+	fs.Float64Var(&v.mu, "mu", 2.5, "convergence speed of the opinions")
+	fs.Float64Var(&v.uncertainty, "uncertainty", 0.3, "opinion uncertainty of the agents")
+	fs.Float64Var(&v.pOnline, "p-online", 0.5, "probability of being online")
+	fs.IntVar(&v.agents, "agents", 300, "number of agents to simulate")
+	fs.IntVar(&v.steps, "steps", 700, "number of simulation steps")
+	fs.IntVar(&v.blogs, "blogs", 10, "number of blogs")
+	fs.IntVar(&v.comments, "comments", 10, "number of comments an agent reads")
+}
+
+func (v *raecVariant) Init(args []string) error {
+	return nil
+}
+
+func (v *raecVariant) Run(w io.Writer) error {
+	r := raec.SimRun(v.mu, v.uncertainty, v.pOnline, v.agents, v.steps, v.blogs, v.comments)
+	return writeResult(w, map[string]float64{"ECRatio": r})
+}
+
+func (v *raecVariant) Sweep(w io.Writer) error {
+	raec.Sweep(w, v.uncertainty, v.agents, v.steps, v.blogs, v.comments)
+	return nil
+}
+
+// the first model with a physical and a virtual landscape
+type v1Variant struct {
+	traits, features, size, agents, steps, blogs int
+	minTraits, maxTraits                         int
+	pveloc, steplength, sight, pOnline, pLooking float64
+}
+
+func (v *v1Variant) Description() string {
+	return "Axelrod cultures on a physical and a virtual landscape"
+}
+
+func (v *v1Variant) Flags(cmd string, fs *flag.FlagSet) {
+	if cmd == "sweep" {
+		fs.IntVar(&v.minTraits, "min-traits", 10, "smallest number of traits of the sweep")
+		fs.IntVar(&v.maxTraits, "max-traits", 20, "number of traits the sweep stops at")
+	} else {
+		fs.IntVar(&v.traits, "traits", 25, "number of cultural traits per feature")
+	}
+	fs.IntVar(&v.features, "features", 15, "number of cultural features")
+	fs.IntVar(&v.size, "size", 10, "size (width/height) of the landscape")
+	fs.Float64Var(&v.pveloc, "pveloc", 0.15, "probability that an agent moves")
+	fs.Float64Var(&v.steplength, "steplength", 0.2, "maximal distance a agent can travel per step")
+	fs.Float64Var(&v.sight, "sight", 1, "radius in which agent can interact")
+	fs.IntVar(&v.blogs, "blogs", 4, "number of blogs to follow in the virtual world")
+	fs.Float64Var(&v.pOnline, "p-online", 0.5, "probability of being online")
+	fs.Float64Var(&v.pLooking, "p-looking", 0.2, "probability of looking for new blogs (more similar and ditching old ones)")
+	fs.IntVar(&v.steps, "steps", 200, "number of simulation steps")
+	fs.IntVar(&v.agents, "agents", 30, "number of agents to simulate")
+}
+
+func (v *v1Variant) Init(args []string) error {
+	return nil
+}
+
+func (v *v1Variant) Run(w io.Writer) error {
+	r := v1.SimRun(v.traits, v.features, v.size, v.agents, v.steps, v.blogs,
+		v.pveloc, v.steplength, v.sight, v.pOnline, v.pLooking)
+	return writeResult(w, r)
+}
+
+func (v *v1Variant) Sweep(w io.Writer) error {
+	v1.Sweep(w, v.minTraits, v.maxTraits, v.features, v.size, v.agents, v.steps, v.blogs,
+		v.pveloc, v.steplength, v.sight, v.pOnline, v.pLooking)
+	return nil
+}
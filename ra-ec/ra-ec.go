@@ -1,9 +1,12 @@
-package main
+// Package raec is the relative agreement echo chamber model, opinions are
+// continuous and blogs are read by subscribers who agree with the writer.
+package raec
 
 import "math"
 import "github.com/nairboon/goabm"
 import "math/rand"
 import "fmt"
+import "io"
 
 type Opinion float64
 
@@ -284,7 +287,8 @@ func (a *EchoChamberModel) CreateAgent(agenter interface{}) goabm.Agenter {
 	return agent
 }
 
-func simRun(MU, Uncertainty, POnline float64, N, runs, blogs, NComments int) float64 {
+// runs the model and returns the share of echo chambers
+func SimRun(MU, Uncertainty, POnline float64, N, runs, blogs, NComments int) float64 {
 
 	model := &EchoChamberModel{
 		MU:          MU,
@@ -337,27 +341,16 @@ func simRun(MU, Uncertainty, POnline float64, N, runs, blogs, NComments int) flo
 	return model.ECRatio
 }
 
-func main() {
-	goabm.Init()
-
-	//simRun(2.3, 0.3, 0.5, 300, 700, 10, 10)
-
-	//return
-	//ponline := 0.5
-
-	agents := 300
-	runs := 700
-	blogs := 10
-
+// sweeps the probability to be online for a few μ
+func Sweep(w io.Writer, u float64, agents, runs, blogs, comments int) {
 	samplestep := 0.1
-        u:= 0.3
-	fmt.Printf("mu, ponline, deltares\n")
+	fmt.Fprintf(w, "mu, ponline, deltares\n")
 	for mu := 2.5; mu < 2.6; mu += samplestep {
 
 		rs := 0.0
 		for ir := 0.1; ir < 1.0; ir += 0.05 {
 
-			r := simRun(mu, u, ir, agents, runs, blogs, 10)
+			r := SimRun(mu, u, ir, agents, runs, blogs, comments)
 			/*
 				d := math.Abs(r-0.64) * 10
 
@@ -367,7 +360,7 @@ func main() {
 			}*/
 			rs += r
 
-		        fmt.Printf("%f, %f, %f\n", mu, ir, r)
+		        fmt.Fprintf(w, "%f, %f, %f\n", mu, ir, r)
 		}
 		//avg := rs / float64(ir)
 		//fmt.Printf("%f, %f, %f\n", mu, ir, avg)
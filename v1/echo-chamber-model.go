@@ -3,17 +3,14 @@ A Agent model to simulate opinion dynamics in echo chambers using the goabm libr
 Copyright 2014 by Remo Hertig <remo.hertig@bluewin.ch>
 */
 
-package flache
+package v1
 
 import "fmt"
 import "math/rand"
 
 import "goabm"
-import "flag"
 import "sort"
-import "os"
-import "log"
-import "runtime/pprof"
+import "io"
 
 // Implementation of the Agent, cultural Traits are stored in features
 type EchoChamberAgent struct {
@@ -287,118 +284,21 @@ func Abs(x int) int {
 	return x
 }
 
-func omain() {
-	goabm.Init()
+// sweeps the number of traits from min to max
+func Sweep(w io.Writer, min, max, features, size, numAgents, runs, FollowedBlogs int, probveloc, steplength, sight, POnline, PLooking float64) {
+	Min1 := min
+	Max1 := max
 
-	var traits = flag.Int("traits", 25, "number of cultural traits per feature")
-	var features = flag.Int("features", 15, "number of cultural features")
-	var size = flag.Int("size", 10, "size (width/height) of the landscape")
+	Min2 := min
+	Max2 := max
 
-	var probveloc = flag.Float64("pveloc", 0.15, "probability that an agent moves")
-	var steplength = flag.Float64("steplength", 0.2, "maximal distance a agent can travel per step")
-	var sight = flag.Float64("sight", 1, "radius in which agent can interact")
-
-	var FollowedBlogs = flag.Int("blogs", 4, "number of blogs to follow in the virtual world")
-	var POnline = flag.Float64("p-online", 0.5, "probability of being online")
-	var PLooking = flag.Float64("p-looking", 0.2, "probability of looking for new blogs (more similar and ditching old ones)")
-
-	var runs = flag.Int("runs", 300, "number of simulation runs")
-	var numAgents = flag.Int("agents", 30, "number of agents to simulate")
-
-	var memprofile = flag.String("memprofile", "", "write memory profile to this file")
-	var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
-
-	flag.Parse()
-
-	if *cpuprofile != "" {
-		f, err := os.Create(*cpuprofile)
-		if err != nil {
-			log.Fatal(err)
-		}
-		pprof.StartCPUProfile(f)
-		defer pprof.StopCPUProfile()
-	}
-
-	model := &EchoChamberModel{Traits: *traits, Features: *features, PVeloc: *probveloc, Steplength: *steplength,
-		FollowedBlogs: *FollowedBlogs, POnline: *POnline, PLookingForBlogs: *PLooking}
-	physicalWorld := &goabm.FixedLandscapeWithMovement{Size: *size, NAgents: *numAgents, Sight: *sight}
-	virtualWorld := &goabm.NetworkLandscape{}
-
-	combinedLandscape := &MultilevelLandscape{Base: physicalWorld, Overlay: virtualWorld}
-
-	sim := &goabm.Simulation{Landscape: combinedLandscape, Model: model, Log: goabm.Logger{StdOut: false}}
-	fmt.Println("ABM simulation")
-	sim.Init()
-
-	var diffScore = 0
-
-	for i := 0; i < *runs; i++ {
-
-		diffScore += Abs(model.PhysicalCultures - model.VirtualCultures)
-		if model.PhysicalCultures == 1 || model.VirtualCultures == 1 {
-			sim.Stop()
-			fmt.Printf("Stimulation prematurely done\n")
-			break
-		}
-		sim.Step()
-
-	}
-	sim.Stop()
-
-	d := model.Landscape.Dump()
-
-	var avgOffline, avgOnline float64
-	var sOnline, sOffline uint
-
-	for _, a := range d.Nodes {
-		v := a.(*EchoChamberAgent)
-		sOnline += v.OnlineChangeCounter
-		sOffline += v.OfflineChangeCounter
-		//fmt.Printf("Node: %d : %d - %d\n",i,v.OnlineChangeCounter,v.OfflineChangeCounter)
-	}
-	avgOffline = float64(sOffline / uint(len(d.Nodes)))
-	avgOnline = float64(sOnline / uint(len(d.Nodes)))
-	fmt.Printf("\nAverage Online: %f Offline: %f\n", avgOnline, avgOffline)
-
-	fmt.Printf("Total diff: %d\n", diffScore)
-	if *memprofile != "" {
-		f, err := os.Create(*memprofile)
-		if err != nil {
-			log.Fatal(err)
-		}
-		pprof.WriteHeapProfile(f)
-		f.Close()
-		return
-	}
-	//fmt.Printf("%v\n",sim.Landscape.GetAgents())
-}
-
-func main() {
-	features := 15
-	size := 10
-	numAgents := 30
-	runs := 200
-	FollowedBlogs := 4
-	probveloc := 0.15
-	steplength := 0.2
-	sight := 1.0
-	POnline := 0.5
-	PLooking := 0.2
-	goabm.Init()
-	//fmt.Printf("#Parameter search...\n");
-	Min1 := 10
-	Max1 := 20
-
-	Min2 := 10
-	Max2 := 20
-
-	fmt.Printf("traits,f, cdiff,onc,offc,avgon,avgoff\n")
+	fmt.Fprintf(w, "traits,f, cdiff,onc,offc,avgon,avgoff\n")
 	for i := Min1; i < Max1; i++ {
 		for j := Min2; j < Max2; j++ {
 
-			r := simRun(i, features, size, numAgents, runs, FollowedBlogs, probveloc, steplength, sight, POnline, PLooking)
+			r := SimRun(i, features, size, numAgents, runs, FollowedBlogs, probveloc, steplength, sight, POnline, PLooking)
 
-			fmt.Printf("%d, %d, %d, %d, %d, %f, %f\n", i, j, r.CultureDiff, r.OnlineCultures, r.OfflineCultures, r.AvgOnline, r.AvgOffline)
+			fmt.Fprintf(w, "%d, %d, %d, %d, %d, %f, %f\n", i, j, r.CultureDiff, r.OnlineCultures, r.OfflineCultures, r.AvgOnline, r.AvgOffline)
 		}
 	}
 
@@ -412,7 +312,8 @@ type SimRes struct {
 	OfflineCultures int
 }
 
-func simRun(traits, features, size, numAgents, runs, FollowedBlogs int, probveloc, steplength, sight, POnline, PLooking float64) SimRes {
+// runs the model and compares the cultures of both worlds
+func SimRun(traits, features, size, numAgents, runs, FollowedBlogs int, probveloc, steplength, sight, POnline, PLooking float64) SimRes {
 
 	model := &EchoChamberModel{Traits: traits, Features: features, PVeloc: probveloc, Steplength: steplength,
 		FollowedBlogs: FollowedBlogs, POnline: POnline, PLookingForBlogs: PLooking}